@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/someuser/gameserver/internal/loadtest"
+)
+
+//simulates the players of a scenario against a gameserver and reports its latencies, errors and throughput
+func main() {
+
+	scenarioFile := flag.String("scenario", "configs/loadtest/smoke.json", "json file of the scenario to run")
+	server := flag.String("server", "", "base url of the gameserver, overrides the one of the scenario")
+	reportFile := flag.String("report", "", "json file the report is also written to")
+	flag.Parse()
+
+	scenario, err := loadtest.LoadScenario(*scenarioFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *server != "" {
+		scenario.Server = *server
+	}
+	for _, warning := range scenario.Warnings() {
+		log.Print("warning: ", warning)
+	}
+
+	//an interrupted run still reports what was measured so far
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-stop
+		log.Print("stopping the load test")
+		cancel()
+	}()
+
+	log.Printf("running %s: %d sessions of %d players against %s", scenario.Name, scenario.Sessions,
+		scenario.PlayersPerSession, scenario.Server)
+	report, err := loadtest.Run(ctx, scenario)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report.Print(os.Stdout)
+
+	if *reportFile != "" {
+		data, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(*reportFile, data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -1,13 +1,27 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/rs/cors"
+	"github.com/spf13/viper"
+
+	clusterService "github.com/someuser/gameserver/internal/cluster/service"
+	gamesService "github.com/someuser/gameserver/internal/games/service"
 	"github.com/someuser/gameserver/internal/routes"
+	"github.com/someuser/gameserver/internal/users/db"
+	webhooksService "github.com/someuser/gameserver/internal/webhooks/service"
 )
 
+//closeTimeout is how long the requests still being answered have once the games are over
+const closeTimeout = 5 * time.Second
+
 func main() {
 
 	r := routes.Handlers()
@@ -21,9 +35,35 @@ func main() {
 
 	handler := c.Handler(r)
 
-	err := http.ListenAndServe(":8080", handler)
-	if err != nil {
-		log.Fatal(err)
+	server := &http.Server{Addr: ":8080", Handler: handler}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	//kubernetes sends SIGTERM and kills the server once its termination grace period is over
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	grace := viper.GetDuration("SHUTDOWN_GRACE_PERIOD")
+	log.Printf("shutting down, the game sessions have %s to end", grace)
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := gamesService.Shutdown(ctx); err != nil {
+		log.Print("the game sessions still running were saved ", err.Error())
 	}
 
+	ctx, cancelClose := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancelClose()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Print("error occued during server shutdown ", err.Error())
+	}
+	webhooksService.Get().Close()
+	clusterService.Get().PubSub.Close()
+	if err := db.Get().Close(); err != nil {
+		log.Print("error occued closing the database ", err.Error())
+	}
+	log.Println("server stopped")
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/someuser/gameserver/internal/cluster"
+)
+
+//the broker relaying the messages between the replicas of the gameserver
+func main() {
+
+	addr := flag.String("addr", ":7070", "address the broker listens on")
+	flag.Parse()
+
+	broker, err := cluster.ListenTCPBroker(*addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("pubsub broker listening on %s", broker.Addr())
+	log.Fatal(broker.Serve())
+}
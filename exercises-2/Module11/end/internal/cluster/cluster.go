@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"errors"
+	"time"
+)
+
+//DefaultNodeTTL is how long a node is considered alive after its last heartbeat
+const DefaultNodeTTL = 30 * time.Second
+
+var (
+	//ErrNoOwner is returned for the sessions no live node runs
+	ErrNoOwner = errors.New("no live node owns the session")
+	//ErrOwnedElsewhere is returned when claiming a session another live node runs
+	ErrOwnedElsewhere = errors.New("the session is owned by another node")
+)
+
+//Node is a replica of the gameserver, Addr is where the other nodes reach it
+type Node struct {
+	ID   string `json:"id"`
+	Addr string `json:"addr"`
+}
+
+//Directory maps each session to the node running it, it is shared by all the nodes
+type Directory interface {
+	//Heartbeat tells the others the node is alive, the sessions of a node that stops are taken over
+	Heartbeat(node Node) error
+	//Claim makes the node the owner of the session unless another live node owns it
+	Claim(sessionID string, node Node) error
+	//Release forgets the owner of the session if it is the node
+	Release(sessionID string, node Node) error
+	//Owner returns the live node running the session
+	Owner(sessionID string) (Node, error)
+	//Nodes returns the live nodes
+	Nodes() ([]Node, error)
+}
+
+//Membership is the directory as seen by a node, it is how the game manager claims its sessions
+type Membership struct {
+	Directory Directory
+	Self      Node
+}
+
+func (membership Membership) ClaimSession(sessionID string) error {
+	return membership.Directory.Claim(sessionID, membership.Self)
+}
+
+func (membership Membership) ReleaseSession(sessionID string) error {
+	return membership.Directory.Release(sessionID, membership.Self)
+}
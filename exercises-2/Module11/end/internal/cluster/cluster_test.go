@@ -0,0 +1,168 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryDirectory(t *testing.T) {
+	now := time.Now()
+	directory := NewMemoryDirectory(time.Minute)
+	directory.now = func() time.Time { return now }
+
+	a := Node{ID: "a", Addr: "http://a:8080"}
+	b := Node{ID: "b", Addr: "http://b:8080"}
+
+	if _, err := directory.Owner("session"); err != ErrNoOwner {
+		t.Errorf("Owner() of an unknown session = %v, want %v", err, ErrNoOwner)
+	}
+	if err := directory.Claim("session", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := directory.Claim("session", b); err != ErrOwnedElsewhere {
+		t.Errorf("Claim() of a session of a live node = %v, want %v", err, ErrOwnedElsewhere)
+	}
+	if owner, err := directory.Owner("session"); err != nil || owner != a {
+		t.Errorf("Owner() = %v, %v, want node a", owner, err)
+	}
+
+	//a stops sending heartbeats, its sessions can be taken over
+	now = now.Add(2 * time.Minute)
+	directory.Heartbeat(b)
+	if _, err := directory.Owner("session"); err != ErrNoOwner {
+		t.Errorf("Owner() of the session of a dead node = %v, want %v", err, ErrNoOwner)
+	}
+	if nodes, _ := directory.Nodes(); len(nodes) != 1 || nodes[0] != b {
+		t.Errorf("Nodes() = %v, want the live node b", nodes)
+	}
+	if err := directory.Claim("session", b); err != nil {
+		t.Errorf("Claim() of the session of a dead node = %v", err)
+	}
+
+	directory.Release("session", a)
+	if owner, _ := directory.Owner("session"); owner != b {
+		t.Error("Release() by a node that doesn't own the session removed the owner")
+	}
+	directory.Release("session", b)
+	if _, err := directory.Owner("session"); err != ErrNoOwner {
+		t.Errorf("Owner() after Release() = %v, want %v", err, ErrNoOwner)
+	}
+}
+
+//receiver subscribes to the topic and returns the channel the messages are sent to
+func receiver(t *testing.T, pubsub PubSub, topic string) (chan string, func()) {
+	t.Helper()
+	received := make(chan string, 10)
+	unsubscribe, err := pubsub.Subscribe(topic, func(data []byte) {
+		received <- string(data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return received, unsubscribe
+}
+
+func expect(t *testing.T, received chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-received:
+		if got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%q was not received", want)
+	}
+}
+
+func expectNothing(t *testing.T, received chan string) {
+	t.Helper()
+	select {
+	case got := <-received:
+		t.Errorf("received %q, want nothing", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLocalPubSub(t *testing.T) {
+	pubsub := NewLocalPubSub()
+	received, unsubscribe := receiver(t, pubsub, "invitations")
+	other, _ := receiver(t, pubsub, "sessions")
+
+	pubsub.Publish("invitations", []byte("hello"))
+	expect(t, received, "hello")
+	expectNothing(t, other)
+
+	unsubscribe()
+	pubsub.Publish("invitations", []byte("bye"))
+	expectNothing(t, received)
+}
+
+//waitSubscribed publishes on the topic until the receiver gets it, the subscription reaches the broker asynchronously
+func waitSubscribed(t *testing.T, publisher PubSub, topic string, received chan string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		publisher.Publish(topic, []byte("ping"))
+		select {
+		case <-received:
+			//drain the other pings
+			time.Sleep(20 * time.Millisecond)
+			for len(received) > 0 {
+				<-received
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	t.Fatalf("subscription to %s didn't reach the broker", topic)
+}
+
+func TestTCPPubSub(t *testing.T) {
+	broker, err := ListenTCPBroker("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go broker.Serve()
+	defer broker.Close()
+
+	a, err := DialPubSub(broker.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := DialPubSub(broker.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	onA, _ := receiver(t, a, "invitations")
+	onB, unsubscribeB := receiver(t, b, "invitations")
+	waitSubscribed(t, a, "invitations", onA)
+	waitSubscribed(t, a, "invitations", onB)
+	for len(onA) > 0 {
+		<-onA
+	}
+
+	//every subscriber gets the message, including the node that published it
+	b.Publish("invitations", []byte("hello"))
+	expect(t, onA, "hello")
+	expect(t, onB, "hello")
+
+	unsubscribeB()
+	time.Sleep(50 * time.Millisecond)
+	a.Publish("invitations", []byte("bye"))
+	expect(t, onA, "bye")
+	expectNothing(t, onB)
+
+	//the nodes reconnect and subscribe again when the broker comes back
+	addr := broker.Addr()
+	broker.Close()
+	restarted, err := ListenTCPBroker(addr)
+	if err != nil {
+		t.Skipf("can't listen again on %s: %v", addr, err)
+	}
+	go restarted.Serve()
+	defer restarted.Close()
+	waitSubscribed(t, b, "invitations", onA)
+}
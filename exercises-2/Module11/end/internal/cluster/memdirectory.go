@@ -0,0 +1,91 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+//MemoryDirectory keeps the directory in the process, it is enough for a single node and for tests
+type MemoryDirectory struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	now    func() time.Time
+	seen   map[string]time.Time
+	nodes  map[string]Node
+	owners map[string]Node
+}
+
+//NewMemoryDirectory type
+func NewMemoryDirectory(ttl time.Duration) *MemoryDirectory {
+	if ttl <= 0 {
+		ttl = DefaultNodeTTL
+	}
+	return &MemoryDirectory{
+		ttl:    ttl,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+		nodes:  make(map[string]Node),
+		owners: make(map[string]Node),
+	}
+}
+
+func (directory *MemoryDirectory) alive(node Node) bool {
+	seen, ok := directory.seen[node.ID]
+	return ok && directory.now().Sub(seen) < directory.ttl
+}
+
+func (directory *MemoryDirectory) Heartbeat(node Node) error {
+	directory.mu.Lock()
+	defer directory.mu.Unlock()
+
+	directory.seen[node.ID] = directory.now()
+	directory.nodes[node.ID] = node
+	return nil
+}
+
+func (directory *MemoryDirectory) Claim(sessionID string, node Node) error {
+	directory.mu.Lock()
+	defer directory.mu.Unlock()
+
+	directory.seen[node.ID] = directory.now()
+	directory.nodes[node.ID] = node
+	if owner, ok := directory.owners[sessionID]; ok && owner.ID != node.ID && directory.alive(owner) {
+		return ErrOwnedElsewhere
+	}
+	directory.owners[sessionID] = node
+	return nil
+}
+
+func (directory *MemoryDirectory) Release(sessionID string, node Node) error {
+	directory.mu.Lock()
+	defer directory.mu.Unlock()
+
+	if owner, ok := directory.owners[sessionID]; ok && owner.ID == node.ID {
+		delete(directory.owners, sessionID)
+	}
+	return nil
+}
+
+func (directory *MemoryDirectory) Owner(sessionID string) (Node, error) {
+	directory.mu.Lock()
+	defer directory.mu.Unlock()
+
+	owner, ok := directory.owners[sessionID]
+	if !ok || !directory.alive(owner) {
+		return Node{}, ErrNoOwner
+	}
+	return owner, nil
+}
+
+func (directory *MemoryDirectory) Nodes() ([]Node, error) {
+	directory.mu.Lock()
+	defer directory.mu.Unlock()
+
+	var nodes []Node
+	for _, node := range directory.nodes {
+		if directory.alive(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
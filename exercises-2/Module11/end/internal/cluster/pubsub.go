@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"sync"
+)
+
+//PubSub carries the messages between the nodes, every subscriber of a topic,
+//on this node or another one, gets each message published on it
+type PubSub interface {
+	Publish(topic string, data []byte) error
+	//Subscribe calls the handler for the messages of the topic until the returned function is called
+	Subscribe(topic string, handler func(data []byte)) (func(), error)
+	Close() error
+}
+
+//subscriptions are the handlers of the topics, shared by the implementations
+type subscriptions struct {
+	mu       sync.Mutex
+	handlers map[string]map[*func([]byte)]struct{}
+}
+
+//add returns the function removing the handler and whether it is the first one of the topic
+func (subs *subscriptions) add(topic string, handler func([]byte)) (func() bool, bool) {
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+
+	if subs.handlers == nil {
+		subs.handlers = make(map[string]map[*func([]byte)]struct{})
+	}
+	first := len(subs.handlers[topic]) == 0
+	if first {
+		subs.handlers[topic] = make(map[*func([]byte)]struct{})
+	}
+	key := &handler
+	subs.handlers[topic][key] = struct{}{}
+
+	//remove reports whether it removed the last handler of the topic
+	remove := func() bool {
+		subs.mu.Lock()
+		defer subs.mu.Unlock()
+
+		if _, ok := subs.handlers[topic][key]; !ok {
+			return false
+		}
+		delete(subs.handlers[topic], key)
+		if len(subs.handlers[topic]) == 0 {
+			delete(subs.handlers, topic)
+			return true
+		}
+		return false
+	}
+	return remove, first
+}
+
+func (subs *subscriptions) topics() []string {
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+
+	topics := make([]string, 0, len(subs.handlers))
+	for topic := range subs.handlers {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+func (subs *subscriptions) dispatch(topic string, data []byte) {
+	subs.mu.Lock()
+	handlers := make([]func([]byte), 0, len(subs.handlers[topic]))
+	for handler := range subs.handlers[topic] {
+		handlers = append(handlers, *handler)
+	}
+	subs.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(data)
+	}
+}
+
+//LocalPubSub delivers the messages within the process, it is the backbone of a single node
+type LocalPubSub struct {
+	subs subscriptions
+}
+
+//NewLocalPubSub type
+func NewLocalPubSub() *LocalPubSub {
+	return &LocalPubSub{}
+}
+
+func (pubsub *LocalPubSub) Publish(topic string, data []byte) error {
+	pubsub.subs.dispatch(topic, data)
+	return nil
+}
+
+func (pubsub *LocalPubSub) Subscribe(topic string, handler func(data []byte)) (func(), error) {
+	remove, _ := pubsub.subs.add(topic, handler)
+	return func() { remove() }, nil
+}
+
+func (pubsub *LocalPubSub) Close() error {
+	return nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/someuser/gameserver/internal/cluster"
+)
+
+const heartbeatInterval = cluster.DefaultNodeTTL / 3
+
+var clusterService *ClusterService
+
+//ClusterService is this node and how it reaches the others
+type ClusterService struct {
+	Self      cluster.Node
+	Directory cluster.Directory
+	PubSub    cluster.PubSub
+	//Redirect sends the clients to the node running their session instead of proxying them
+	Redirect bool
+}
+
+//Get returns the cluster service, a single node keeps everything in the process unless
+//CLUSTER_DIRECTORY=mysql and CLUSTER_PUBSUB=tcp with the broker at CLUSTER_PUBSUB_ADDR
+func Get() *ClusterService {
+	if clusterService == nil {
+		hostname, _ := os.Hostname()
+		self := cluster.Node{
+			ID:   viper.GetString("CLUSTER_NODE_ID"),
+			Addr: viper.GetString("CLUSTER_NODE_ADDR"),
+		}
+		if self.ID == "" {
+			self.ID = hostname
+		}
+		if self.Addr == "" {
+			self.Addr = "http://" + hostname + ":8080"
+		}
+
+		var directory cluster.Directory
+		if viper.GetString("CLUSTER_DIRECTORY") == "mysql" {
+			directory = GetDirectoryDataStore()
+		} else {
+			directory = cluster.NewMemoryDirectory(cluster.DefaultNodeTTL)
+		}
+
+		var pubsub cluster.PubSub = cluster.NewLocalPubSub()
+		if viper.GetString("CLUSTER_PUBSUB") == "tcp" {
+			tcp, err := cluster.DialPubSub(viper.GetString("CLUSTER_PUBSUB_ADDR"))
+			if err != nil {
+				log.Fatalf("Error connecting to the pubsub broker, %s", err)
+			}
+			pubsub = tcp
+		}
+
+		clusterService = &ClusterService{
+			Self:      self,
+			Directory: directory,
+			PubSub:    pubsub,
+			Redirect:  viper.GetString("CLUSTER_JOIN_MODE") == "redirect",
+		}
+		go clusterService.heartbeat()
+	}
+	return clusterService
+}
+
+//Membership is the directory as seen by this node
+func (cs *ClusterService) Membership() cluster.Membership {
+	return cluster.Membership{Directory: cs.Directory, Self: cs.Self}
+}
+
+//heartbeat keeps this node alive in the directory so its sessions are not taken over
+func (cs *ClusterService) heartbeat() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		if err := cs.Directory.Heartbeat(cs.Self); err != nil {
+			log.Print("error occued during cluster heartbeat ", err.Error())
+		}
+		<-ticker.C
+	}
+}
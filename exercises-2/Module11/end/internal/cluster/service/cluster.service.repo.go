@@ -0,0 +1,100 @@
+package service
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/someuser/gameserver/internal/cluster"
+	database "github.com/someuser/gameserver/internal/users/db"
+)
+
+type DirectoryDB struct {
+	*sql.DB
+}
+
+func GetDirectoryDataStore() cluster.Directory {
+	return &DirectoryDB{database.Get()}
+}
+
+//aliveSince is the oldest heartbeat of a live node
+func aliveSince() time.Time {
+	return time.Now().Add(-cluster.DefaultNodeTTL)
+}
+
+func (db *DirectoryDB) Heartbeat(node cluster.Node) error {
+
+	_, err := db.Exec(`insert into cluster_nodes(id,addr,seen_at)values(?,?,?)
+						on duplicate key update addr = values(addr), seen_at = values(seen_at)`,
+		node.ID, node.Addr, time.Now())
+	return err
+}
+
+//Claim locks the owner of the session for the time it takes to check it is not a live node
+func (db *DirectoryDB) Claim(sessionID string, node cluster.Node) error {
+
+	if err := db.Heartbeat(node); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ownerID string
+	var seenAt sql.NullTime
+	row := tx.QueryRow(`select o.node_id, n.seen_at from session_owners o left join cluster_nodes n on n.id = o.node_id
+						where o.session_id = ? for update`, sessionID)
+	err = row.Scan(&ownerID, &seenAt)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && ownerID != node.ID && seenAt.Valid && seenAt.Time.After(aliveSince()) {
+		return cluster.ErrOwnedElsewhere
+	}
+
+	_, err = tx.Exec(`insert into session_owners(session_id,node_id)values(?,?)
+						on duplicate key update node_id = values(node_id)`, sessionID, node.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DirectoryDB) Release(sessionID string, node cluster.Node) error {
+
+	_, err := db.Exec("delete from session_owners where session_id = ? and node_id = ?", sessionID, node.ID)
+	return err
+}
+
+func (db *DirectoryDB) Owner(sessionID string) (cluster.Node, error) {
+
+	var owner cluster.Node
+	row := db.QueryRow(`select n.id, n.addr from session_owners o join cluster_nodes n on n.id = o.node_id
+						where o.session_id = ? and n.seen_at > ?`, sessionID, aliveSince())
+	err := row.Scan(&owner.ID, &owner.Addr)
+	if err == sql.ErrNoRows {
+		return owner, cluster.ErrNoOwner
+	}
+	return owner, err
+}
+
+func (db *DirectoryDB) Nodes() ([]cluster.Node, error) {
+
+	rows, err := db.Query("select id, addr from cluster_nodes where seen_at > ?", aliveSince())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []cluster.Node
+	for rows.Next() {
+		var node cluster.Node
+		if err := rows.Scan(&node.ID, &node.Addr); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
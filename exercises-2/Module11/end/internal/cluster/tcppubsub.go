@@ -0,0 +1,291 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	tcpWriteWait     = 10 * time.Second
+	maxReconnectWait = 10 * time.Second
+	//brokerQueueSize is how many messages a slow subscriber can fall behind before being dropped
+	brokerQueueSize = 1024
+)
+
+//ErrPubSubClosed is returned when using a closed pub/sub or one that lost its broker
+var ErrPubSubClosed = errors.New("pubsub is not connected")
+
+//frame is what goes over the connections between the nodes and the broker, one json per line
+type frame struct {
+	Op    string `json:"op"`
+	Topic string `json:"topic"`
+	Data  []byte `json:"data,omitempty"`
+}
+
+const (
+	opSubscribe   = "sub"
+	opUnsubscribe = "unsub"
+	opPublish     = "pub"
+)
+
+//TCPBroker relays the messages published by the nodes to the nodes subscribed to their topic
+type TCPBroker struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    map[*brokerConn]struct{}
+}
+
+type brokerConn struct {
+	conn   net.Conn
+	topics map[string]bool
+	send   chan frame
+}
+
+//ListenTCPBroker listens on the address, Serve has to be called to accept the nodes
+func ListenTCPBroker(addr string) (*TCPBroker, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &TCPBroker{listener: listener, conns: make(map[*brokerConn]struct{})}, nil
+}
+
+//Addr is the address the broker listens on
+func (broker *TCPBroker) Addr() string {
+	return broker.listener.Addr().String()
+}
+
+//Serve accepts the nodes until the broker is closed
+func (broker *TCPBroker) Serve() error {
+	for {
+		conn, err := broker.listener.Accept()
+		if err != nil {
+			return err
+		}
+		c := &brokerConn{conn: conn, topics: make(map[string]bool), send: make(chan frame, brokerQueueSize)}
+		broker.mu.Lock()
+		broker.conns[c] = struct{}{}
+		broker.mu.Unlock()
+
+		go broker.write(c)
+		go broker.read(c)
+	}
+}
+
+//Close stops the broker and disconnects the nodes
+func (broker *TCPBroker) Close() error {
+	err := broker.listener.Close()
+	broker.mu.Lock()
+	for c := range broker.conns {
+		c.conn.Close()
+	}
+	broker.mu.Unlock()
+	return err
+}
+
+func (broker *TCPBroker) drop(c *brokerConn) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	if _, ok := broker.conns[c]; ok {
+		delete(broker.conns, c)
+		close(c.send)
+	}
+	c.conn.Close()
+}
+
+func (broker *TCPBroker) read(c *brokerConn) {
+	defer broker.drop(c)
+
+	decoder := json.NewDecoder(c.conn)
+	for {
+		var f frame
+		if err := decoder.Decode(&f); err != nil {
+			return
+		}
+		switch f.Op {
+		case opSubscribe:
+			broker.mu.Lock()
+			c.topics[f.Topic] = true
+			broker.mu.Unlock()
+		case opUnsubscribe:
+			broker.mu.Lock()
+			delete(c.topics, f.Topic)
+			broker.mu.Unlock()
+		case opPublish:
+			broker.publish(f)
+		}
+	}
+}
+
+func (broker *TCPBroker) publish(f frame) {
+	broker.mu.Lock()
+	var slow []*brokerConn
+	for c := range broker.conns {
+		if !c.topics[f.Topic] {
+			continue
+		}
+		select {
+		case c.send <- f:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	broker.mu.Unlock()
+
+	for _, c := range slow {
+		log.Printf("pubsub: dropping %s which is too slow", c.conn.RemoteAddr())
+		broker.drop(c)
+	}
+}
+
+func (broker *TCPBroker) write(c *brokerConn) {
+	encoder := json.NewEncoder(c.conn)
+	for f := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(tcpWriteWait))
+		if err := encoder.Encode(f); err != nil {
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+//TCPPubSub is a node connected to a TCPBroker, it reconnects when the connection is lost
+//and subscribes again to its topics
+type TCPPubSub struct {
+	addr string
+	subs subscriptions
+
+	mu      sync.Mutex
+	conn    net.Conn
+	encoder *json.Encoder
+	closed  bool
+}
+
+//DialPubSub connects to the broker at the address
+func DialPubSub(addr string) (*TCPPubSub, error) {
+	pubsub := &TCPPubSub{addr: addr}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	pubsub.setConn(conn)
+	go pubsub.read(conn)
+	return pubsub, nil
+}
+
+func (pubsub *TCPPubSub) setConn(conn net.Conn) {
+	pubsub.mu.Lock()
+	defer pubsub.mu.Unlock()
+
+	pubsub.conn = conn
+	pubsub.encoder = json.NewEncoder(conn)
+}
+
+func (pubsub *TCPPubSub) send(f frame) error {
+	pubsub.mu.Lock()
+	defer pubsub.mu.Unlock()
+
+	if pubsub.closed || pubsub.conn == nil {
+		return ErrPubSubClosed
+	}
+	pubsub.conn.SetWriteDeadline(time.Now().Add(tcpWriteWait))
+	return pubsub.encoder.Encode(f)
+}
+
+func (pubsub *TCPPubSub) Publish(topic string, data []byte) error {
+	return pubsub.send(frame{Op: opPublish, Topic: topic, Data: data})
+}
+
+func (pubsub *TCPPubSub) Subscribe(topic string, handler func(data []byte)) (func(), error) {
+	remove, first := pubsub.subs.add(topic, handler)
+	if first {
+		//a node that is reconnecting subscribes once connected again
+		if err := pubsub.send(frame{Op: opSubscribe, Topic: topic}); err != nil && pubsub.isClosed() {
+			remove()
+			return nil, err
+		}
+	}
+	return func() {
+		if last := remove(); last {
+			pubsub.send(frame{Op: opUnsubscribe, Topic: topic})
+		}
+	}, nil
+}
+
+func (pubsub *TCPPubSub) isClosed() bool {
+	pubsub.mu.Lock()
+	defer pubsub.mu.Unlock()
+
+	return pubsub.closed
+}
+
+func (pubsub *TCPPubSub) Close() error {
+	pubsub.mu.Lock()
+	defer pubsub.mu.Unlock()
+
+	pubsub.closed = true
+	if pubsub.conn == nil {
+		return nil
+	}
+	return pubsub.conn.Close()
+}
+
+//read dispatches the messages of the broker and reconnects when the connection is lost
+func (pubsub *TCPPubSub) read(conn net.Conn) {
+	for {
+		decoder := json.NewDecoder(conn)
+		for {
+			var f frame
+			if err := decoder.Decode(&f); err != nil {
+				break
+			}
+			if f.Op == opPublish {
+				pubsub.subs.dispatch(f.Topic, f.Data)
+			}
+		}
+		conn.Close()
+
+		if conn = pubsub.reconnect(); conn == nil {
+			return
+		}
+	}
+}
+
+//reconnect dials the broker until it answers or the pub/sub is closed, it returns nil once closed
+func (pubsub *TCPPubSub) reconnect() net.Conn {
+	pubsub.mu.Lock()
+	pubsub.conn = nil
+	pubsub.mu.Unlock()
+
+	wait := 100 * time.Millisecond
+	for !pubsub.isClosed() {
+		conn, err := net.Dial("tcp", pubsub.addr)
+		if err != nil {
+			time.Sleep(wait)
+			if wait *= 2; wait > maxReconnectWait {
+				wait = maxReconnectWait
+			}
+			continue
+		}
+		pubsub.mu.Lock()
+		if pubsub.closed {
+			pubsub.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		pubsub.conn = conn
+		pubsub.encoder = json.NewEncoder(conn)
+		pubsub.mu.Unlock()
+
+		for _, topic := range pubsub.subs.topics() {
+			pubsub.send(frame{Op: opSubscribe, Topic: topic})
+		}
+		return conn
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package games
+
+import (
+	"errors"
+	"sort"
+)
+
+//ErrSessionEnded is returned when asking something of a session that has ended
+var ErrSessionEnded = errors.New("game session has ended")
+
+//sessionControl is run by the session loop, it returns true when the session has to end
+type sessionControl func(gameSession *GameSession) bool
+
+//SessionDetail is what an operator sees of a session
+type SessionDetail struct {
+	SessionInfo
+	InitialGameData string       `json:"initialGameData"`
+	JoinCode        string       `json:"joinCode,omitempty"`
+	Seq             uint64       `json:"seq"`
+	SpectatorList   []PlayerInfo `json:"spectatorList"`
+}
+
+//OnSystemMessage is sent to everyone in a session by the operators
+type OnSystemMessage struct {
+	Message string `json:"message"`
+}
+
+//OnPlayerKicked is sent to everyone in a session when an operator removes a player
+type OnPlayerKicked struct {
+	Email  string `json:"email"`
+	Reason string `json:"reason,omitempty"`
+}
+
+//GetActiveSessions returns the running sessions, the oldest first
+func (manager *GameManager) GetActiveSessions() []*GameSession {
+	ch := make(chan []*GameSession)
+	manager.listSessions <- ch
+	sessions := <-ch
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions
+}
+
+func (manager *GameManager) activeSessions() []*GameSession {
+	sessions := make([]*GameSession, 0, len(manager.activeGames))
+	for _, session := range manager.activeGames {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+//control runs the function in the session loop and waits for it, unless the session has ended
+func (gameSession *GameSession) control(f func(gameSession *GameSession) (bool, error)) error {
+	result := make(chan error, 1)
+	control := func(gameSession *GameSession) bool {
+		end, err := f(gameSession)
+		result <- err
+		return end
+	}
+	select {
+	case gameSession.controls <- control:
+		return <-result
+	case <-gameSession.done:
+		return ErrSessionEnded
+	}
+}
+
+//Detail returns the description of the session with its game data
+func (gameSession *GameSession) Detail() (SessionDetail, error) {
+	var detail SessionDetail
+	err := gameSession.control(func(gameSession *GameSession) (bool, error) {
+		detail = SessionDetail{
+			SessionInfo:     gameSession.info(),
+			InitialGameData: gameSession.InitialGameData,
+			JoinCode:        gameSession.JoinCode,
+			Seq:             gameSession.seq,
+			SpectatorList:   make([]PlayerInfo, 0, len(gameSession.Spectators)),
+		}
+		for _, spectator := range gameSession.Spectators {
+			detail.SpectatorList = append(detail.SpectatorList, PlayerInfo{
+				Player:    Player{ID: spectator.ID, Name: spectator.Name, Email: spectator.Email, Spectator: true},
+				Connected: spectator.IsConnected(),
+			})
+		}
+		sort.Slice(detail.SpectatorList, func(i, j int) bool {
+			return detail.SpectatorList[i].Email < detail.SpectatorList[j].Email
+		})
+		return false, nil
+	})
+	return detail, err
+}
+
+//Kick removes the player or spectator from the session and closes its connection,
+//the game held back for a kicked player starts when everyone left joined
+func (gameSession *GameSession) Kick(email string, reason string) error {
+	return gameSession.control(func(gameSession *GameSession) (bool, error) {
+		kicked := OnPlayerKicked{Email: email, Reason: reason}
+		if spectator, ok := gameSession.Spectators[email]; ok {
+			gameSession.removeSpectator(spectator)
+			return false, nil
+		}
+		player, ok := gameSession.Players[email]
+		if !ok {
+			return false, errors.New("no such player in the session")
+		}
+		//the kicked player is told why before being disconnected
+		if player.IsConnected() {
+			msg, _ := WrapCommand(ON_PLAYER_KICKED, kicked, Player{})
+			player.SendMessage(&msg)
+		}
+		gameSession.removeUser(player)
+		gameSession.persist()
+
+		msg, _ := WrapCommand(ON_PLAYER_KICKED, kicked, Player{})
+		gameSession.sendMsgToPlayers(&msg)
+		return gameSession.startWhenReady(), nil
+	})
+}
+
+//Broadcast sends a message from the operators to everyone in the session
+func (gameSession *GameSession) Broadcast(message string) error {
+	return gameSession.control(func(gameSession *GameSession) (bool, error) {
+		msg, err := WrapCommand(ON_SYSTEM_MESSAGE, OnSystemMessage{Message: message}, Player{})
+		if err != nil {
+			return false, err
+		}
+		gameSession.sendMsgToPlayers(&msg)
+		return false, nil
+	})
+}
+
+//End ends the session, the players get ON_GAME_OVER with the reason
+func (gameSession *GameSession) End(reason string) error {
+	return gameSession.control(func(gameSession *GameSession) (bool, error) {
+		over := GameResult{Message: reason}
+		msg, err := WrapCommand(ON_GAME_OVER, over, Player{})
+		if err != nil {
+			return false, err
+		}
+		gameSession.sendMsgToPlayers(&msg)
+		gameSession.result = &over
+		return true, nil
+	})
+}
@@ -0,0 +1,57 @@
+package games
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGameSession_admin(t *testing.T) {
+	manager := newTestGameManager(nil)
+	session := manager.CreateNewGameSession(Game{ID: "chess"})
+	go session.Run()
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}, GameData: "board"}, dave)
+	session.SendToGame <- &startGame
+
+	if sessions := manager.GetActiveSessions(); len(sessions) != 1 || sessions[0] != session {
+		t.Fatalf("GetActiveSessions() = %v", sessions)
+	}
+
+	if err := session.Broadcast("server restarts in 5 minutes"); err != nil {
+		t.Fatal(err)
+	}
+	detail, err := session.Detail()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the broadcast follows the session moving to running
+	if detail.InitialGameData != "board" || detail.Seq != 2 || detail.State != SessionRunning || len(detail.Players) != 1 {
+		t.Errorf("Detail() = %+v", detail)
+	}
+
+	if err := session.Kick("nobody@gmail.com", ""); err == nil {
+		t.Error("Kick() of someone not in the session succeeded")
+	}
+	if err := session.Kick(dan.Email, "cheating"); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := session.Info(); len(info.Players) != 0 {
+		t.Errorf("players after dan was kicked = %+v", info.Players)
+	}
+
+	if err := session.End("maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for manager.GetSessionByID(session.ID) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("ended session is still active")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := session.Broadcast("too late"); err != ErrSessionEnded {
+		t.Errorf("Broadcast() after the end = %v, want %v", err, ErrSessionEnded)
+	}
+}
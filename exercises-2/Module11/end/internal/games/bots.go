@@ -0,0 +1,287 @@
+package games
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/someuser/gameserver/internal/users"
+)
+
+//the difficulties of the bots, what they change is up to the bot of each game
+const (
+	BotEasy   = "easy"
+	BotMedium = "medium"
+	BotHard   = "hard"
+)
+
+const (
+	//defaultBotThink is how long a bot waits before each move when the game doesn't say
+	defaultBotThink = time.Second
+	//botInboxSize is how many messages of the session a bot can fall behind, the oldest are dropped
+	botInboxSize = 64
+	//botEmailDomain is the domain of the emails the bots get, the users service doesn't let anyone register with it
+	botEmailDomain = users.BotEmailDomain
+)
+
+//BotConfig lets bots take the seats of the invited players who don't show up
+type BotConfig struct {
+	//FillAfterSeconds is how long after the START_GAME the invited players who didn't join are replaced by bots
+	FillAfterSeconds int `json:"fillAfterSeconds,omitempty"`
+	//Difficulty of the bots, BotEasy, BotMedium or BotHard
+	Difficulty string `json:"difficulty,omitempty"`
+	//ThinkMillis is how long the bots wait before each move so the players can follow
+	ThinkMillis int `json:"thinkMillis,omitempty"`
+}
+
+//Validate checks the config can be used
+func (config BotConfig) Validate() error {
+	if config.FillAfterSeconds < 0 || config.ThinkMillis < 0 {
+		return errors.New("the bot delays can't be negative")
+	}
+	switch config.Difficulty {
+	case "", BotEasy, BotMedium, BotHard:
+		return nil
+	}
+	return errors.New("unknown bot difficulty " + config.Difficulty)
+}
+
+//OnBotJoined is sent to the players of a session when a bot takes the seat of an invited user who didn't join
+type OnBotJoined struct {
+	Replaced string `json:"replaced"`
+	Bot      Player `json:"bot"`
+}
+
+//Bot plays a game on the server in place of a player, it is told what the session sends to its seat
+//and answers with its moves like a client would
+type Bot interface {
+	//Play returns the messages the bot sends after getting msg, none while it is not its turn
+	Play(self Player, msg GameMsg) []GameMsg
+}
+
+//BotFactory creates the bot of a single seat with the given difficulty
+type BotFactory func(difficulty string) Bot
+
+var (
+	botsMu sync.RWMutex
+	bots   = make(map[string]BotFactory)
+)
+
+//RegisterBot makes a bot available for the game with the given id,
+//it is meant to be called from the init function of the package implementing the game
+func RegisterBot(gameID string, factory BotFactory) {
+	botsMu.Lock()
+	defer botsMu.Unlock()
+
+	if factory == nil {
+		panic("games: RegisterBot factory is nil")
+	}
+	if _, dup := bots[gameID]; dup {
+		panic("games: RegisterBot called twice for game " + gameID)
+	}
+	bots[gameID] = factory
+}
+
+//newBot returns a new bot for the game or false when no bot can play it
+func newBot(gameID string, difficulty string) (Bot, bool) {
+	botsMu.RLock()
+	defer botsMu.RUnlock()
+
+	if factory, ok := bots[gameID]; ok {
+		return factory(difficulty), true
+	}
+	return nil, false
+}
+
+func (game Game) bots() BotConfig {
+	if game.Bots == nil {
+		return BotConfig{}
+	}
+	config := *game.Bots
+	if config.Difficulty == "" {
+		config.Difficulty = BotMedium
+	}
+	return config
+}
+
+//botFillDelay is how long the invited players have before bots take their seats, 0 when they never do
+func (game Game) botFillDelay() time.Duration {
+	return time.Duration(game.bots().FillAfterSeconds) * time.Second
+}
+
+func (game Game) botThink() time.Duration {
+	if millis := game.bots().ThinkMillis; millis > 0 {
+		return time.Duration(millis) * time.Millisecond
+	}
+	return defaultBotThink
+}
+
+//scheduleBots starts the wait after which the seats of the invited players who didn't join are filled
+func (gameSession *GameSession) scheduleBots() {
+	delay := gameSession.Game.botFillDelay()
+	if delay <= 0 || gameSession.state != SessionLobby {
+		return
+	}
+	resetTimer(gameSession.botTimer, delay)
+}
+
+//fillSeats gives the seats of the invited players who still didn't join to bots
+func (gameSession *GameSession) fillSeats() {
+	if gameSession.state != SessionLobby {
+		return
+	}
+	filled := false
+	for email, player := range gameSession.Players {
+		if player.IsConnected() || player.Bot || email == gameSession.host {
+			continue
+		}
+		gameSession.botCount++
+		difficulty := gameSession.Game.bots().Difficulty
+		seat := gameSession.CreateNewPlayer(nil, 0, fmt.Sprintf("Bot %d (%s)", gameSession.botCount, difficulty),
+			fmt.Sprintf("bot%d@%s", gameSession.botCount, botEmailDomain))
+		seat.Bot = true
+		if !gameSession.startBot(seat) {
+			return
+		}
+		//the seat is taken until the bot joins so the game doesn't start without it
+		delete(gameSession.Players, email)
+		gameSession.Players[seat.Email] = seat
+		filled = true
+
+		msg, _ := WrapCommand(ON_BOT_JOINED, OnBotJoined{Replaced: email, Bot: *seat}, Player{})
+		gameSession.sendMsgToPlayers(&msg)
+	}
+	if filled {
+		gameSession.persist()
+	}
+}
+
+//startBots connects the bots of a restored session again
+func (gameSession *GameSession) startBots() {
+	for _, player := range gameSession.Players {
+		if player.Bot && !player.IsConnected() {
+			gameSession.startBot(player)
+		}
+	}
+}
+
+//startBot joins a bot to the seat, it returns false when no bot can play the game
+func (gameSession *GameSession) startBot(seat *Player) bool {
+	config := gameSession.Game.bots()
+	bot, ok := newBot(gameSession.Game.ID, config.Difficulty)
+	if !ok {
+		return false
+	}
+	//the seat stays in the session until the bot registers, the bot joins as a player of its own
+	player := gameSession.CreateNewPlayer(nil, seat.ID, seat.Name, seat.Email)
+	player.Bot = true
+	conn := newBotConn(bot, *player, gameSession.Game.botThink())
+	go player.Join(conn, 0)
+	return true
+}
+
+//botConn is the connection of a bot, what the session writes to it is played by the bot
+//and the moves of the bot are read back by its player
+type botConn struct {
+	bot   Bot
+	self  Player
+	think time.Duration
+	//inbox holds the messages of the session until the bot plays them, moves the moves until they are read
+	inbox  chan GameMsg
+	moves  chan []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+var errBotConnClosed = errors.New("bot connection closed")
+
+func newBotConn(bot Bot, self Player, think time.Duration) *botConn {
+	conn := &botConn{
+		bot:    bot,
+		self:   self,
+		think:  think,
+		inbox:  make(chan GameMsg, botInboxSize),
+		moves:  make(chan []byte),
+		closed: make(chan struct{}),
+	}
+	go conn.play()
+	return conn
+}
+
+//play has the bot play the messages of the session, it waits before each move
+func (conn *botConn) play() {
+	for {
+		select {
+		case msg := <-conn.inbox:
+			for _, move := range conn.bot.Play(conn.self, msg) {
+				timer := time.NewTimer(conn.think)
+				select {
+				case <-timer.C:
+				case <-conn.closed:
+					timer.Stop()
+					return
+				}
+				data, err := json.Marshal(move)
+				if err != nil {
+					continue
+				}
+				select {
+				case conn.moves <- data:
+				case <-conn.closed:
+					return
+				}
+			}
+		case <-conn.closed:
+			return
+		}
+	}
+}
+
+func (conn *botConn) Subprotocol() string { return "" }
+
+func (conn *botConn) ReadMessage() (int, []byte, error) {
+	select {
+	case data := <-conn.moves:
+		return websocket.TextMessage, data, nil
+	case <-conn.closed:
+		return 0, nil, errBotConnClosed
+	}
+}
+
+//WriteMessage hands the message to the bot, it never waits for the bot to play
+func (conn *botConn) WriteMessage(messageType int, data []byte) error {
+	var msg GameMsg
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-conn.closed:
+			return errBotConnClosed
+		case conn.inbox <- msg:
+			return nil
+		default:
+		}
+		//the bot fell behind, it only needs the latest messages
+		select {
+		case <-conn.inbox:
+		default:
+		}
+	}
+}
+
+func (conn *botConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	return nil
+}
+func (conn *botConn) SetReadDeadline(t time.Time) error           { return nil }
+func (conn *botConn) SetReadLimit(limit int64)                    {}
+func (conn *botConn) SetWriteDeadline(t time.Time) error          { return nil }
+func (conn *botConn) SetPongHandler(h func(appData string) error) {}
+func (conn *botConn) Close() error {
+	conn.once.Do(func() { close(conn.closed) })
+	return nil
+}
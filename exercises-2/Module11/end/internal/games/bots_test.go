@@ -0,0 +1,123 @@
+package games
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+//counterBot adds one to the counter every time the state changes
+type counterBot struct{}
+
+func (counterBot) Play(self Player, msg GameMsg) []GameMsg {
+	if msg.GameAction != ON_GAME_STATE_CHANGED {
+		return nil
+	}
+	return []GameMsg{{GameAction: GAME_PLAY, Data: "+1"}}
+}
+
+func init() {
+	RegisterBot(counterGame.ID, func(difficulty string) Bot { return counterBot{} })
+}
+
+//recordingRecorder keeps the players of the results recorded
+type recordingRecorder struct {
+	mu      sync.Mutex
+	players []Player
+}
+
+func (recorder *recordingRecorder) RecordResult(gameID string, sessionID string, players []Player, result GameResult) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.players = append(recorder.players, players...)
+	return nil
+}
+
+func TestGameSession_fillSeats(t *testing.T) {
+	recorder := &recordingRecorder{}
+	manager := newTestGameManager(nil)
+	manager.SetResultRecorder(recorder)
+	game := lifecycleGame(LifecycleConfig{AutoStart: true}, 2, 2)
+	game.Bots = &BotConfig{Difficulty: BotHard, ThinkMillis: 1}
+	session := manager.CreateNewGameSession(game)
+	go session.Run()
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	daveConn := newFakeConn(false)
+	session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email).Start(daveConn)
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}}, dave)
+	session.SendToGame <- &startGame
+
+	//dan doesn't show up, a bot takes the seat and the game starts with it
+	session.control(func(gameSession *GameSession) (bool, error) {
+		gameSession.fillSeats()
+		return false, nil
+	})
+	var joined OnBotJoined
+	json.Unmarshal([]byte(expectAction(t, daveConn, ON_BOT_JOINED).Data), &joined)
+	if joined.Replaced != dan.Email || !joined.Bot.Bot || joined.Bot.Name != "Bot 1 (hard)" {
+		t.Errorf("bot joined = %+v, want a bot in the seat of dan", joined)
+	}
+	var connected Player
+	json.Unmarshal([]byte(expectAction(t, daveConn, ON_USER_CONNECTED).Data), &connected)
+	if connected.Email != joined.Bot.Email || !connected.Bot {
+		t.Errorf("player connected = %+v, want the bot flagged", connected)
+	}
+
+	//the bot plays until the game is over, only dave is rated
+	expectAction(t, daveConn, ON_GAME_OVER)
+	select {
+	case <-session.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the session didn't end")
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.players) != 1 || recorder.players[0].Email != dave.Email {
+		t.Errorf("players rated = %+v, want only dave", recorder.players)
+	}
+}
+
+func TestGameSession_anyPlayerConnected(t *testing.T) {
+	manager := newGameManager()
+	session := manager.newGameSession(counterGame, "session")
+	session.addUsersToSession([]Player{{Name: "Bot 1 (easy)", Email: "bot1@" + botEmailDomain, Bot: true}})
+	session.Players["bot1@"+botEmailDomain].Conn = newFakeConn(false)
+	if session.anyPlayerConnected() {
+		t.Error("a session with only a bot left has a player connected")
+	}
+	session.addUsersToSession([]Player{{ID: 1, Name: "dave", Email: "dave123@gmail.com"}})
+	session.Players["dave123@gmail.com"].Conn = newFakeConn(false)
+	if !session.anyPlayerConnected() {
+		t.Error("dave is not connected")
+	}
+}
+
+//playersLogic is a counter game that keeps its players
+type playersLogic struct {
+	counterLogic
+	players []Player
+}
+
+func (logic *playersLogic) Players() []Player { return logic.players }
+
+func TestGameSession_recordResult(t *testing.T) {
+	recorder := &recordingRecorder{}
+	manager := newGameManager()
+	manager.SetResultRecorder(recorder)
+	session := manager.newGameSession(counterGame, "session")
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	bot := Player{Name: "Bot 1 (easy)", Email: "bot1@" + botEmailDomain, Bot: true}
+	session.logic = &playersLogic{players: []Player{dave, dan, bot}}
+	//dan left before the end
+	session.addUsersToSession([]Player{dave, bot})
+
+	session.recordResult(GameResult{Winners: []Player{dave}})
+	if len(recorder.players) != 2 || recorder.players[0] != dave || recorder.players[1] != dan {
+		t.Errorf("players rated = %+v, want dave and dan", recorder.players)
+	}
+}
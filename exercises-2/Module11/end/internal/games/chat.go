@@ -0,0 +1,272 @@
+package games
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//ChatConfig is how the players chat in the sessions
+type ChatConfig struct {
+	//Scrollback is how many of the last chat messages the players joining a session get
+	Scrollback int
+	//Rate is how many messages a player can send per second once it has sent Burst of them at once
+	Rate  float64
+	Burst int
+}
+
+//DefaultChatConfig type
+var DefaultChatConfig = ChatConfig{
+	Scrollback: 50,
+	Rate:       1,
+	Burst:      5,
+}
+
+//Validate checks the config can be used
+func (config ChatConfig) Validate() error {
+	if config.Scrollback < 0 {
+		return errors.New("the chat scrollback can't be negative")
+	}
+	if config.Rate <= 0 || config.Burst <= 0 {
+		return errors.New("the chat rate and burst must be positive")
+	}
+	return nil
+}
+
+var (
+	//ErrMuted is returned for the chat messages of a player the host muted
+	ErrMuted = NewGameError(ErrCodeNotAllowed, "you are muted")
+	//ErrChatRateLimited is returned for the chat messages of a player sending them too fast
+	ErrChatRateLimited = NewGameError(ErrCodeRateLimited, "you are sending messages too fast")
+)
+
+//ChatMessage is sent by a player to chat with everyone in the session
+type ChatMessage struct {
+	Text string `json:"text"`
+}
+
+//Validate type
+func (chat *ChatMessage) Validate() error {
+	if strings.TrimSpace(chat.Text) == "" {
+		return errors.New("the message is empty")
+	}
+	return nil
+}
+
+//MutePlayer is sent by the host to mute or unmute a player
+type MutePlayer struct {
+	Email string `json:"email"`
+}
+
+//Validate type
+func (mute *MutePlayer) Validate() error {
+	if mute.Email == "" {
+		return errors.New("the email of the player is missing")
+	}
+	return nil
+}
+
+func init() {
+	RegisterPayload(CHAT_MESSAGE, 1, func() interface{} { return &ChatMessage{} })
+	RegisterPayload(MUTE_PLAYER, 1, func() interface{} { return &MutePlayer{} })
+	RegisterPayload(UNMUTE_PLAYER, 1, func() interface{} { return &MutePlayer{} })
+}
+
+//OnChatMessage is sent to everyone in the session, the sender included, once the message went through the filters
+type OnChatMessage struct {
+	From Player    `json:"from"`
+	Text string    `json:"text"`
+	Time time.Time `json:"time"`
+}
+
+//OnChatHistory holds the last chat messages of the session, it is sent to the players joining it
+type OnChatHistory struct {
+	Messages []OnChatMessage `json:"messages"`
+}
+
+//OnPlayerMuted is sent to everyone in the session when the host mutes or unmutes a player
+type OnPlayerMuted struct {
+	Email string `json:"email"`
+}
+
+//ChatFilter checks a chat message before it is sent, it returns the text to send
+//or the error telling the player why the message was not sent
+type ChatFilter interface {
+	Filter(from Player, text string) (string, error)
+}
+
+//ChatFilterFunc type
+type ChatFilterFunc func(from Player, text string) (string, error)
+
+func (f ChatFilterFunc) Filter(from Player, text string) (string, error) {
+	return f(from, text)
+}
+
+//MaxLengthFilter rejects the messages longer than max characters
+func MaxLengthFilter(max int) ChatFilter {
+	return ChatFilterFunc(func(from Player, text string) (string, error) {
+		if utf8.RuneCountInString(text) > max {
+			return "", NewGameError(ErrCodeNotAllowed, fmt.Sprintf("the message is longer than %d characters", max))
+		}
+		return text, nil
+	})
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+|\b[a-z0-9-]+\.(com|net|org|io|gg|ly)\b`)
+
+//LinkFilter rejects the messages with links
+var LinkFilter = ChatFilterFunc(func(from Player, text string) (string, error) {
+	if linkPattern.MatchString(text) {
+		return "", NewGameError(ErrCodeNotAllowed, "links are not allowed in the chat")
+	}
+	return text, nil
+})
+
+//WordListFilter masks the words of the list, whatever their case
+func WordListFilter(words []string) ChatFilter {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return ChatFilterFunc(func(from Player, text string) (string, error) { return text, nil })
+	}
+	pattern := regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	return ChatFilterFunc(func(from Player, text string) (string, error) {
+		return pattern.ReplaceAllStringFunc(text, func(word string) string {
+			return strings.Repeat("*", utf8.RuneCountInString(word))
+		}), nil
+	})
+}
+
+//SetChatConfig sets how the players chat and the filters their messages go through in order,
+//it has to be called before Run
+func (manager *GameManager) SetChatConfig(config ChatConfig, filters ...ChatFilter) {
+	manager.chatConfig = config
+	manager.chatFilters = filters
+}
+
+//tokenBucket lets through burst messages at once and then rate messages per second
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (bucket *tokenBucket) allow(rate float64, burst int, now time.Time) bool {
+	if bucket.last.IsZero() {
+		bucket.tokens = float64(burst)
+	} else {
+		bucket.tokens += now.Sub(bucket.last).Seconds() * rate
+		if bucket.tokens > float64(burst) {
+			bucket.tokens = float64(burst)
+		}
+	}
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+//chatRoom is the chat of a session, it is only used by the session loop
+type chatRoom struct {
+	scrollback []OnChatMessage
+	buckets    map[string]*tokenBucket
+	muted      map[string]bool
+}
+
+func newChatRoom() chatRoom {
+	return chatRoom{
+		buckets: make(map[string]*tokenBucket),
+		muted:   make(map[string]bool),
+	}
+}
+
+//chat sends the message of the player to everyone once it went through the filters of the manager
+func (gameSession *GameSession) chat(gameMsg *GameMsg, chat *ChatMessage) {
+	player, ok := gameSession.Players[gameMsg.Player.Email]
+	if !ok {
+		return
+	}
+	manager := gameSession.gameManager
+	room := &gameSession.chatRoom
+	if room.muted[player.Email] {
+		player.sendError(ErrMuted, ErrCodeNotAllowed, gameMsg)
+		return
+	}
+	now := time.Now()
+	bucket, ok := room.buckets[player.Email]
+	if !ok {
+		bucket = &tokenBucket{}
+		room.buckets[player.Email] = bucket
+	}
+	if !bucket.allow(manager.chatConfig.Rate, manager.chatConfig.Burst, now) {
+		player.sendError(ErrChatRateLimited, ErrCodeRateLimited, gameMsg)
+		return
+	}
+
+	from := Player{ID: player.ID, Name: player.Name, Email: player.Email}
+	text := chat.Text
+	for _, filter := range manager.chatFilters {
+		var err error
+		if text, err = filter.Filter(from, text); err != nil {
+			player.sendError(err, ErrCodeNotAllowed, gameMsg)
+			return
+		}
+	}
+
+	onChat := OnChatMessage{From: from, Text: text, Time: now}
+	if size := manager.chatConfig.Scrollback; size > 0 {
+		room.scrollback = append(room.scrollback, onChat)
+		if len(room.scrollback) > size {
+			room.scrollback = room.scrollback[len(room.scrollback)-size:]
+		}
+	}
+	//the chat is broadcast as the other messages so it is in the replay of the session
+	msg, err := WrapCommand(ON_CHAT_MESSAGE, onChat, Player{})
+	if err != nil {
+		return
+	}
+	gameSession.sendMsgToPlayers(&msg)
+}
+
+//mute lets only the host mute or unmute the players of the session
+func (gameSession *GameSession) mute(gameMsg *GameMsg, email string, muted bool) {
+	player, ok := gameSession.Players[gameMsg.Player.Email]
+	if !ok {
+		return
+	}
+	if player.Email != gameSession.host {
+		player.sendError(NewGameError(ErrCodeNotAllowed, "only the host can mute the players"), ErrCodeNotAllowed, gameMsg)
+		return
+	}
+	if _, ok := gameSession.Players[email]; !ok || email == gameSession.host {
+		player.sendError(NewGameError(ErrCodeInvalidRequest, "no such player to mute"), ErrCodeInvalidRequest, gameMsg)
+		return
+	}
+	action := GameAction(ON_PLAYER_MUTED)
+	if muted {
+		gameSession.chatRoom.muted[email] = true
+	} else {
+		delete(gameSession.chatRoom.muted, email)
+		action = ON_PLAYER_UNMUTED
+	}
+	msg, _ := WrapCommand(action, OnPlayerMuted{Email: email}, Player{})
+	gameSession.sendMsgToPlayers(&msg)
+}
+
+//chatHistory returns the scrollback for a player joining the session, false when there is none
+func (gameSession *GameSession) chatHistory() (GameMsg, bool) {
+	if len(gameSession.chatRoom.scrollback) == 0 {
+		return GameMsg{}, false
+	}
+	history := OnChatHistory{Messages: append([]OnChatMessage(nil), gameSession.chatRoom.scrollback...)}
+	msg, err := WrapCommand(ON_CHAT_HISTORY, history, Player{})
+	return msg, err == nil
+}
@@ -0,0 +1,176 @@
+package games
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestChatFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  ChatFilter
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{name: "short enough", filter: MaxLengthFilter(5), text: "héllo", want: "héllo"},
+		{name: "too long", filter: MaxLengthFilter(5), text: "hello!", wantErr: true},
+		{name: "no link", filter: LinkFilter, text: "good game.", want: "good game."},
+		{name: "url", filter: LinkFilter, text: "see https://cheat.example", wantErr: true},
+		{name: "domain", filter: LinkFilter, text: "go to cheats.com now", wantErr: true},
+		{name: "masked words", filter: WordListFilter([]string{"noob", " darn "}), text: "Noob, darn it", want: "****, **** it"},
+		{name: "part of a word", filter: WordListFilter([]string{"ass"}), text: "pass", want: "pass"},
+		{name: "empty list", filter: WordListFilter([]string{""}), text: "noob", want: "noob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.Filter(Player{}, tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Filter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Filter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	config := ChatConfig{Rate: 2, Burst: 3}
+	bucket := &tokenBucket{}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if !bucket.allow(config.Rate, config.Burst, now) {
+			t.Fatalf("message %d of the burst was not allowed", i)
+		}
+	}
+	if bucket.allow(config.Rate, config.Burst, now) {
+		t.Error("a message past the burst was allowed")
+	}
+	if !bucket.allow(config.Rate, config.Burst, now.Add(500*time.Millisecond)) {
+		t.Error("a message was not allowed once a token came back")
+	}
+	if !bucket.allow(config.Rate, config.Burst, now.Add(time.Hour)) || !bucket.allow(config.Rate, config.Burst, now.Add(time.Hour)) ||
+		!bucket.allow(config.Rate, config.Burst, now.Add(time.Hour)) || bucket.allow(config.Rate, config.Burst, now.Add(time.Hour)) {
+		t.Error("the bucket is not refilled up to the burst")
+	}
+}
+
+//chatPlayer adds a connected player to the session whose messages are kept in its outbox
+func chatPlayer(session *GameSession, id uint, name string, email string) *Player {
+	player := session.CreateNewPlayer(nil, id, name, email)
+	player.Conn = newFakeConn(false)
+	player.outbox = newOutbox(100, OverflowDisconnect)
+	session.Players[email] = player
+	if session.host == "" {
+		session.host = email
+	}
+	return player
+}
+
+//received returns the messages queued for the player with the action
+func received(player *Player, action GameAction) []GameMsg {
+	msgs, _ := player.outbox.take()
+	var found []GameMsg
+	for _, msg := range msgs {
+		if msg.GameAction == action {
+			found = append(found, msg)
+		}
+	}
+	return found
+}
+
+func sendChat(session *GameSession, from *Player, text string) {
+	msg, _ := WrapCommand(CHAT_MESSAGE, ChatMessage{Text: text}, *from)
+	if err := DecodePayload(session.Game.ID, &msg); err != nil {
+		panic(err)
+	}
+	session.chat(&msg, msg.Payload.(*ChatMessage))
+}
+
+func TestGameSession_chat(t *testing.T) {
+	replays := NewMemoryReplayLog()
+	manager := newGameManager()
+	manager.SetReplayLog(replays)
+	manager.SetChatConfig(ChatConfig{Scrollback: 2, Rate: 1, Burst: 2}, WordListFilter([]string{"noob"}), LinkFilter)
+	session := manager.newGameSession(Game{ID: "chess"}, "session")
+
+	dave := chatPlayer(session, 1, "dave", "dave123@gmail.com")
+	dan := chatPlayer(session, 2, "dan", "dan@gmail.com")
+
+	sendChat(session, dan, "hi noob")
+	chats := received(dave, ON_CHAT_MESSAGE)
+	if len(chats) != 1 {
+		t.Fatalf("dave got %d chat messages, want 1", len(chats))
+	}
+	var chat OnChatMessage
+	json.Unmarshal([]byte(chats[0].Data), &chat)
+	if chat.Text != "hi ****" || chat.From.Email != dan.Email {
+		t.Errorf("dave got %+v", chat)
+	}
+	if len(received(dan, ON_CHAT_MESSAGE)) != 1 {
+		t.Error("dan didn't get its own message back")
+	}
+
+	//a blocked message and the ones past the rate limit are only sent back to the sender as errors,
+	//the blocked ones count in the rate
+	sendChat(session, dan, "www.cheats.example")
+	sendChat(session, dan, "gg")
+	if chats := received(dave, ON_CHAT_MESSAGE); len(chats) != 0 {
+		t.Errorf("dave got %d chat messages, want none", len(chats))
+	}
+	if errs := received(dan, ON_ERROR); len(errs) != 2 {
+		t.Errorf("dan got %d errors, want the link and the rate limit", len(errs))
+	}
+
+	//only the host mutes the players
+	mute := func(from *Player, email string, muted bool) {
+		msg, _ := WrapCommand(MUTE_PLAYER, MutePlayer{Email: email}, *from)
+		session.mute(&msg, email, muted)
+	}
+	mute(dan, dave.Email, true)
+	if errs := received(dan, ON_ERROR); len(errs) != 1 || session.chatRoom.muted[dave.Email] {
+		t.Error("a player who is not the host muted the host")
+	}
+	mute(dave, dan.Email, true)
+	if len(received(dan, ON_PLAYER_MUTED)) != 1 {
+		t.Error("the players were not told dan is muted")
+	}
+	session.chatRoom.buckets = make(map[string]*tokenBucket)
+	sendChat(session, dan, "let me talk")
+	if errs := received(dan, ON_ERROR); len(errs) != 1 || len(received(dave, ON_CHAT_MESSAGE)) != 0 {
+		t.Error("a muted player could chat")
+	}
+	mute(dave, dan.Email, false)
+	sendChat(session, dan, "thanks")
+	sendChat(session, dave, "np")
+	if len(received(dave, ON_CHAT_MESSAGE)) != 2 {
+		t.Error("an unmuted player couldn't chat")
+	}
+
+	//a player joining gets the scrollback
+	late := chatPlayer(session, 3, "bob", "bob@gmail.com")
+	session.syncPlayer(late)
+	history := received(late, ON_CHAT_HISTORY)
+	if len(history) != 1 {
+		t.Fatal("the player joining didn't get the scrollback")
+	}
+	var scrollback OnChatHistory
+	json.Unmarshal([]byte(history[0].Data), &scrollback)
+	if len(scrollback.Messages) != 2 || scrollback.Messages[0].Text != "thanks" || scrollback.Messages[1].Text != "np" {
+		t.Errorf("scrollback = %+v, want the last 2 messages", scrollback.Messages)
+	}
+
+	//the chat is in the replay
+	entries, _ := replays.Read(session.ID)
+	chatEntries := 0
+	for _, entry := range entries {
+		if entry.Msg.GameAction == ON_CHAT_MESSAGE {
+			chatEntries++
+		}
+	}
+	if chatEntries != 3 {
+		t.Errorf("the replay has %d chat messages, want 3", chatEntries)
+	}
+}
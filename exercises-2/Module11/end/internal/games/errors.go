@@ -0,0 +1,84 @@
+package games
+
+import (
+	"github.com/someuser/gameserver/internal/games/wire"
+)
+
+//ErrorCode is the machine readable reason of an error sent to the clients
+type ErrorCode string
+
+const (
+	ErrCodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	ErrCodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	ErrCodeGameNotFound       ErrorCode = "GAME_NOT_FOUND"
+	ErrCodeSessionNotFound    ErrorCode = "SESSION_NOT_FOUND"
+	ErrCodeInvitationNotFound ErrorCode = "INVITATION_NOT_FOUND"
+	ErrCodeNotInvited         ErrorCode = "NOT_INVITED"
+	ErrCodeNotAllowed         ErrorCode = "NOT_ALLOWED"
+	ErrCodeWrongPassphrase    ErrorCode = "WRONG_PASSPHRASE"
+	ErrCodeNotYourTurn        ErrorCode = "NOT_YOUR_TURN"
+	ErrCodeInvalidMove        ErrorCode = "INVALID_MOVE"
+	ErrCodeInvalidMessage     ErrorCode = "INVALID_MESSAGE"
+	ErrCodeRateLimited        ErrorCode = "RATE_LIMITED"
+	ErrCodeProtocolMismatch   ErrorCode = "PROTOCOL_MISMATCH"
+	ErrCodeUnavailable        ErrorCode = "UNAVAILABLE"
+	ErrCodeInternal           ErrorCode = "INTERNAL"
+)
+
+//GameError is an error with the code the clients get
+type GameError struct {
+	Code    ErrorCode
+	Message string
+}
+
+//NewGameError type
+func NewGameError(code ErrorCode, message string) *GameError {
+	return &GameError{Code: code, Message: message}
+}
+
+func (err *GameError) Error() string {
+	return err.Message
+}
+
+var (
+	//ErrNotYourTurn is returned for the moves of a player whose turn it is not
+	ErrNotYourTurn = NewGameError(ErrCodeNotYourTurn, "not your turn")
+	//ErrGameNotSupported is returned for the games that are not in the catalog
+	ErrGameNotSupported = NewGameError(ErrCodeGameNotFound, "Game Is Not Supported")
+)
+
+//ErrorCodeOf returns the code of the error, fallback for the errors that don't have one
+func ErrorCodeOf(err error, fallback ErrorCode) ErrorCode {
+	switch err := err.(type) {
+	case *GameError:
+		return err.Code
+	case *PayloadError, *wire.DecodeError:
+		return ErrCodeInvalidMessage
+	}
+	if err == ErrSessionEnded {
+		return ErrCodeSessionNotFound
+	}
+	return fallback
+}
+
+//OnError is sent to a player whose message failed, Action and Data are the message when there is one
+type OnError struct {
+	Code    ErrorCode  `json:"code"`
+	Message string     `json:"message"`
+	Action  GameAction `json:"action,omitempty"`
+	Data    string     `json:"data,omitempty"`
+}
+
+//sendError lets the player know why its message failed, fallback is the code of the errors that don't have one
+func (player *Player) sendError(err error, fallback ErrorCode, failed *GameMsg) {
+	onError := OnError{Code: ErrorCodeOf(err, fallback), Message: err.Error()}
+	if failed != nil {
+		onError.Action = failed.GameAction
+		onError.Data = failed.Data
+	}
+	msg, wrapErr := WrapCommand(ON_ERROR, onError, Player{})
+	if wrapErr != nil {
+		return
+	}
+	player.SendMessage(&msg)
+}
@@ -0,0 +1,31 @@
+package games
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/someuser/gameserver/internal/games/wire"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCode
+	}{
+		{err: ErrNotYourTurn, want: ErrCodeNotYourTurn},
+		{err: ErrGameNotSupported, want: ErrCodeGameNotFound},
+		{err: ErrUnsupportedVersion, want: ErrCodeProtocolMismatch},
+		{err: ErrSessionEnded, want: ErrCodeSessionNotFound},
+		{err: &PayloadError{Action: START_GAME, Err: errors.New("no players")}, want: ErrCodeInvalidMessage},
+		{err: &wire.DecodeError{Err: errors.New("bad json")}, want: ErrCodeInvalidMessage},
+		{err: errors.New("card already flipped"), want: ErrCodeInvalidMove},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.err), func(t *testing.T) {
+			if got := ErrorCodeOf(tt.err, ErrCodeInvalidMove); got != tt.want {
+				t.Errorf("ErrorCodeOf() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
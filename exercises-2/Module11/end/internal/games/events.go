@@ -0,0 +1,49 @@
+package games
+
+import (
+	"log"
+)
+
+//the events of the sessions told to the EventPublisher
+const (
+	EventSessionCreated = "session.created"
+	EventPlayerJoined   = "player.joined"
+	EventPlayerLeft     = "player.left"
+	EventGameEnded      = "game.ended"
+)
+
+//EventPublisher lets the services outside of the server, like the webhooks, know what happens in the sessions
+type EventPublisher interface {
+	Publish(eventType string, data interface{}) error
+}
+
+//SessionEvent is the data of the events of the sessions, Player is who joined or left
+//and Result how the game ended
+type SessionEvent struct {
+	SessionID string      `json:"sessionId"`
+	GameID    string      `json:"gameId"`
+	Player    *Player     `json:"player,omitempty"`
+	Players   []Player    `json:"players,omitempty"`
+	Result    *GameResult `json:"result,omitempty"`
+}
+
+//SetEventPublisher sets who is told about the events of the sessions, it has to be called before Run
+func (manager *GameManager) SetEventPublisher(events EventPublisher) {
+	manager.events = events
+}
+
+//publish tells the publisher of the manager about the event, the players of the session are added to it
+func (gameSession *GameSession) publish(eventType string, event SessionEvent) {
+	events := gameSession.gameManager.events
+	if events == nil {
+		return
+	}
+	event.SessionID = gameSession.ID
+	event.GameID = gameSession.Game.ID
+	for _, player := range gameSession.Players {
+		event.Players = append(event.Players, Player{ID: player.ID, Name: player.Name, Email: player.Email})
+	}
+	if err := events.Publish(eventType, event); err != nil {
+		log.Printf("couldn't publish %s of session %s: %v", eventType, gameSession.ID, err)
+	}
+}
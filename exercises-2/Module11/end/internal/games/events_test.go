@@ -0,0 +1,75 @@
+package games
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+//recordingPublisher keeps the events published
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []string
+	data   []SessionEvent
+}
+
+func (publisher *recordingPublisher) Publish(eventType string, data interface{}) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	publisher.events = append(publisher.events, eventType)
+	publisher.data = append(publisher.data, data.(SessionEvent))
+	return nil
+}
+
+func (publisher *recordingPublisher) published() ([]string, []SessionEvent) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	return append([]string(nil), publisher.events...), append([]SessionEvent(nil), publisher.data...)
+}
+
+//waitFor waits for count events to be published
+func (publisher *recordingPublisher) waitFor(count int) ([]string, []SessionEvent) {
+	deadline := time.Now().Add(2 * time.Second)
+	events, data := publisher.published()
+	for len(events) < count && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		events, data = publisher.published()
+	}
+	return events, data
+}
+
+func TestGameSession_events(t *testing.T) {
+	publisher := &recordingPublisher{}
+	manager := newGameManager()
+	manager.games[counterGame.ID] = counterGame
+	manager.SetEventPublisher(publisher)
+	go manager.Run()
+
+	session := manager.CreateNewGameSession(counterGame)
+	go session.Run()
+	conn := newFakeConn(false)
+	session.CreateNewPlayer(nil, 1, "dave", "dave123@gmail.com").Start(conn)
+	publisher.waitFor(2)
+	conn.Close()
+	publisher.waitFor(3)
+	session.End("game over")
+
+	want := []string{EventSessionCreated, EventPlayerJoined, EventPlayerLeft, EventGameEnded}
+	events, data := publisher.waitFor(len(want))
+	if len(events) != len(want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	for i, event := range want {
+		if events[i] != event || data[i].SessionID != session.ID || data[i].GameID != counterGame.ID {
+			t.Errorf("event %d = %s %+v, want %s of the session", i, events[i], data[i], event)
+		}
+	}
+	if player := data[1].Player; player == nil || player.Email != "dave123@gmail.com" {
+		t.Errorf("player who joined = %+v", player)
+	}
+	if result := data[3].Result; result == nil || result.Message != "game over" {
+		t.Errorf("result = %+v, want the reason the session was ended", result)
+	}
+}
@@ -1,8 +1,36 @@
 package games
 
+import "encoding/json"
+
 //Game Type
 type Game struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Version     string `json:"version,omitempty"`
+	MinPlayers  int    `json:"minPlayers,omitempty"`
+	MaxPlayers  int    `json:"maxPlayers,omitempty"`
+	//SpectatorDelay holds back what spectators see by that many seconds
+	SpectatorDelay int `json:"spectatorDelaySeconds,omitempty"`
+	//Turns enables the turn timer of the games whose rules are implemented as a TurnBasedGameLogic
+	Turns *TurnConfig `json:"turns,omitempty"`
+	//Lifecycle sets how long the sessions wait for the players and when they start and end
+	Lifecycle *LifecycleConfig `json:"lifecycle,omitempty"`
+	//Bots lets bots take the seats of the invited players who don't join in time, the game needs a lifecycle with
+	//autoStart as the seats can only be filled while the START_GAME is held back in the lobby
+	Bots *BotConfig `json:"bots,omitempty"`
+	//Options describes the game data the host can send when starting the game, it is passed as is to the clients
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+//TurnConfig sets how long the players of a turn based game have to play
+type TurnConfig struct {
+	//SecondsPerTurn is the time a player has for each move, with a bank it is added to the bank at every turn
+	SecondsPerTurn int `json:"secondsPerTurn,omitempty"`
+	//BankSeconds turns the timer into a chess clock, each player has this much time for the whole game
+	BankSeconds int `json:"bankSeconds,omitempty"`
+	//WarnSeconds lets the players know when the player to play has only that many seconds left
+	WarnSeconds int `json:"warnSeconds,omitempty"`
+	//OnTimeout is what happens to a player who runs out of time, TurnTimeoutSkip or TurnTimeoutForfeit
+	OnTimeout string `json:"onTimeout,omitempty"`
 }
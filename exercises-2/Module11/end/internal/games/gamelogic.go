@@ -0,0 +1,80 @@
+package games
+
+import (
+	"sync"
+)
+
+//GameLogic is implemented by games whose rules are enforced by the server.
+//a session holding a GameLogic no longer relays GAME_PLAY messages as is,
+//it validates and applies them and broadcasts the resulting state instead
+type GameLogic interface {
+	//Start initializes a new game for the given players, options is the game data sent by the host
+	Start(players []Player, options string) error
+	//ValidateMove checks that the player is allowed to make the move in the current state
+	ValidateMove(player Player, move *GameMsg) error
+	//ApplyMove applies a validated move and computes the next state of the game
+	ApplyMove(player Player, move *GameMsg) error
+	//State returns the current state of the game as it should be seen by the players
+	State() string
+	//GameOver reports if the game has ended and if so its result
+	GameOver() (bool, GameResult)
+}
+
+//PlayersGameLogic is implemented by the game logics that keep the players they were started with,
+//the ones who left the session before the game was over are then rated with the others
+type PlayersGameLogic interface {
+	GameLogic
+	//Players returns the players the game was started with, the ones who left or forfeited included
+	Players() []Player
+}
+
+//GameResult is sent as the payload of ON_GAME_OVER when the rules decide the game has ended
+type GameResult struct {
+	Winners []Player       `json:"winners"`
+	Scores  map[string]int `json:"scores,omitempty"`
+	Message string         `json:"message,omitempty"`
+}
+
+//ResultRecorder is told the result of the games decided by the server rules
+type ResultRecorder interface {
+	RecordResult(gameID string, sessionID string, players []Player, result GameResult) error
+}
+
+//Inviter lets the users a host invited to a session know about it
+type Inviter interface {
+	SendGameInvitation(sessionID string, game Game, host Player, invited []Player) error
+}
+
+//GameLogicFactory creates a new instance of a game logic for a single session
+type GameLogicFactory func() GameLogic
+
+var (
+	gameLogicsMu sync.RWMutex
+	gameLogics   = make(map[string]GameLogicFactory)
+)
+
+//RegisterGameLogic makes a game logic available for the game with the given id,
+//it is meant to be called from the init function of the package implementing the game
+func RegisterGameLogic(gameID string, factory GameLogicFactory) {
+	gameLogicsMu.Lock()
+	defer gameLogicsMu.Unlock()
+
+	if factory == nil {
+		panic("games: RegisterGameLogic factory is nil")
+	}
+	if _, dup := gameLogics[gameID]; dup {
+		panic("games: RegisterGameLogic called twice for game " + gameID)
+	}
+	gameLogics[gameID] = factory
+}
+
+//newGameLogic returns a new game logic for the game or nil if the game is only played on the client
+func newGameLogic(gameID string) GameLogic {
+	gameLogicsMu.RLock()
+	defer gameLogicsMu.RUnlock()
+
+	if factory, ok := gameLogics[gameID]; ok {
+		return factory()
+	}
+	return nil
+}
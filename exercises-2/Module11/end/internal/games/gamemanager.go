@@ -1,12 +1,20 @@
 package games
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type GetSession struct {
@@ -21,26 +29,86 @@ type GameManager struct {
 
 	getSessionChannel chan *GetSession
 
+	listSessions chan chan []*GameSession
+
+	adopt chan *adoption
+
 	activeGames map[string]*GameSession
 
-	game Game
+	//games is the catalog of supported games, it is only written when the manager is created
+	games map[string]Game
+
+	store SessionStore
+
+	results ResultRecorder
+
+	inviter Inviter
+
+	replays ReplayLog
+
+	ownership SessionOwnership
+
+	connConfig ConnConfig
+
+	chatConfig ChatConfig
+
+	chatFilters []ChatFilter
+
+	joinCodes JoinCodeStore
+
+	joinCodeTTL time.Duration
+
+	rateLimits RateLimitConfig
+
+	bans BanStore
+
+	events EventPublisher
+
+	//draining is closed once the server is shutting down
+	draining chan struct{}
+
+	drainOnce *sync.Once
 }
 
 func CreateGameManager() GameManager {
 
-	manager := GameManager{
+	manager := newGameManager()
+
+	manager.loadGameConfig()
+
+	return manager
+}
+
+func newGameManager() GameManager {
+	return GameManager{
 		register:          make(chan *GameSession),
 		unRegister:        make(chan *GameSession),
 		getSessionChannel: make(chan *GetSession),
+		listSessions:      make(chan chan []*GameSession),
+		adopt:             make(chan *adoption),
 		activeGames:       make(map[string]*GameSession),
+		games:             make(map[string]Game),
+		connConfig:        DefaultConnConfig,
+		chatConfig:        DefaultChatConfig,
+		rateLimits:        DefaultRateLimitConfig,
+		bans:              NewMemoryBanStore(),
+		draining:          make(chan struct{}),
+		drainOnce:         &sync.Once{},
 	}
+}
 
-	manager.loadGameConfig()
+//SetConnConfig sets how the messages are sent to the players, it has to be called before Run
+func (manager *GameManager) SetConnConfig(config ConnConfig) {
+	manager.connConfig = config
+}
 
-	return manager
+//SetSessionStore sets where sessions are persisted, it has to be called before Run
+//which brings back the sessions found in the store
+func (manager *GameManager) SetSessionStore(store SessionStore) {
+	manager.store = store
 }
 
-func (manager *GameManager) loadGameConfig() (id string, name string, description string) {
+func (manager *GameManager) loadGameConfig() {
 	dir, _ := os.Getwd()
 
 	//first look for the GAME_SERVER_HOMEDIR
@@ -61,31 +129,252 @@ func (manager *GameManager) loadGameConfig() (id string, name string, descriptio
 		log.Fatalf("Error reading config file, %s", err)
 	}
 
-	manager.game = Game{
+	//the catalog holds a file per game, by default in the games folder next to the app.env
+	catalogDir := viper.GetString("GAME_CATALOG_DIR")
+	if catalogDir == "" {
+		catalogDir = filepath.Join(dir, "games")
+	}
+	fallback := Game{
 		ID:          viper.GetString("GAME_ID"),
 		Name:        viper.GetString("GAME_NAME"),
 		Description: viper.GetString("GAME_DESCRIPTION"),
 	}
+	catalog, err := loadGames(catalogDir, fallback)
+	if err != nil {
+		log.Fatalf("Error reading game catalog, %s", err)
+	}
+	for _, game := range catalog {
+		manager.games[game.ID] = game
+	}
+}
 
-	return
+//loadGames returns the games of the catalog in the directory, a single game configured in the app.env
+//is still supported when there is no catalog
+func loadGames(catalogDir string, fallback Game) ([]Game, error) {
+	catalog, err := loadGameCatalog(catalogDir)
+	if err != nil || len(catalog) > 0 {
+		return catalog, err
+	}
+	return []Game{fallback}, nil
 }
 
-//CreateNewGameSession type
-func (manager *GameManager) CreateNewGameSession() *GameSession {
-	game := &GameSession{
-		SendToGame:  make(chan *GameMsg),
-		Register:    make(chan *Player),
-		UnRegister:  make(chan *Player),
-		Players:     make(map[string]*Player),
-		ID:          uuid.New().String(),
-		gameManager: manager,
+//loadGameCatalog reads the game definitions from the json files in the directory,
+//a missing directory is an empty catalog
+func loadGameCatalog(dir string) ([]Game, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	manager.register <- game
+	var catalog []Game
+	ids := make(map[string]string)
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var game Game
+		if err := json.Unmarshal(data, &game); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if game.ID == "" {
+			return nil, fmt.Errorf("%s: game id is missing", path)
+		}
+		if other, ok := ids[game.ID]; ok {
+			return nil, fmt.Errorf("%s: game %s is already defined in %s", path, game.ID, other)
+		}
+		if game.MaxPlayers > 0 && game.MinPlayers > game.MaxPlayers {
+			return nil, fmt.Errorf("%s: minPlayers is greater than maxPlayers", path)
+		}
+		if turns := game.Turns; turns != nil && turns.OnTimeout != "" &&
+			turns.OnTimeout != TurnTimeoutSkip && turns.OnTimeout != TurnTimeoutForfeit {
+			return nil, fmt.Errorf("%s: unknown onTimeout %s", path, turns.OnTimeout)
+		}
+		if lifecycle := game.lifecycle(); lifecycle.LobbySeconds < 0 || lifecycle.IdleSeconds < 0 {
+			return nil, fmt.Errorf("%s: the lifecycle timeouts can't be negative", path)
+		}
+		if err := checkOptionsSchema(game.Options); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if err := game.bots().Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if game.Bots != nil && !game.lifecycle().AutoStart {
+			return nil, fmt.Errorf("%s: the bots need a lifecycle with autoStart", path)
+		}
+		ids[game.ID] = path
+		catalog = append(catalog, game)
+	}
+	return catalog, nil
+}
+
+//checkOptionsSchema checks the options of a game are a json schema of an object, the clients build the form
+//of the game data from its properties
+func checkOptionsSchema(options json.RawMessage) error {
+	if len(options) == 0 {
+		return nil
+	}
+	var schema struct {
+		Type       string                     `json:"type"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(options, &schema); err != nil {
+		return fmt.Errorf("invalid options schema: %v", err)
+	}
+	if schema.Type != "object" {
+		return errors.New("the options schema must be of type object")
+	}
+	return nil
+}
+
+//SetResultRecorder sets who records the results of the games, it has to be called before Run
+func (manager *GameManager) SetResultRecorder(recorder ResultRecorder) {
+	manager.results = recorder
+}
+
+//SetInviter sets who sends the invitations of the hosts, it has to be called before Run
+func (manager *GameManager) SetInviter(inviter Inviter) {
+	manager.inviter = inviter
+}
 
+//CreateNewGameSession creates a session for the game, if the game has its rules
+//implemented on the server the session will enforce them
+func (manager *GameManager) CreateNewGameSession(g Game) *GameSession {
+	//a public session without a passphrase is always created
+	game, _ := manager.CreateSession(g, SessionOptions{})
 	return game
 }
 
+//CreateSession creates a session for the game with the options of the host, it gets a join code
+//when the manager has a join code store. A private session fails without one as no one could join it
+func (manager *GameManager) CreateSession(g Game, options SessionOptions) (*GameSession, error) {
+	game := manager.newGameSession(g, uuid.New().String())
+	game.Private = options.Private
+	if options.Passphrase != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(options.Passphrase), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		game.passphrase = hash
+	}
+	if manager.joinCodes != nil {
+		code, err := manager.reserveJoinCode(game.ID)
+		if err != nil && options.Private {
+			return nil, err
+		}
+		if err != nil {
+			log.Printf("couldn't get a join code for session %s: %v", game.ID, err)
+		}
+		game.JoinCode = code
+	} else if options.Private {
+		return nil, errors.New("private sessions need join codes")
+	}
+	if err := manager.claimSession(game.ID); err != nil {
+		log.Printf("couldn't claim session %s: %v", game.ID, err)
+	}
+	game.persist()
+
+	manager.register <- game
+	game.publish(EventSessionCreated, SessionEvent{})
+
+	return game, nil
+}
+
+//CreateMatchedSession creates and runs a session for players brought together by the matchmaking,
+//the first player hosts the game and the invited ones, like the members of the parties of the players, get an invitation
+func (manager *GameManager) CreateMatchedSession(g Game, players []Player, invited []Player) (string, error) {
+	if len(players) == 0 {
+		return "", errors.New("can't create a session without players")
+	}
+	if manager.Draining() {
+		return "", ErrShuttingDown
+	}
+	session := manager.CreateNewGameSession(g)
+	//the matched players are told about the session by the matchmaking, they are not invited
+	for _, player := range players {
+		session.notified[player.Email] = true
+	}
+	go session.Run()
+
+	others := append(append([]Player(nil), players[1:]...), invited...)
+	startGame, err := WrapCommand(START_GAME, StartGameMsg{Players: others}, players[0])
+	if err != nil {
+		return "", err
+	}
+	session.SendToGame <- &startGame
+
+	return session.ID, nil
+}
+
+func (manager *GameManager) newGameSession(g Game, id string) *GameSession {
+	spectatorTimer := time.NewTimer(time.Hour)
+	spectatorTimer.Stop()
+	botTimer := time.NewTimer(time.Hour)
+	botTimer.Stop()
+
+	return &GameSession{
+		SendToGame:     make(chan *GameMsg),
+		Register:       make(chan *Player),
+		UnRegister:     make(chan *Player),
+		Players:        make(map[string]*Player),
+		Spectators:     make(map[string]*Player),
+		ID:             id,
+		Game:           g,
+		CreatedAt:      time.Now(),
+		logic:          newGameLogic(g.ID),
+		gameManager:    manager,
+		spectatorTimer: spectatorTimer,
+		notified:       make(map[string]bool),
+		botTimer:       botTimer,
+		declined:       make(chan string),
+		controls:       make(chan sessionControl),
+		turns:          newTurnClock(g.Turns),
+		state:          SessionLobby,
+		chatRoom:       newChatRoom(),
+		inbound:        &sessionLimiter{},
+		infoRequest:    make(chan chan SessionInfo),
+		done:           make(chan struct{}),
+	}
+}
+
+//restoreSessions brings back the sessions that were active when the server stopped, the records that
+//can't be restored are kept in the store
+func (manager *GameManager) restoreSessions() {
+	if manager.store == nil {
+		return
+	}
+	records, err := manager.store.GetSessions()
+	if err != nil {
+		log.Println("couldn't load the persisted sessions", err)
+		return
+	}
+	for _, record := range records {
+		//the sessions another server runs are left to it
+		if err := manager.claimSession(record.ID); err != nil {
+			continue
+		}
+		game, err := manager.GetGame(record.GameID)
+		if err == nil {
+			session := manager.newGameSession(game, record.ID)
+			if err = session.restore(record); err == nil {
+				manager.activeGames[session.ID] = session
+				go session.Run()
+				continue
+			}
+		}
+		log.Printf("couldn't restore session %s, skipping it: %v", record.ID, err)
+		manager.releaseSession(record.ID)
+	}
+	log.Printf("restored %d game sessions", len(manager.activeGames))
+}
+
 func (manager *GameManager) GetSessionByID(session string) *GameSession {
 	if manager.getSessionChannel != nil {
 		ch := make(chan *GameSession)
@@ -100,6 +389,8 @@ func (manager *GameManager) GetSessionByID(session string) *GameSession {
 
 func (manager *GameManager) Run() {
 
+	manager.restoreSessions()
+
 	for {
 		select {
 		case session := <-manager.register:
@@ -108,14 +399,30 @@ func (manager *GameManager) Run() {
 			delete(manager.activeGames, session.ID)
 		case getsession := <-manager.getSessionChannel:
 			getsession.gameSession <- manager.activeGames[getsession.sessionId]
+		case ch := <-manager.listSessions:
+			ch <- manager.activeSessions()
+		case adopted := <-manager.adopt:
+			manager.adoptSession(adopted)
 		}
 	}
 }
 
 func (manager *GameManager) GetGame(gameId string) (Game, error) {
 
-	if manager.game.ID == gameId {
-		return manager.game, nil
+	if game, ok := manager.games[gameId]; ok {
+		return game, nil
+	}
+	return Game{}, ErrGameNotSupported
+}
+
+//GetGames returns the catalog of supported games ordered by id
+func (manager *GameManager) GetGames() []Game {
+	catalog := make([]Game, 0, len(manager.games))
+	for _, game := range manager.games {
+		catalog = append(catalog, game)
 	}
-	return Game{}, errors.New("Game Is Not Supported")
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].ID < catalog[j].ID
+	})
+	return catalog
 }
@@ -0,0 +1,80 @@
+package games
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadGameCatalog(t *testing.T) {
+	const memory = `{"id": "memory", "minPlayers": 1, "maxPlayers": 4,
+		"options": {"type": "object", "properties": {"pairs": {"type": "integer"}}}}`
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantIDs []string
+		wantErr bool
+	}{
+		{name: "no catalog"},
+		{name: "games", files: map[string]string{
+			"memory.json": memory,
+			"chess.json":  `{"id": "chess", "minPlayers": 2, "maxPlayers": 2}`,
+			"README.md":   "not a game",
+		}, wantIDs: []string{"chess", "memory"}},
+		{name: "duplicate ids", files: map[string]string{
+			"memory.json": memory,
+			"other.json":  `{"id": "memory"}`,
+		}, wantErr: true},
+		{name: "missing id", files: map[string]string{"memory.json": `{"name": "memory"}`}, wantErr: true},
+		{name: "min greater than max", files: map[string]string{"memory.json": `{"id": "memory", "minPlayers": 3, "maxPlayers": 2}`},
+			wantErr: true},
+		{name: "options not an object", files: map[string]string{"memory.json": `{"id": "memory", "options": ["pairs"]}`},
+			wantErr: true},
+		{name: "options of another type", files: map[string]string{"memory.json": `{"id": "memory", "options": {"type": "string"}}`},
+			wantErr: true},
+		{name: "bots without autoStart", files: map[string]string{"memory.json": `{"id": "memory", "bots": {"difficulty": "easy"}}`},
+			wantErr: true},
+		{name: "invalid json", files: map[string]string{"memory.json": `{"id": `}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, _ := ioutil.TempDir("", "catalog")
+			defer os.RemoveAll(dir)
+			for name, content := range tt.files {
+				ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+			}
+
+			catalog, err := loadGameCatalog(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadGameCatalog() = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, game := range catalog {
+				ids = append(ids, game.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("games = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestLoadGames_fallback(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "catalog")
+	defer os.RemoveAll(dir)
+	fallback := Game{ID: "pokemoncards", Name: "Pokemon memory game"}
+
+	//the game of the app.env is used without a catalog
+	games, err := loadGames(filepath.Join(dir, "missing"), fallback)
+	if err != nil || len(games) != 1 || games[0].ID != fallback.ID || games[0].Name != fallback.Name {
+		t.Errorf("loadGames() without catalog = %+v, %v", games, err)
+	}
+
+	//and ignored once there is one
+	ioutil.WriteFile(filepath.Join(dir, "chess.json"), []byte(`{"id": "chess"}`), 0644)
+	if games, err := loadGames(dir, fallback); err != nil || len(games) != 1 || games[0].ID != "chess" {
+		t.Errorf("loadGames() with a catalog = %+v, %v", games, err)
+	}
+}
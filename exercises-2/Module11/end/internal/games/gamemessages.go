@@ -1,24 +1,62 @@
 package games
 
+import (
+	"errors"
+	"time"
+)
+
 type GameAction string
 
 const (
-	START_GAME              GameAction = "START_GAME"
-	GAME_PLAY                          = "GAME_PLAY"
-	UPDATE_GAME_STATE                  = "UPDATE_GAME_STATE"
-	ON_GAME_SESSION_CREATED            = "ON_GAME_SESSION_CREATED"
-	ON_GAME_OVER                       = "ON_GAME_OVER"
-	ON_GAME_INIT                       = "ON_GAME_INIT"
-	ON_USER_CONNECTED                  = "ON_USER_CONNECTED"
-	ON_USER_DISCONNECTED               = "ON_USER_DISCONNECTED"
+	START_GAME               GameAction = "START_GAME"
+	GAME_PLAY                           = "GAME_PLAY"
+	UPDATE_GAME_STATE                   = "UPDATE_GAME_STATE"
+	ON_GAME_SESSION_CREATED             = "ON_GAME_SESSION_CREATED"
+	ON_GAME_OVER                        = "ON_GAME_OVER"
+	ON_GAME_INIT                        = "ON_GAME_INIT"
+	ON_USER_CONNECTED                   = "ON_USER_CONNECTED"
+	ON_USER_DISCONNECTED                = "ON_USER_DISCONNECTED"
+	ON_GAME_STATE_CHANGED               = "ON_GAME_STATE_CHANGED"
+	ON_GAME_RESUMED                     = "ON_GAME_RESUMED"
+	ON_MATCHMAKING_QUEUED               = "ON_MATCHMAKING_QUEUED"
+	ON_MATCH_FOUND                      = "ON_MATCH_FOUND"
+	ON_GAME_INVITATION                  = "ON_GAME_INVITATION"
+	ON_INVITATION_DECLINED              = "ON_INVITATION_DECLINED"
+	ON_TURN_CHANGED                     = "ON_TURN_CHANGED"
+	ON_TURN_TIME_WARNING                = "ON_TURN_TIME_WARNING"
+	ON_TURN_TIMEOUT                     = "ON_TURN_TIMEOUT"
+	ON_REPLAY_MESSAGE                   = "ON_REPLAY_MESSAGE"
+	ON_REPLAY_ENDED                     = "ON_REPLAY_ENDED"
+	ON_SYSTEM_MESSAGE                   = "ON_SYSTEM_MESSAGE"
+	ON_PLAYER_KICKED                    = "ON_PLAYER_KICKED"
+	ON_ERROR                            = "ON_ERROR"
+	CHAT_MESSAGE                        = "CHAT_MESSAGE"
+	MUTE_PLAYER                         = "MUTE_PLAYER"
+	UNMUTE_PLAYER                       = "UNMUTE_PLAYER"
+	ON_CHAT_MESSAGE                     = "ON_CHAT_MESSAGE"
+	ON_CHAT_HISTORY                     = "ON_CHAT_HISTORY"
+	ON_PLAYER_MUTED                     = "ON_PLAYER_MUTED"
+	ON_PLAYER_UNMUTED                   = "ON_PLAYER_UNMUTED"
+	ON_SERVER_SHUTDOWN                  = "ON_SERVER_SHUTDOWN"
+	ON_SESSION_STATE_CHANGED            = "ON_SESSION_STATE_CHANGED"
+	ON_BOT_JOINED                       = "ON_BOT_JOINED"
 )
 
 type GameMsg struct {
 	GameAction `json:"action"`
 	Data       string `json:"data"`
 	Player     Player `json:"player"`
+	//Seq orders the messages broadcast by a session, clients send back the last one they got when rejoining
+	Seq uint64 `json:"seq,omitempty"`
+	//Version is the protocol version of the message
+	Version int `json:"version,omitempty"`
+	//Payload is the data decoded into the payload registered for the action
+	Payload interface{} `json:"-"`
 }
 
+//RawField lets the binary codecs carry the data as native maps and arrays, see wire.RawFielder
+func (gameMsg GameMsg) RawField() string { return "data" }
+
 //the create game happens through http
 
 type StartGameMsg struct {
@@ -26,7 +64,49 @@ type StartGameMsg struct {
 	GameData string   `json:"gamedata"`
 }
 
+func init() {
+	RegisterPayload(START_GAME, 1, func() interface{} { return &StartGameMsg{} })
+}
+
+//Validate checks the invited players can be told about the game
+func (startGame *StartGameMsg) Validate() error {
+	for _, player := range startGame.Players {
+		if player.Email == "" {
+			return errors.New("the email of an invited player is missing")
+		}
+	}
+	return nil
+}
+
+//OnGameResumed is sent to a player who rejoined once all the messages it missed were sent again
+type OnGameResumed struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+//OnInvitationDeclined is sent to the players of a session when an invited user declines to join
+type OnInvitationDeclined struct {
+	Email string `json:"email"`
+}
+
+//OnTurnChanged is sent when a player starts a turn, Banks holds the time left to each player of a chess clock
+type OnTurnChanged struct {
+	Player      Player             `json:"player"`
+	SecondsLeft float64            `json:"secondsLeft"`
+	Deadline    time.Time          `json:"deadline"`
+	Banks       map[string]float64 `json:"banks,omitempty"`
+}
+
+//OnTurnTimeout is sent when a player ran out of time, Action is what was done about it
+type OnTurnTimeout struct {
+	Player Player `json:"player"`
+	Action string `json:"action"`
+}
+
 type OnNewGameSessionCreated struct {
 	Game      `json:"game"`
 	SessionID string `json:"id"`
+	//JoinCode is what the host gives to the players to join, it is the only way into a private session
+	JoinCode string `json:"joinCode,omitempty"`
+	Private  bool   `json:"private,omitempty"`
 }
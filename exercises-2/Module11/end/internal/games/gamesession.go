@@ -1,13 +1,17 @@
 package games
 
 import (
+	"errors"
+	"log"
+	"sort"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 const (
-	maxGameStartTime = (30 * time.Minute)
+	//maxMsgHistory is how many broadcast messages are kept for players who reconnect
+	maxMsgHistory = 256
 )
 
 type GameSession struct {
@@ -15,9 +19,113 @@ type GameSession struct {
 	Register        chan *Player
 	UnRegister      chan *Player
 	Players         map[string]*Player
+	Spectators      map[string]*Player
 	ID              string
 	InitialGameData string
+	Game            Game
+	CreatedAt       time.Time
+	logic           GameLogic
 	gameManager     *GameManager
+	//seq is the sequence of the last broadcast message, history holds the most recent ones
+	seq     uint64
+	history []*GameMsg
+	//replayed is the number of messages recorded in the replay log
+	replayed int
+	//delayed holds the broadcasts waiting to be sent to spectators
+	delayed        []delayedMsg
+	spectatorTimer *time.Timer
+	infoRequest    chan chan SessionInfo
+	//controls are the requests of the operators
+	controls chan sessionControl
+	//declined receives the email of the invited users who declined to join
+	declined chan string
+	//turns times the turns of the players when the game has a time limit on them
+	turns *turnClock
+	//notified are the players the matchmaking told about the session, they are not invited
+	notified map[string]bool
+	//Private sessions are joined with their JoinCode, passphrase is the bcrypt hash of the passphrase of the session
+	Private    bool
+	JoinCode   string
+	passphrase []byte
+	//host is the email of the player who started the game, it can mute the others
+	host     string
+	chatRoom chatRoom
+	//inbound limits the messages of all the players together
+	inbound *sessionLimiter
+	//suspended sessions are stopped by a shutdown, they stay in the store to be resumed
+	suspended bool
+	//state is where the session is in its lifecycle, stateTimer ends it when it stays there for too long
+	state      string
+	stateTimer *time.Timer
+	//pendingStart is the START_GAME waiting for the players to join when the game starts automatically
+	pendingStart *pendingStart
+	//botTimer fills the seats of the invited players who didn't join with bots, botCount numbers the bots
+	botTimer *time.Timer
+	botCount int
+	//result is how the game ended, it is told to the event publisher
+	result *GameResult
+	//done is closed once the session has ended
+	done chan struct{}
+}
+
+//SessionInfo describes a session and who is in it
+type SessionInfo struct {
+	ID         string       `json:"id"`
+	Game       Game         `json:"game"`
+	Players    []PlayerInfo `json:"players"`
+	Spectators int          `json:"spectators"`
+	Host       string       `json:"host,omitempty"`
+	Private    bool         `json:"private"`
+	State      string       `json:"state"`
+	CreatedAt  time.Time    `json:"createdAt"`
+}
+
+//PlayerInfo type
+type PlayerInfo struct {
+	Player
+	Connected bool `json:"connected"`
+}
+
+//Info returns the description of the session, it is answered by the session loop
+func (gameSession *GameSession) Info() (SessionInfo, error) {
+	ch := make(chan SessionInfo, 1)
+	select {
+	case gameSession.infoRequest <- ch:
+		return <-ch, nil
+	case <-gameSession.done:
+		return SessionInfo{}, errors.New("game session has ended")
+	}
+}
+
+//DeclineInvitation removes an invited user who declined from the session and lets the players know
+func (gameSession *GameSession) DeclineInvitation(email string) {
+	select {
+	case gameSession.declined <- email:
+	case <-gameSession.done:
+	}
+}
+
+func (gameSession *GameSession) info() SessionInfo {
+	info := SessionInfo{
+		ID:         gameSession.ID,
+		Game:       gameSession.Game,
+		Players:    make([]PlayerInfo, 0, len(gameSession.Players)),
+		Spectators: len(gameSession.Spectators),
+		Host:       gameSession.host,
+		Private:    gameSession.Private,
+		State:      gameSession.state,
+		CreatedAt:  gameSession.CreatedAt,
+	}
+	for _, player := range gameSession.Players {
+		info.Players = append(info.Players, PlayerInfo{
+			Player:    Player{ID: player.ID, Name: player.Name, Email: player.Email, Bot: player.Bot},
+			Connected: player.IsConnected(),
+		})
+	}
+	sort.Slice(info.Players, func(i, j int) bool {
+		return info.Players[i].Email < info.Players[j].Email
+	})
+	return info
 }
 
 func (gameSession *GameSession) CreateNewPlayer(conn *websocket.Conn, id uint, name string, email string) *Player {
@@ -27,42 +135,201 @@ func (gameSession *GameSession) CreateNewPlayer(conn *websocket.Conn, id uint, n
 		Name:        name,
 		Email:       email,
 		Conn:        nil,
-		RecvMsgChan: nil,
 		GameSession: gameSession,
 	}
 	return player
 }
 
 func (gameSession *GameSession) sendMsgToPlayers(gameMsg *GameMsg) {
+	//number the message and keep it for the players who will reconnect
+	msg := *gameMsg
+	gameSession.seq++
+	msg.Seq = gameSession.seq
+	gameSession.history = append(gameSession.history, &msg)
+	gameSession.recordReplay(ReplayOut, &msg)
+	if len(gameSession.history) > maxMsgHistory {
+		gameSession.history = gameSession.history[len(gameSession.history)-maxMsgHistory:]
+	}
+
 	for _, player := range gameSession.Players {
 		//only if we have a conn ready
 		if !player.IsConnected() {
 			continue
 		}
-		//skip sending to the one who send the message , unless it is ment for all
-		if gameMsg.Player == (Player{}) { //if ment for all
-			player.SendMessage(gameMsg)
-		} else if gameMsg.Player.Email != player.Email {
-			player.SendMessage(gameMsg)
+		if msg.isFor(player) {
+			player.SendMessage(&msg)
+		}
+	}
+	gameSession.sendMsgToSpectators(&msg)
+}
+
+//isFor skips sending to the one who send the message , unless it is ment for all
+func (gameMsg *GameMsg) isFor(player *Player) bool {
+	return gameMsg.Player == (Player{}) || gameMsg.Player.Email != player.Email
+}
+
+//syncPlayer sends a player who joined the messages it missed since its last one,
+//or the whole state of the game when they are no longer in the history
+func (gameSession *GameSession) syncPlayer(player *Player) {
+	from := player.lastSeq
+	if from == 0 || from > gameSession.seq || len(gameSession.history) == 0 ||
+		from+1 < gameSession.history[0].Seq {
+		player.SendCurrentGameStateToPlayer()
+		if history, ok := gameSession.chatHistory(); ok {
+			player.SendMessage(&history)
+		}
+		return
+	}
+	for _, msg := range gameSession.history {
+		if msg.Seq > from && msg.isFor(player) {
+			player.SendMessage(msg)
 		}
 	}
+	resumed, _ := WrapCommand(ON_GAME_RESUMED, OnGameResumed{From: from, To: gameSession.seq}, Player{})
+	player.SendMessage(&resumed)
 }
 
 //add the intvited users to session and wait for them to join the game
-// when a user joins the game he become a player
-func (gameSession *GameSession) addUsersToSession(players []Player) {
-	for _, player := range players {
+// when a user joins the game he become a player, it returns the users who were not in the session yet
+func (gameSession *GameSession) addUsersToSession(players []Player) []Player {
+	added := []Player{}
+	for i := range players {
+		player := players[i]
+		if _, ok := gameSession.Players[player.Email]; ok {
+			continue
+		}
+		player.GameSession = gameSession
 		gameSession.Players[player.Email] = &player
+		added = append(added, Player{ID: player.ID, Name: player.Name, Email: player.Email, Bot: player.Bot})
 	}
+	return added
+}
+
+//invite sends the invitations to the users the host added to the session
+func (gameSession *GameSession) invite(host Player, invited []Player) {
+	inviter := gameSession.gameManager.inviter
+	var uninformed []Player
+	for _, player := range invited {
+		if !gameSession.notified[player.Email] {
+			uninformed = append(uninformed, player)
+		}
+	}
+	if inviter == nil || len(uninformed) == 0 {
+		return
+	}
+	invited = uninformed
+	host = Player{ID: host.ID, Name: host.Name, Email: host.Email}
+	//sending can be slow, it must not hold the session
+	go func() {
+		if err := inviter.SendGameInvitation(gameSession.ID, gameSession.Game, host, invited); err != nil {
+			log.Printf("couldn't send the invitations of session %s: %v", gameSession.ID, err)
+		}
+	}()
+}
+
+//declineInvitation removes the invited user unless they already joined, the game held back for them
+//starts when everyone left joined. It returns true when the game is over
+func (gameSession *GameSession) declineInvitation(email string) bool {
+	player, ok := gameSession.Players[email]
+	if !ok || player.IsConnected() {
+		return false
+	}
+	delete(gameSession.Players, email)
+	gameSession.persist()
+
+	msg, _ := WrapCommand(ON_INVITATION_DECLINED, OnInvitationDeclined{Email: email}, Player{})
+	gameSession.sendMsgToPlayers(&msg)
+	return gameSession.startWhenReady()
 }
 func (gameSession *GameSession) setInitData(data string) {
 	gameSession.InitialGameData = data
 }
 
+//record returns what needs to be persisted of the session
+func (gameSession *GameSession) record() SessionRecord {
+	record := SessionRecord{
+		ID:              gameSession.ID,
+		GameID:          gameSession.Game.ID,
+		InitialGameData: gameSession.InitialGameData,
+		Private:         gameSession.Private,
+		Passphrase:      string(gameSession.passphrase),
+		JoinCode:        gameSession.JoinCode,
+		Host:            gameSession.host,
+		CreatedAt:       gameSession.CreatedAt,
+		UpdatedAt:       time.Now(),
+	}
+	for _, player := range gameSession.Players {
+		record.Players = append(record.Players, Player{ID: player.ID, Name: player.Name, Email: player.Email, Bot: player.Bot})
+	}
+	if logic, ok := gameSession.logic.(PersistentGameLogic); ok {
+		snapshot, err := logic.Snapshot()
+		if err != nil {
+			log.Printf("couldn't snapshot game state of session %s: %v", gameSession.ID, err)
+		}
+		record.LogicState = snapshot
+	}
+	return record
+}
+
+//persist saves the session so it survives a restart of the server
+func (gameSession *GameSession) persist() {
+	store := gameSession.gameManager.store
+	if store == nil {
+		return
+	}
+	if err := store.SaveSession(gameSession.record()); err != nil {
+		log.Printf("couldn't save session %s: %v", gameSession.ID, err)
+	}
+}
+
+//restore brings back the invited players and the state of a persisted session,
+//the players have to join again to become connected
+func (gameSession *GameSession) restore(record SessionRecord) error {
+	gameSession.CreatedAt = record.CreatedAt
+	gameSession.Private = record.Private
+	gameSession.passphrase = []byte(record.Passphrase)
+	gameSession.JoinCode = record.JoinCode
+	gameSession.host = record.Host
+	gameSession.addUsersToSession(record.Players)
+	gameSession.setInitData(record.InitialGameData)
+	//a game with a state was started before the restart
+	if record.LogicState != "" || (gameSession.logic == nil && record.InitialGameData != "") {
+		gameSession.state = SessionRunning
+	}
+	//the replay carries on after the messages recorded before the restart
+	if replays := gameSession.gameManager.replays; replays != nil {
+		if entries, err := replays.Read(gameSession.ID); err == nil {
+			gameSession.replayed = len(entries)
+		}
+	}
+
+	if gameSession.logic == nil || record.LogicState == "" {
+		return nil
+	}
+	logic, ok := gameSession.logic.(PersistentGameLogic)
+	if !ok {
+		return errors.New("the state of game " + gameSession.Game.ID + " can not be restored")
+	}
+	return logic.Restore(record.LogicState)
+}
+
 func (gameSession *GameSession) cleanGameSession() {
 	for _, player := range gameSession.Players {
 		gameSession.removeUser(player)
 	}
+	for _, spectator := range gameSession.Spectators {
+		gameSession.removeSpectator(spectator)
+	}
+	//a suspended session was saved and released to be resumed
+	if !gameSession.suspended {
+		if store := gameSession.gameManager.store; store != nil {
+			if err := store.DeleteSession(gameSession.ID); err != nil {
+				log.Printf("couldn't delete session %s: %v", gameSession.ID, err)
+			}
+		}
+		gameSession.releaseJoinCode()
+		gameSession.gameManager.releaseSession(gameSession.ID)
+	}
 	gameSession.gameManager.unRegister <- gameSession
 }
 
@@ -72,61 +339,272 @@ func (gameSession *GameSession) removeUser(player *Player) {
 }
 func (gameSession *GameSession) Run() {
 
-	timer := time.NewTimer(maxGameStartTime)
+	gameSession.stateTimer = time.NewTimer(gameSession.timeout())
 	defer func() {
-		timer.Stop()
+		gameSession.flushReplay()
+		gameSession.stateTimer.Stop()
+		gameSession.spectatorTimer.Stop()
+		gameSession.botTimer.Stop()
+		if gameSession.turns != nil {
+			gameSession.turns.timer.Stop()
+		}
+		if !gameSession.suspended {
+			gameSession.setState(SessionFinished)
+			gameSession.publish(EventGameEnded, SessionEvent{Result: gameSession.result})
+		}
+		close(gameSession.done)
 		gameSession.cleanGameSession()
 	}()
+	//a restored game carries on with the turn of the player who was to play
+	gameSession.updateTurn()
+	gameSession.startBots()
 	for {
 		select {
 		case player := <-gameSession.Register:
+			if player.Spectator {
+				gameSession.addSpectator(player)
+				continue
+			}
 			//if the user exists in the invite list but not yet active override it with the new one
 			//if the user is trying to reconnect drop the old connection in favour of the new one
-			if val, ok := gameSession.Players[player.Email]; ok {
+			if gameSession.isFull(player.Email) {
+				player.sendError(ErrSessionFull, ErrCodeNotAllowed, nil)
+				player.Stop()
+				continue
+			}
+			val, ok := gameSession.Players[player.Email]
+			if ok {
 				gameSession.removeUser(val)
 			}
 			gameSession.Players[player.Email] = player
+			if gameSession.host == "" {
+				gameSession.host = player.Email
+			}
+			if !ok {
+				gameSession.persist()
+			}
+			if player.syncState {
+				gameSession.syncPlayer(player)
+			}
 			gameData, _ := WrapCommand(ON_USER_CONNECTED, *player, *player)
 			gameSession.sendMsgToPlayers(&gameData)
+			gameSession.publish(EventPlayerJoined, SessionEvent{Player: &Player{ID: player.ID, Name: player.Name, Email: player.Email, Bot: player.Bot}})
+			if gameOver := gameSession.startWhenReady(); gameOver {
+				return
+			}
+			//the turn of a player is timed once it is connected
+			gameSession.updateTurn()
 
 		case player := <-gameSession.UnRegister:
-			if val, ok := gameSession.Players[player.Email]; ok {
+			if player.Spectator {
+				gameSession.removeSpectator(player)
+				continue
+			}
+			//a player who reconnected already replaced the one leaving
+			if val, ok := gameSession.Players[player.Email]; ok && val == player {
 				gameData, _ := WrapCommand(ON_USER_DISCONNECTED, *player, *player)
 				gameSession.sendMsgToPlayers(&gameData)
 				gameSession.removeUser(val)
+				gameSession.publish(EventPlayerLeft, SessionEvent{Player: &Player{ID: player.ID, Name: player.Name, Email: player.Email, Bot: player.Bot}})
+				if gameSession.Game.lifecycle().EndWhenEmpty && !gameSession.anyPlayerConnected() {
+					return
+				}
 			}
 
 		case gameMsg := <-gameSession.SendToGame:
-			msg := UnWrapGameMsg(*gameMsg)
-			if t, ok := msg.(StartGameMsg); ok == true {
-				gameSession.addUsersToSession(t.Players)
-				gameSession.setInitData(t.GameData)
+			gameSession.recordReplay(ReplayIn, gameMsg)
+			if err := DecodePayload(gameSession.Game.ID, gameMsg); err != nil {
+				gameSession.rejectMove(gameMsg, err)
+				continue
+			}
+			//a running game is idle once nothing is sent for too long
+			if gameSession.state == SessionRunning {
+				resetTimer(gameSession.stateTimer, gameSession.timeout())
+			}
+			if t, ok := gameMsg.Payload.(*StartGameMsg); ok {
+				if err := gameSession.checkStart(gameMsg.Player); err != nil {
+					gameSession.rejectMove(gameMsg, err)
+					continue
+				}
+				if err := gameSession.checkPlayerCount(gameMsg.Player, *t); err != nil {
+					gameSession.rejectMove(gameMsg, err)
+					continue
+				}
+				if gameSession.host == "" {
+					gameSession.host = gameMsg.Player.Email
+				}
+				invited := gameSession.addUsersToSession(t.Players)
+				gameSession.invite(gameMsg.Player, invited)
+				gameSession.scheduleBots()
+				if gameSession.logic == nil {
+					gameSession.setInitData(t.GameData)
+				}
+				if gameSession.Game.lifecycle().AutoStart {
+					gameSession.pendingStart = &pendingStart{host: gameMsg.Player, startGame: *t}
+					if gameOver := gameSession.startWhenReady(); gameOver {
+						return
+					}
+				} else if gameSession.logic != nil {
+					if gameOver := gameSession.startGame(gameMsg.Player, *t); gameOver {
+						return
+					}
+				} else {
+					gameSession.setState(SessionRunning)
+				}
+				gameSession.persist()
 			} else if gameMsg.GameAction == UPDATE_GAME_STATE {
-				gameSession.setInitData(gameMsg.Data)
+				//when the server runs the rules the clients can not override the state
+				if gameSession.logic != nil {
+					gameSession.rejectMove(gameMsg, NewGameError(ErrCodeNotAllowed, "game state is managed by the server"))
+				} else {
+					gameSession.setInitData(gameMsg.Data)
+					gameSession.persist()
+				}
+			} else if chat, ok := gameMsg.Payload.(*ChatMessage); ok {
+				gameSession.chat(gameMsg, chat)
+			} else if mute, ok := gameMsg.Payload.(*MutePlayer); ok {
+				gameSession.mute(gameMsg, mute.Email, gameMsg.GameAction == MUTE_PLAYER)
+			} else if gameMsg.GameAction == GAME_PLAY && gameSession.logic != nil {
+				if gameOver := gameSession.playMove(gameMsg); gameOver {
+					return
+				}
 			} else {
-				timer.Reset(maxGameStartTime)
 				gameSession.sendMsgToPlayers(gameMsg)
 			}
 
-		case <-timer.C:
-			//check if there is no one on the session then delete the session
-			if !gameSession.allplayersAreConnected() {
-				exception := struct {
-					Message string
-				}{Message: "time out : not all participants have joined"}
-				msg, _ := WrapCommand(ON_GAME_OVER, exception, Player{})
-				gameSession.sendMsgToPlayers(&msg)
+		case <-gameSession.spectatorTimer.C:
+			gameSession.sendDelayedMsgs()
+
+		case <-gameSession.botTimer.C:
+			gameSession.fillSeats()
+
+		case <-gameSession.turns.C():
+			if gameOver := gameSession.onTurnTimer(); gameOver {
 				return
-			} else if len(gameSession.Players) == 0 {
+			}
+
+		case ch := <-gameSession.infoRequest:
+			ch <- gameSession.info()
+
+		case email := <-gameSession.declined:
+			if gameOver := gameSession.declineInvitation(email); gameOver {
 				return
-			} else {
-				timer.Reset(maxGameStartTime)
 			}
 
+		case control := <-gameSession.controls:
+			if end := control(gameSession); end {
+				return
+			}
+
+		case <-gameSession.stateTimer.C:
+			if end := gameSession.onTimeout(); end {
+				return
+			}
+			gameSession.stateTimer.Reset(gameSession.timeout())
 		}
 	}
 }
 
+//startGame starts the game rules with the host and the invited players
+func (gameSession *GameSession) startGame(host Player, startGame StartGameMsg) bool {
+	players := []Player{host}
+	for _, player := range startGame.Players {
+		if player.Email != host.Email {
+			players = append(players, player)
+		}
+	}
+	if err := gameSession.logic.Start(players, startGame.GameData); err != nil {
+		log.Printf("couldn't start game %s for session %s: %v", gameSession.Game.ID, gameSession.ID, err)
+		gameSession.rejectMove(&GameMsg{GameAction: START_GAME, Data: startGame.GameData, Player: host}, err)
+		return false
+	}
+	gameSession.setState(SessionRunning)
+	return gameSession.broadcastGameState()
+}
+
+//playMove validates the move against the game rules, applies it and lets all the players know the new state
+func (gameSession *GameSession) playMove(gameMsg *GameMsg) bool {
+	if err := gameSession.checkTurn(gameMsg.Player); err != nil {
+		gameSession.rejectMove(gameMsg, err)
+		return false
+	}
+	if err := gameSession.logic.ValidateMove(gameMsg.Player, gameMsg); err != nil {
+		gameSession.rejectMove(gameMsg, err)
+		return false
+	}
+	if err := gameSession.logic.ApplyMove(gameMsg.Player, gameMsg); err != nil {
+		gameSession.rejectMove(gameMsg, err)
+		return false
+	}
+	return gameSession.broadcastGameState()
+}
+
+//broadcastGameState sends the current state to everyone and ends the game if the rules say so
+//or else starts the next turn, it returns true when the game is over
+func (gameSession *GameSession) broadcastGameState() bool {
+	state := gameSession.logic.State()
+	gameSession.setInitData(state)
+	gameSession.persist()
+
+	stateMsg, _ := WrapCommand(ON_GAME_STATE_CHANGED, state, Player{})
+	gameSession.sendMsgToPlayers(&stateMsg)
+
+	if over, result := gameSession.logic.GameOver(); over {
+		msg, _ := WrapCommand(ON_GAME_OVER, result, Player{})
+		gameSession.sendMsgToPlayers(&msg)
+		gameSession.result = &result
+		gameSession.recordResult(result)
+		return true
+	}
+	gameSession.updateTurn()
+	return false
+}
+
+//recordResult keeps the result of a game decided by the server rules
+func (gameSession *GameSession) recordResult(result GameResult) {
+	recorder := gameSession.gameManager.results
+	if recorder == nil {
+		return
+	}
+	//the players who left before the end are rated too, the rules and the result still know them
+	var known []Player
+	if logic, ok := gameSession.logic.(PlayersGameLogic); ok {
+		known = append(known, logic.Players()...)
+	}
+	known = append(known, result.Winners...)
+	for _, player := range gameSession.Players {
+		known = append(known, *player)
+	}
+	players := make([]Player, 0, len(known))
+	index := make(map[string]int)
+	for _, player := range known {
+		//the bots are not rated
+		if player.Bot || player.Spectator {
+			continue
+		}
+		player = Player{ID: player.ID, Name: player.Name, Email: player.Email}
+		if i, ok := index[player.Email]; ok {
+			//the players still in the session are the ones the server knows best
+			players[i] = player
+			continue
+		}
+		index[player.Email] = len(players)
+		players = append(players, player)
+	}
+	if err := recorder.RecordResult(gameSession.Game.ID, gameSession.ID, players, result); err != nil {
+		log.Printf("couldn't record the result of session %s: %v", gameSession.ID, err)
+	}
+}
+
+//rejectMove lets only the sender know its message was not accepted and why with ON_ERROR
+func (gameSession *GameSession) rejectMove(gameMsg *GameMsg, reason error) {
+	player, ok := gameSession.Players[gameMsg.Player.Email]
+	if !ok || !player.IsConnected() {
+		return
+	}
+	player.sendError(reason, ErrCodeInvalidMove, gameMsg)
+}
+
 func (gameSession *GameSession) allplayersAreConnected() bool {
 	for _, player := range gameSession.Players {
 		if !player.IsConnected() {
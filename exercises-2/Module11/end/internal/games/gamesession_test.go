@@ -0,0 +1,156 @@
+package games
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGameSession_syncPlayer(t *testing.T) {
+	manager := newGameManager()
+	session := manager.newGameSession(counterGame, "session")
+	session.setInitData("state")
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	for i := 0; i < maxMsgHistory+10; i++ {
+		msg, _ := WrapCommand(GAME_PLAY, "move", Player{})
+		session.sendMsgToPlayers(&msg)
+	}
+	//messages sent by the player itself are not sent back
+	own, _ := WrapCommand(GAME_PLAY, "own move", dave)
+	session.sendMsgToPlayers(&own)
+
+	tests := []struct {
+		name     string
+		lastSeq  uint64
+		wantMsgs int
+		wantInit bool
+	}{
+		{name: "new player gets the state", lastSeq: 0, wantMsgs: 1, wantInit: true},
+		{name: "missed messages are sent again", lastSeq: session.seq - 5, wantMsgs: 4 + 1},
+		{name: "nothing missed", lastSeq: session.seq, wantMsgs: 1},
+		{name: "gap too large gets the state", lastSeq: 5, wantMsgs: 1, wantInit: true},
+		{name: "unknown sequence gets the state", lastSeq: session.seq + 100, wantMsgs: 1, wantInit: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email)
+			player.outbox = newOutbox(maxMsgHistory+10, OverflowDisconnect)
+			player.lastSeq = tt.lastSeq
+
+			session.syncPlayer(player)
+			msgs, _ := player.outbox.take()
+			if len(msgs) != tt.wantMsgs {
+				t.Fatalf("syncPlayer() sent %d messages, want %d", len(msgs), tt.wantMsgs)
+			}
+			if gotInit := msgs[0].GameAction == ON_GAME_INIT; gotInit != tt.wantInit {
+				t.Errorf("syncPlayer() first message = %s", msgs[0].GameAction)
+			}
+			if tt.wantInit && msgs[0].Seq != session.seq {
+				t.Errorf("ON_GAME_INIT seq = %d, want %d", msgs[0].Seq, session.seq)
+			}
+			if !tt.wantInit && msgs[len(msgs)-1].GameAction != ON_GAME_RESUMED {
+				t.Errorf("syncPlayer() last message = %s, want %s", msgs[len(msgs)-1].GameAction, ON_GAME_RESUMED)
+			}
+		})
+	}
+}
+
+type recordingInviter struct {
+	invited chan []Player
+}
+
+func (inviter *recordingInviter) SendGameInvitation(sessionID string, game Game, host Player, invited []Player) error {
+	inviter.invited <- invited
+	return nil
+}
+
+func TestGameSession_invitations(t *testing.T) {
+	manager := newTestGameManager(nil)
+	inviter := &recordingInviter{invited: make(chan []Player, 1)}
+	manager.SetInviter(inviter)
+
+	session := manager.CreateNewGameSession(Game{ID: "chess"})
+	go session.Run()
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}}, dave)
+	session.SendToGame <- &startGame
+
+	if invited := <-inviter.invited; len(invited) != 1 || invited[0].Email != dan.Email {
+		t.Fatalf("invited %+v, want dan", invited)
+	}
+
+	session.DeclineInvitation(dan.Email)
+	info, _ := session.Info()
+	if len(info.Players) != 0 {
+		t.Errorf("players after dan declined = %+v", info.Players)
+	}
+}
+
+func TestGameManager_CreateMatchedSession(t *testing.T) {
+	manager := newTestGameManager(nil)
+	inviter := &recordingInviter{invited: make(chan []Player, 1)}
+	manager.SetInviter(inviter)
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	bob := Player{Email: "bob@gmail.com"}
+	if _, err := manager.CreateMatchedSession(Game{ID: "chess"}, []Player{dave, dan}, []Player{bob}); err != nil {
+		t.Fatal(err)
+	}
+	//the matched players were told by the matchmaking, only the member of the party is invited
+	if invited := <-inviter.invited; len(invited) != 1 || invited[0].Email != bob.Email {
+		t.Errorf("invited %+v, want bob", invited)
+	}
+}
+
+func TestGameSession_gameLogic(t *testing.T) {
+	manager := newTestGameManager(nil)
+	session := manager.CreateNewGameSession(counterGame)
+	go session.Run()
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	daveConn := newFakeConn(false)
+	session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email).Start(daveConn)
+	danConn := newFakeConn(false)
+	session.CreateNewPlayer(nil, dan.ID, dan.Name, dan.Email).Join(danConn, 0)
+
+	//only the host starts the game
+	danStart, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dave}}, dan)
+	session.SendToGame <- &danStart
+	expectRejected(t, danConn, ErrCodeNotAllowed)
+
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}}, dave)
+	session.SendToGame <- &startGame
+	if msg := expectAction(t, danConn, ON_GAME_STATE_CHANGED); msg.Data != "0" {
+		t.Errorf("state after the start = %q, want %q", msg.Data, "0")
+	}
+	waitForState(t, session, SessionRunning)
+
+	//a running game is not started again
+	session.SendToGame <- &startGame
+	expectRejected(t, daveConn, ErrCodeNotAllowed)
+
+	//the rules reject the move and the state stays the same
+	invalid, _ := WrapCommand(GAME_PLAY, "+2", dan)
+	session.SendToGame <- &invalid
+	expectRejected(t, danConn, ErrCodeInvalidMove)
+
+	move, _ := WrapCommand(GAME_PLAY, "+1", dan)
+	for want := 1; want <= 3; want++ {
+		session.SendToGame <- &move
+		if msg := expectAction(t, daveConn, ON_GAME_STATE_CHANGED); msg.Data != strconv.Itoa(want) {
+			t.Fatalf("state after %d moves = %q", want, msg.Data)
+		}
+	}
+	expectAction(t, daveConn, ON_GAME_OVER)
+	expectAction(t, danConn, ON_GAME_OVER)
+	select {
+	case <-session.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the session didn't end with the game")
+	}
+}
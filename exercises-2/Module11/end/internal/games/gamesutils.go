@@ -12,8 +12,7 @@ func WrapCommand(action GameAction, cmd interface{}, player Player) (GameMsg, er
 	if t, ok := cmd.(string); !ok {
 		msg, err := json.Marshal(cmd)
 		if err != nil {
-			log.Println("couldn't join player")
-			//TBD exception should be thrown here to the client by sending a json error message
+			log.Printf("couldn't marshal the data of %s: %v", action, err)
 			return GameMsg{}, errors.New("couldn't Marshal Object")
 		}
 		dataStr = string(msg)
@@ -25,16 +24,7 @@ func WrapCommand(action GameAction, cmd interface{}, player Player) (GameMsg, er
 		GameAction: action,
 		Data:       dataStr,
 		Player:     player,
+		Version:    ProtocolVersion,
 	}
 	return gameMsg, nil
 }
-
-func UnWrapGameMsg(gameMsg GameMsg) interface{} {
-	switch gameMsg.GameAction {
-	case START_GAME:
-		var startGame StartGameMsg
-		json.Unmarshal([]byte(gameMsg.Data), &startGame)
-		return startGame
-	}
-	return nil
-}
@@ -0,0 +1,181 @@
+package games
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	//joinCodeLength is the number of characters of a join code
+	joinCodeLength = 6
+	//joinCodeAlphabet leaves out the characters that are easily mistaken for one another
+	joinCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	//joinCodeAttempts is how many codes are drawn before giving up on finding a free one
+	joinCodeAttempts = 10
+	//DefaultJoinCodeTTL is how long the join codes can be used when the server doesn't say
+	DefaultJoinCodeTTL = time.Hour
+)
+
+var (
+	//ErrJoinCodeTaken is returned by the stores when the code is used by a session whose code hasn't expired
+	ErrJoinCodeTaken = errors.New("join code is already taken")
+	//ErrJoinCodeNotFound is returned for the codes that are unknown or expired
+	ErrJoinCodeNotFound = NewGameError(ErrCodeSessionNotFound, "no game session with this join code")
+	//ErrPrivateSession is returned to the users joining a private session with its id without being invited
+	ErrPrivateSession = NewGameError(ErrCodeNotInvited, "the game session is private, join it with its code")
+	//ErrWrongPassphrase is returned to the users joining a session without its passphrase
+	ErrWrongPassphrase = NewGameError(ErrCodeWrongPassphrase, "wrong passphrase for the game session")
+)
+
+//JoinCodeStore maps the join codes to the sessions, it is shared by the servers running the sessions
+type JoinCodeStore interface {
+	//Reserve maps the code to the session until it expires, it fails with ErrJoinCodeTaken when the code is in use
+	Reserve(code string, sessionID string, expires time.Time) error
+	//Resolve returns the session of the code, ErrJoinCodeNotFound when the code is unknown or expired
+	Resolve(code string, now time.Time) (string, error)
+	Delete(code string) error
+}
+
+//SessionOptions are chosen by the host when creating a session
+type SessionOptions struct {
+	//Private sessions are only joined with their code, or with their id by the invited players
+	Private bool
+	//Passphrase is asked from the users joining the session when it is not empty
+	Passphrase string
+}
+
+//SetJoinCodes gives the new sessions a join code valid for ttl, it has to be called before Run
+func (manager *GameManager) SetJoinCodes(store JoinCodeStore, ttl time.Duration) {
+	manager.joinCodes = store
+	manager.joinCodeTTL = ttl
+}
+
+//newJoinCode draws a random code
+func newJoinCode() (string, error) {
+	var code strings.Builder
+	max := big.NewInt(int64(len(joinCodeAlphabet)))
+	for i := 0; i < joinCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(joinCodeAlphabet[n.Int64()])
+	}
+	return code.String(), nil
+}
+
+//reserveJoinCode finds a free code for the session
+func (manager *GameManager) reserveJoinCode(sessionID string) (string, error) {
+	expires := time.Now().Add(manager.joinCodeTTL)
+	for i := 0; i < joinCodeAttempts; i++ {
+		code, err := newJoinCode()
+		if err != nil {
+			return "", err
+		}
+		err = manager.joinCodes.Reserve(code, sessionID, expires)
+		if err == nil {
+			return code, nil
+		}
+		if err != ErrJoinCodeTaken {
+			return "", err
+		}
+	}
+	return "", errors.New("couldn't find a free join code")
+}
+
+//ResolveJoinCode returns the id of the session of the code, the codes are read whatever their case
+func (manager *GameManager) ResolveJoinCode(code string) (string, error) {
+	if manager.joinCodes == nil {
+		return "", ErrJoinCodeNotFound
+	}
+	return manager.joinCodes.Resolve(strings.ToUpper(strings.TrimSpace(code)), time.Now())
+}
+
+//releaseJoinCode frees the code of a session that ended
+func (gameSession *GameSession) releaseJoinCode() {
+	store := gameSession.gameManager.joinCodes
+	if store == nil || gameSession.JoinCode == "" {
+		return
+	}
+	if err := store.Delete(gameSession.JoinCode); err != nil {
+		log.Printf("couldn't delete the join code of session %s: %v", gameSession.ID, err)
+	}
+}
+
+//CheckAccess tells if the user can join the session. The users already in it, as the invited players are,
+//join freely. The others can't be banned, they need the passphrase of the session and, when it is private,
+//to come with its join code
+func (gameSession *GameSession) CheckAccess(email string, passphrase string, byCode bool) error {
+	var member bool
+	err := gameSession.control(func(gameSession *GameSession) (bool, error) {
+		_, member = gameSession.Players[email]
+		return false, nil
+	})
+	if err != nil || member {
+		return err
+	}
+	if err := gameSession.gameManager.CheckBan(email); err != nil {
+		return err
+	}
+	if gameSession.Private && !byCode {
+		return ErrPrivateSession
+	}
+	//the hash is set when the session is created, it is compared out of the session loop as it is slow
+	if len(gameSession.passphrase) > 0 && bcrypt.CompareHashAndPassword(gameSession.passphrase, []byte(passphrase)) != nil {
+		return ErrWrongPassphrase
+	}
+	return nil
+}
+
+//MemoryJoinCodeStore keeps the join codes in memory, it is meant for tests and single server runs
+type MemoryJoinCodeStore struct {
+	mu    sync.Mutex
+	codes map[string]joinCode
+}
+
+type joinCode struct {
+	sessionID string
+	expires   time.Time
+}
+
+//NewMemoryJoinCodeStore type
+func NewMemoryJoinCodeStore() *MemoryJoinCodeStore {
+	return &MemoryJoinCodeStore{codes: make(map[string]joinCode)}
+}
+
+func (store *MemoryJoinCodeStore) Reserve(code string, sessionID string, expires time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if existing, ok := store.codes[code]; ok && time.Now().Before(existing.expires) {
+		return ErrJoinCodeTaken
+	}
+	store.codes[code] = joinCode{sessionID: sessionID, expires: expires}
+	return nil
+}
+
+func (store *MemoryJoinCodeStore) Resolve(code string, now time.Time) (string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, ok := store.codes[code]
+	if !ok || !now.Before(existing.expires) {
+		return "", ErrJoinCodeNotFound
+	}
+	return existing.sessionID, nil
+}
+
+func (store *MemoryJoinCodeStore) Delete(code string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.codes, code)
+	return nil
+}
@@ -0,0 +1,119 @@
+package games
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryJoinCodeStore(t *testing.T) {
+	store := NewMemoryJoinCodeStore()
+	now := time.Now()
+
+	if err := store.Reserve("ABC234", "session1", now.Add(time.Minute)); err != nil {
+		t.Fatalf("Reserve() = %v", err)
+	}
+	if err := store.Reserve("ABC234", "session2", now.Add(time.Minute)); err != ErrJoinCodeTaken {
+		t.Errorf("Reserve() of a code in use = %v, want %v", err, ErrJoinCodeTaken)
+	}
+	if id, err := store.Resolve("ABC234", now); err != nil || id != "session1" {
+		t.Errorf("Resolve() = %s, %v, want session1", id, err)
+	}
+	if _, err := store.Resolve("ABC234", now.Add(time.Hour)); err != ErrJoinCodeNotFound {
+		t.Errorf("Resolve() of an expired code = %v, want %v", err, ErrJoinCodeNotFound)
+	}
+
+	//an expired code goes to the next session asking for it
+	if err := store.Reserve("XYZ789", "session1", now.Add(-time.Second)); err != nil {
+		t.Fatalf("Reserve() = %v", err)
+	}
+	if err := store.Reserve("XYZ789", "session2", now.Add(time.Minute)); err != nil {
+		t.Errorf("Reserve() of an expired code = %v", err)
+	}
+
+	store.Delete("ABC234")
+	if _, err := store.Resolve("ABC234", now); err != ErrJoinCodeNotFound {
+		t.Errorf("Resolve() of a deleted code = %v, want %v", err, ErrJoinCodeNotFound)
+	}
+}
+
+func TestNewJoinCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := newJoinCode()
+		if err != nil {
+			t.Fatalf("newJoinCode() = %v", err)
+		}
+		if len(code) != joinCodeLength {
+			t.Fatalf("newJoinCode() = %s, want %d characters", code, joinCodeLength)
+		}
+		for _, c := range code {
+			if !strings.ContainsRune(joinCodeAlphabet, c) {
+				t.Fatalf("newJoinCode() = %s, %c is not in the alphabet", code, c)
+			}
+		}
+	}
+}
+
+func TestGameManager_CreateSession(t *testing.T) {
+	store := NewMemorySessionStore()
+	manager := newGameManager()
+	manager.games[counterGame.ID] = counterGame
+	manager.SetSessionStore(store)
+	manager.SetJoinCodes(NewMemoryJoinCodeStore(), time.Minute)
+	go manager.Run()
+
+	if _, err := newTestGameManager(nil).CreateSession(counterGame, SessionOptions{Private: true}); err == nil {
+		t.Error("a private session was created without join codes")
+	}
+
+	session, err := manager.CreateSession(counterGame, SessionOptions{Private: true, Passphrase: "secret"})
+	if err != nil {
+		t.Fatalf("CreateSession() = %v", err)
+	}
+	go session.Run()
+	if id, err := manager.ResolveJoinCode(" " + strings.ToLower(session.JoinCode)); err != nil || id != session.ID {
+		t.Fatalf("ResolveJoinCode() = %s, %v, want %s", id, err, session.ID)
+	}
+	records, _ := store.GetSessions()
+	if len(records) != 1 || !records[0].Private || records[0].JoinCode != session.JoinCode || records[0].Passphrase == "secret" {
+		t.Errorf("saved session = %+v, want it private with its code and the hash of its passphrase", records)
+	}
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{{Email: "dan@gmail.com"}}}, dave)
+	session.SendToGame <- &startGame
+
+	tests := []struct {
+		name       string
+		email      string
+		passphrase string
+		byCode     bool
+		want       error
+	}{
+		{name: "invited player", email: "dan@gmail.com"},
+		{name: "id of a private session", email: "bob@gmail.com", passphrase: "secret", want: ErrPrivateSession},
+		{name: "wrong passphrase", email: "bob@gmail.com", passphrase: "guess", byCode: true, want: ErrWrongPassphrase},
+		{name: "code and passphrase", email: "bob@gmail.com", passphrase: "secret", byCode: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := session.CheckAccess(tt.email, tt.passphrase, tt.byCode); err != tt.want {
+				t.Errorf("CheckAccess() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	//the code is free again once the session ended
+	session.End("game over")
+	<-session.done
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := manager.ResolveJoinCode(session.JoinCode); err == ErrJoinCodeNotFound {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the join code of the session that ended still resolves")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
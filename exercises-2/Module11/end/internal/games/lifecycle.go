@@ -0,0 +1,204 @@
+package games
+
+import (
+	"fmt"
+	"time"
+)
+
+//the states of a session, it waits for its players in the lobby until the game starts and is finished once it ended
+const (
+	SessionLobby    = "lobby"
+	SessionRunning  = "running"
+	SessionFinished = "finished"
+)
+
+const (
+	//defaultLobbyTimeout is how long the players have to join when the game doesn't say
+	defaultLobbyTimeout = 30 * time.Minute
+	//defaultIdleTimeout is how long a running game can go without a message when the game doesn't say
+	defaultIdleTimeout = 30 * time.Minute
+)
+
+//LifecycleConfig sets how long the sessions of a game last and when they start and end
+type LifecycleConfig struct {
+	//LobbySeconds is how long the invited players have to join before the session ends
+	LobbySeconds int `json:"lobbySeconds,omitempty"`
+	//IdleSeconds ends a running session once nothing was sent for that long
+	IdleSeconds int `json:"idleSeconds,omitempty"`
+	//AutoStart holds back the START_GAME of the host until all the invited players joined
+	AutoStart bool `json:"autoStart,omitempty"`
+	//EndWhenEmpty ends the session as soon as the last connected player leaves
+	EndWhenEmpty bool `json:"endWhenEmpty,omitempty"`
+}
+
+//ErrSessionFull is returned to the players joining a session that has already MaxPlayers
+var ErrSessionFull = NewGameError(ErrCodeNotAllowed, "the game session is full")
+
+//OnSessionStateChanged is sent to everyone in the session when it moves to another state
+type OnSessionStateChanged struct {
+	State string `json:"state"`
+}
+
+//pendingStart is a START_GAME held back until all the players joined
+type pendingStart struct {
+	host      Player
+	startGame StartGameMsg
+}
+
+func (game Game) lifecycle() LifecycleConfig {
+	if game.Lifecycle == nil {
+		return LifecycleConfig{}
+	}
+	return *game.Lifecycle
+}
+
+func (game Game) lobbyTimeout() time.Duration {
+	if seconds := game.lifecycle().LobbySeconds; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultLobbyTimeout
+}
+
+func (game Game) idleTimeout() time.Duration {
+	if seconds := game.lifecycle().IdleSeconds; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultIdleTimeout
+}
+
+//setState moves the session to the state and lets everyone know
+func (gameSession *GameSession) setState(state string) {
+	if gameSession.state == state {
+		return
+	}
+	gameSession.state = state
+	if gameSession.stateTimer != nil && state != SessionFinished {
+		resetTimer(gameSession.stateTimer, gameSession.timeout())
+	}
+	msg, _ := WrapCommand(ON_SESSION_STATE_CHANGED, OnSessionStateChanged{State: state}, Player{})
+	gameSession.sendMsgToPlayers(&msg)
+}
+
+//timeout is how long the session waits in its state, for the players to join or for a message
+func (gameSession *GameSession) timeout() time.Duration {
+	if gameSession.state == SessionRunning {
+		return gameSession.Game.idleTimeout()
+	}
+	return gameSession.Game.lobbyTimeout()
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+//isFull tells if a player who is not in the session yet can't join it
+func (gameSession *GameSession) isFull(email string) bool {
+	if _, ok := gameSession.Players[email]; ok {
+		return false
+	}
+	max := gameSession.Game.MaxPlayers
+	return max > 0 && len(gameSession.Players) >= max
+}
+
+//checkStart rejects the START_GAME of anyone but the host and the ones sent once the game left the lobby
+func (gameSession *GameSession) checkStart(player Player) error {
+	if gameSession.host != "" && player.Email != gameSession.host {
+		return NewGameError(ErrCodeNotAllowed, "only the host can start the game")
+	}
+	if gameSession.state != SessionLobby {
+		return NewGameError(ErrCodeNotAllowed, "the game has already started")
+	}
+	return nil
+}
+
+//checkPlayerCount rejects the START_GAME that would bring the session over MaxPlayers,
+//or that starts the rules of the game right away with less than MinPlayers
+func (gameSession *GameSession) checkPlayerCount(host Player, startGame StartGameMsg) error {
+	emails := map[string]bool{host.Email: true}
+	for email := range gameSession.Players {
+		emails[email] = true
+	}
+	for _, player := range startGame.Players {
+		emails[player.Email] = true
+	}
+	if max := gameSession.Game.MaxPlayers; max > 0 && len(emails) > max {
+		return NewGameError(ErrCodeNotAllowed, fmt.Sprintf("the game can't have more than %d players", max))
+	}
+	if gameSession.logic == nil || gameSession.Game.lifecycle().AutoStart {
+		return nil
+	}
+	//the rules are started with the host and the players of the START_GAME
+	players := map[string]bool{host.Email: true}
+	for _, player := range startGame.Players {
+		players[player.Email] = true
+	}
+	if min := gameSession.Game.MinPlayers; len(players) < min {
+		return NewGameError(ErrCodeNotAllowed, fmt.Sprintf("the game needs at least %d players", min))
+	}
+	return nil
+}
+
+//startWhenReady starts the game held back once all the players joined, there have to be MinPlayers of them.
+//It returns true when the game is over
+func (gameSession *GameSession) startWhenReady() bool {
+	pending := gameSession.pendingStart
+	if pending == nil || !gameSession.allplayersAreConnected() || len(gameSession.Players) < gameSession.Game.MinPlayers {
+		return false
+	}
+	if _, ok := gameSession.Players[pending.host.Email]; !ok {
+		return false
+	}
+	gameSession.pendingStart = nil
+	if gameSession.logic == nil {
+		gameSession.setState(SessionRunning)
+		return false
+	}
+	players := make([]Player, 0, len(gameSession.Players))
+	for _, player := range gameSession.Players {
+		if player.Email != pending.host.Email {
+			players = append(players, Player{ID: player.ID, Name: player.Name, Email: player.Email, Bot: player.Bot})
+		}
+	}
+	pending.startGame.Players = players
+	return gameSession.startGame(pending.host, pending.startGame)
+}
+
+//anyPlayerConnected tells if someone is still playing, the bots don't play on their own
+func (gameSession *GameSession) anyPlayerConnected() bool {
+	for _, player := range gameSession.Players {
+		if player.IsConnected() && !player.Bot {
+			return true
+		}
+	}
+	return false
+}
+
+//onTimeout ends the session whose players didn't all join in time or that was idle for too long,
+//it returns true when the session has to end
+func (gameSession *GameSession) onTimeout() bool {
+	var reason string
+	switch {
+	case gameSession.state == SessionRunning:
+		reason = "time out : no one played for too long"
+	case len(gameSession.Players) == 0:
+		return true
+	case !gameSession.allplayersAreConnected():
+		reason = "time out : not all participants have joined"
+	default:
+		//everyone is there, the host may still start the game
+		return false
+	}
+	exception := struct {
+		Message string
+	}{Message: reason}
+	msg, _ := WrapCommand(ON_GAME_OVER, exception, Player{})
+	gameSession.sendMsgToPlayers(&msg)
+	gameSession.result = &GameResult{Message: reason}
+	return true
+}
@@ -0,0 +1,153 @@
+package games
+
+import (
+	"testing"
+	"time"
+)
+
+//lifecycleGame is the counter game with the lifecycle and the number of players of a test
+func lifecycleGame(lifecycle LifecycleConfig, min int, max int) Game {
+	game := counterGame
+	game.Lifecycle = &lifecycle
+	game.MinPlayers = min
+	game.MaxPlayers = max
+	return game
+}
+
+func waitForState(t *testing.T, session *GameSession, state string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if info, err := session.Info(); err == nil && info.State == state {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("the session didn't get to %s", state)
+}
+
+func TestGameSession_autoStart(t *testing.T) {
+	manager := newTestGameManager(nil)
+	session := manager.CreateNewGameSession(lifecycleGame(LifecycleConfig{AutoStart: true}, 2, 2))
+	go session.Run()
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	daveConn := newFakeConn(false)
+	session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email).Start(daveConn)
+
+	//a third player is one too many
+	tooMany, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan, {Email: "bob@gmail.com"}}}, dave)
+	session.SendToGame <- &tooMany
+	expectRejected(t, daveConn, ErrCodeNotAllowed)
+
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}}, dave)
+	session.SendToGame <- &startGame
+	if info, _ := session.Info(); info.State != SessionLobby {
+		t.Fatalf("state before dan joined = %s, want %s", info.State, SessionLobby)
+	}
+
+	//the game starts once dan joined and the session is full
+	session.CreateNewPlayer(nil, dan.ID, dan.Name, dan.Email).Join(newFakeConn(false), 0)
+	expectAction(t, daveConn, ON_SESSION_STATE_CHANGED)
+	expectAction(t, daveConn, ON_GAME_STATE_CHANGED)
+	waitForState(t, session, SessionRunning)
+
+	bobConn := newFakeConn(false)
+	session.CreateNewPlayer(nil, 3, "bob", "bob@gmail.com").Join(bobConn, 0)
+	expectRejected(t, bobConn, ErrCodeNotAllowed)
+	if info, _ := session.Info(); len(info.Players) != 2 {
+		t.Errorf("players after bob tried to join = %+v", info.Players)
+	}
+}
+
+func TestGameSession_autoStartAfterRemoval(t *testing.T) {
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	tests := []struct {
+		name   string
+		remove func(session *GameSession)
+	}{
+		{name: "declined", remove: func(session *GameSession) { session.DeclineInvitation(dan.Email) }},
+		{name: "kicked", remove: func(session *GameSession) {
+			if err := session.Kick(dan.Email, "no show"); err != nil {
+				t.Fatalf("Kick() = %v", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newTestGameManager(nil)
+			session := manager.CreateNewGameSession(lifecycleGame(LifecycleConfig{AutoStart: true}, 1, 2))
+			go session.Run()
+			daveConn := newFakeConn(false)
+			session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email).Start(daveConn)
+			startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}}, dave)
+			session.SendToGame <- &startGame
+			if info, _ := session.Info(); info.State != SessionLobby {
+				t.Fatalf("state before dan joined = %s, want %s", info.State, SessionLobby)
+			}
+
+			//the game held back for dan starts once dan is no longer expected
+			tt.remove(session)
+			expectAction(t, daveConn, ON_GAME_STATE_CHANGED)
+			waitForState(t, session, SessionRunning)
+		})
+	}
+}
+
+func TestGameSession_endWhenEmpty(t *testing.T) {
+	manager := newTestGameManager(nil)
+	session := manager.CreateNewGameSession(lifecycleGame(LifecycleConfig{EndWhenEmpty: true}, 0, 0))
+	go session.Run()
+
+	conn := newFakeConn(false)
+	session.CreateNewPlayer(nil, 1, "dave", "dave123@gmail.com").Start(conn)
+	waitForState(t, session, SessionLobby)
+	conn.Close()
+
+	select {
+	case <-session.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the session didn't end once the last player left")
+	}
+}
+
+func TestGameSession_onTimeout(t *testing.T) {
+	manager := newGameManager()
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	tests := []struct {
+		name    string
+		state   string
+		players []Player
+		connect bool
+		wantEnd bool
+	}{
+		{name: "empty lobby", state: SessionLobby, wantEnd: true},
+		{name: "players missing", state: SessionLobby, players: []Player{dave}, wantEnd: true},
+		{name: "everyone joined", state: SessionLobby, players: []Player{dave}, connect: true, wantEnd: false},
+		{name: "idle game", state: SessionRunning, players: []Player{dave}, connect: true, wantEnd: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := manager.newGameSession(lifecycleGame(LifecycleConfig{LobbySeconds: 60, IdleSeconds: 10}, 0, 0), "session")
+			session.state = tt.state
+			session.addUsersToSession(tt.players)
+			if tt.connect {
+				session.Players[dave.Email].Conn = newFakeConn(false)
+			}
+			if end := session.onTimeout(); end != tt.wantEnd {
+				t.Errorf("onTimeout() = %v, want %v", end, tt.wantEnd)
+			}
+		})
+	}
+
+	session := manager.newGameSession(lifecycleGame(LifecycleConfig{LobbySeconds: 60, IdleSeconds: 10}, 0, 0), "session")
+	if timeout := session.timeout(); timeout != time.Minute {
+		t.Errorf("lobby timeout = %v, want 1m", timeout)
+	}
+	session.state = SessionRunning
+	if timeout := session.timeout(); timeout != 10*time.Second {
+		t.Errorf("idle timeout = %v, want 10s", timeout)
+	}
+}
@@ -0,0 +1,432 @@
+package matchmaking
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//DefaultRating is used for players who don't have a rating yet
+const DefaultRating = 1500
+
+//Clock lets the tests control the time the matcher sees
+type Clock interface {
+	Now() time.Time
+	//Tick returns the channel on which the matcher wakes up to look for matches
+	Tick(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                        { return time.Now() }
+func (realClock) Tick(d time.Duration) <-chan time.Time { return time.NewTicker(d).C }
+
+//RealClock is the clock used outside of tests
+var RealClock Clock = realClock{}
+
+//Notifier delivers the match found message to a queued player
+type Notifier interface {
+	Notify(msg games.GameMsg) error
+}
+
+//SessionCreator creates the game session for matched players, the first player is the host.
+//The players are told about the session by the matchmaking, the invited ones get an invitation
+type SessionCreator interface {
+	GetGame(gameID string) (games.Game, error)
+	CreateMatchedSession(game games.Game, players []games.Player, invited []games.Player) (string, error)
+}
+
+//Config of the matcher
+type Config struct {
+	//Interval between two looks for matches
+	Interval time.Duration
+	//InitialWindow is the rating difference accepted when a player enters the queue
+	InitialWindow float64
+	//WindowGrowth widens the window by that much per second of waiting
+	WindowGrowth float64
+	//MaxWindow caps the window, 0 means it keeps growing
+	MaxWindow float64
+	//FillTimeout is how long the oldest player waits for a full game before
+	//accepting one with the minimum number of players, 0 means always wait for a full game
+	FillTimeout time.Duration
+}
+
+//DefaultConfig type
+var DefaultConfig = Config{
+	Interval:      time.Second,
+	InitialWindow: 100,
+	WindowGrowth:  10,
+	MaxWindow:     1000,
+	FillTimeout:   time.Minute,
+}
+
+//Ticket is a player, or the leader of a party, waiting for a match
+type Ticket struct {
+	Player games.Player
+	//Party are the other members of the party of the player, they are matched with it and invited to the session
+	Party      []games.Player
+	GameID     string
+	Rating     float64
+	PartySize  int
+	EnqueuedAt time.Time
+	notifier   Notifier
+}
+
+//Status of a queued ticket
+type Status struct {
+	GameID    string  `json:"gameId"`
+	Position  int     `json:"position"`
+	Queued    int     `json:"queued"`
+	Waited    float64 `json:"waitedSeconds"`
+	Window    float64 `json:"ratingWindow"`
+	Rating    float64 `json:"rating"`
+	PartySize int     `json:"partySize"`
+}
+
+//OnMatchFound is sent to every queued player of a match
+type OnMatchFound struct {
+	SessionID string         `json:"id"`
+	Game      games.Game     `json:"game"`
+	Players   []games.Player `json:"players"`
+}
+
+type enqueueRequest struct {
+	ticket *Ticket
+	result chan error
+}
+
+type statusRequest struct {
+	email  string
+	result chan *Status
+}
+
+type cancelRequest struct {
+	email  string
+	result chan bool
+}
+
+//matchResult tells the matcher if the session of a group was created
+type matchResult struct {
+	group   []*Ticket
+	created bool
+}
+
+//Matchmaker groups the queued players of a game and creates their sessions
+type Matchmaker struct {
+	enqueue chan enqueueRequest
+	status  chan statusRequest
+	cancel  chan cancelRequest
+	results chan matchResult
+	queues  map[string][]*Ticket
+	//queued holds the tickets by the email of each member of their party
+	queued map[string]*Ticket
+	//matching holds the tickets whose session is being created, they are queued again if it fails
+	matching map[string]*Ticket
+	sessions SessionCreator
+	clock    Clock
+	config   Config
+}
+
+//CreateMatchmaker type
+func CreateMatchmaker(sessions SessionCreator, clock Clock, config Config) *Matchmaker {
+	return &Matchmaker{
+		enqueue:  make(chan enqueueRequest),
+		status:   make(chan statusRequest),
+		cancel:   make(chan cancelRequest),
+		results:  make(chan matchResult),
+		queues:   make(map[string][]*Ticket),
+		queued:   make(map[string]*Ticket),
+		matching: make(map[string]*Ticket),
+		sessions: sessions,
+		clock:    clock,
+		config:   config,
+	}
+}
+
+//Enqueue puts the player in the queue of the game, the notifier gets the match found message
+func (matchmaker *Matchmaker) Enqueue(ticket Ticket, notifier Notifier) error {
+	ticket.PartySize = 1 + len(ticket.Party)
+	if ticket.Rating == 0 {
+		ticket.Rating = DefaultRating
+	}
+	ticket.notifier = notifier
+
+	result := make(chan error)
+	matchmaker.enqueue <- enqueueRequest{ticket: &ticket, result: result}
+	return <-result
+}
+
+//Status returns where the player stands in the queue, nil if it isn't queued
+func (matchmaker *Matchmaker) Status(email string) *Status {
+	result := make(chan *Status)
+	matchmaker.status <- statusRequest{email: email, result: result}
+	return <-result
+}
+
+//Cancel takes the player, with its party, out of the queue, it returns false if it wasn't queued
+func (matchmaker *Matchmaker) Cancel(email string) bool {
+	result := make(chan bool)
+	matchmaker.cancel <- cancelRequest{email: email, result: result}
+	return <-result
+}
+
+func (matchmaker *Matchmaker) Run() {
+	ticks := matchmaker.clock.Tick(matchmaker.config.Interval)
+
+	for {
+		select {
+		case req := <-matchmaker.enqueue:
+			req.result <- matchmaker.addTicket(req.ticket)
+		case req := <-matchmaker.status:
+			req.result <- matchmaker.ticketStatus(req.email)
+		case req := <-matchmaker.cancel:
+			_, queued := matchmaker.queued[req.email]
+			matching, ok := matchmaker.matching[req.email]
+			matchmaker.removeTicket(req.email)
+			if ok {
+				matchmaker.forget(matching)
+			}
+			req.result <- queued || ok
+		case result := <-matchmaker.results:
+			matchmaker.matched(result)
+		case <-ticks:
+			for gameID := range matchmaker.queues {
+				matchmaker.match(gameID)
+			}
+		}
+	}
+}
+
+func (matchmaker *Matchmaker) addTicket(ticket *Ticket) error {
+	emails := map[string]bool{}
+	for _, member := range members(ticket) {
+		if member.Email == "" || emails[member.Email] {
+			return errors.New("the party members need distinct emails")
+		}
+		emails[member.Email] = true
+		if _, ok := matchmaker.queued[member.Email]; ok {
+			return errors.New("player is already queued")
+		}
+		if _, ok := matchmaker.matching[member.Email]; ok {
+			return errors.New("player is already queued")
+		}
+	}
+	game, err := matchmaker.sessions.GetGame(ticket.GameID)
+	if err != nil {
+		return err
+	}
+	if ticket.PartySize > seats(game) {
+		return errors.New("party is too big for the game")
+	}
+	ticket.EnqueuedAt = matchmaker.clock.Now()
+	matchmaker.queue(ticket)
+	return nil
+}
+
+//queue puts the ticket in the queue of its game behind the ones who waited longer
+func (matchmaker *Matchmaker) queue(ticket *Ticket) {
+	for _, member := range members(ticket) {
+		matchmaker.queued[member.Email] = ticket
+	}
+	queue := matchmaker.queues[ticket.GameID]
+	i := len(queue)
+	for i > 0 && queue[i-1].EnqueuedAt.After(ticket.EnqueuedAt) {
+		i--
+	}
+	queue = append(queue, nil)
+	copy(queue[i+1:], queue[i:])
+	queue[i] = ticket
+	matchmaker.queues[ticket.GameID] = queue
+}
+
+//removeTicket takes the ticket of the player, or of its party, out of the queue
+func (matchmaker *Matchmaker) removeTicket(email string) {
+	ticket, ok := matchmaker.queued[email]
+	if !ok {
+		return
+	}
+	for _, member := range members(ticket) {
+		delete(matchmaker.queued, member.Email)
+	}
+
+	queue := matchmaker.queues[ticket.GameID]
+	for i, queuedTicket := range queue {
+		if queuedTicket == ticket {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) == 0 {
+		delete(matchmaker.queues, ticket.GameID)
+	} else {
+		matchmaker.queues[ticket.GameID] = queue
+	}
+}
+
+//members are the player of the ticket followed by the rest of its party
+func members(ticket *Ticket) []games.Player {
+	return append([]games.Player{ticket.Player}, ticket.Party...)
+}
+
+func (matchmaker *Matchmaker) ticketStatus(email string) *Status {
+	ticket, ok := matchmaker.queued[email]
+	if !ok {
+		return nil
+	}
+	now := matchmaker.clock.Now()
+	status := &Status{
+		GameID:    ticket.GameID,
+		Queued:    len(matchmaker.queues[ticket.GameID]),
+		Waited:    now.Sub(ticket.EnqueuedAt).Seconds(),
+		Window:    matchmaker.window(ticket, now),
+		Rating:    ticket.Rating,
+		PartySize: ticket.PartySize,
+	}
+	for i, queuedTicket := range matchmaker.queues[ticket.GameID] {
+		if queuedTicket == ticket {
+			status.Position = i + 1
+		}
+	}
+	return status
+}
+
+//window is the rating difference the ticket accepts, it widens the longer the ticket waits
+func (matchmaker *Matchmaker) window(ticket *Ticket, now time.Time) float64 {
+	window := matchmaker.config.InitialWindow + matchmaker.config.WindowGrowth*now.Sub(ticket.EnqueuedAt).Seconds()
+	if matchmaker.config.MaxWindow > 0 && window > matchmaker.config.MaxWindow {
+		window = matchmaker.config.MaxWindow
+	}
+	return window
+}
+
+//compatible checks that the ticket and every one in the group accept each other's rating
+func (matchmaker *Matchmaker) compatible(group []*Ticket, ticket *Ticket, now time.Time) bool {
+	for _, member := range group {
+		window := math.Min(matchmaker.window(member, now), matchmaker.window(ticket, now))
+		if math.Abs(member.Rating-ticket.Rating) > window {
+			return false
+		}
+	}
+	return true
+}
+
+//match goes over the queue from the player who waited the most and groups it with
+//the compatible players behind it until the game is full
+func (matchmaker *Matchmaker) match(gameID string) {
+	game, err := matchmaker.sessions.GetGame(gameID)
+	if err != nil {
+		return
+	}
+	now := matchmaker.clock.Now()
+	maxSeats := seats(game)
+	minSeats := game.MinPlayers
+	if minSeats < 2 {
+		minSeats = 2
+	}
+
+	for i := 0; i < len(matchmaker.queues[gameID]); i++ {
+		queue := matchmaker.queues[gameID]
+		anchor := queue[i]
+		group := []*Ticket{anchor}
+		size := anchor.PartySize
+
+		for _, ticket := range queue[i+1:] {
+			if size == maxSeats {
+				break
+			}
+			if size+ticket.PartySize <= maxSeats && matchmaker.compatible(group, ticket, now) {
+				group = append(group, ticket)
+				size += ticket.PartySize
+			}
+		}
+
+		filled := size == maxSeats
+		if !filled && matchmaker.config.FillTimeout > 0 && now.Sub(anchor.EnqueuedAt) >= matchmaker.config.FillTimeout {
+			filled = size >= minSeats
+		}
+		if !filled {
+			continue
+		}
+		matchmaker.startMatch(game, group)
+		//the queue changed, start over from the oldest remaining player
+		i = -1
+	}
+}
+
+//startMatch takes the group out of the queue and creates its session out of the matcher loop,
+//creating the session and telling the players can be slow
+func (matchmaker *Matchmaker) startMatch(game games.Game, group []*Ticket) {
+	for _, ticket := range group {
+		matchmaker.removeTicket(ticket.Player.Email)
+		for _, member := range members(ticket) {
+			matchmaker.matching[member.Email] = ticket
+		}
+	}
+	go func() {
+		matchmaker.results <- matchResult{group: group, created: matchmaker.createMatch(game, group)}
+	}()
+}
+
+//createMatch creates the session of the group, the players of the tickets are told about it
+//and the other members of their parties are invited
+func (matchmaker *Matchmaker) createMatch(game games.Game, group []*Ticket) bool {
+	players := make([]games.Player, 0, len(group))
+	var invited []games.Player
+	for _, ticket := range group {
+		players = append(players, ticket.Player)
+		invited = append(invited, ticket.Party...)
+	}
+
+	sessionID, err := matchmaker.sessions.CreateMatchedSession(game, players, invited)
+	if err != nil {
+		log.Printf("couldn't create a session for game %s: %v", game.ID, err)
+		return false
+	}
+
+	found := OnMatchFound{SessionID: sessionID, Game: game, Players: append(append([]games.Player(nil), players...), invited...)}
+	for _, ticket := range group {
+		msg, err := games.WrapCommand(games.ON_MATCH_FOUND, found, games.Player{})
+		if err != nil {
+			continue
+		}
+		if err := ticket.notifier.Notify(msg); err != nil {
+			log.Printf("couldn't notify %s of match %s: %v", ticket.Player.Email, sessionID, err)
+		}
+	}
+	return true
+}
+
+//matched forgets the tickets of the group once its session is created, or queues them again to
+//try on the next tick, unless their players cancelled meanwhile
+func (matchmaker *Matchmaker) matched(result matchResult) {
+	for _, ticket := range result.group {
+		if matchmaker.matching[ticket.Player.Email] != ticket {
+			continue
+		}
+		matchmaker.forget(ticket)
+		if !result.created {
+			matchmaker.queue(ticket)
+		}
+	}
+}
+
+//forget removes the ticket from the ones whose session is being created
+func (matchmaker *Matchmaker) forget(ticket *Ticket) {
+	for _, member := range members(ticket) {
+		delete(matchmaker.matching, member.Email)
+	}
+}
+
+//seats is the number of players of a full game
+func seats(game games.Game) int {
+	if game.MaxPlayers > 0 {
+		return game.MaxPlayers
+	}
+	if game.MinPlayers > 2 {
+		return game.MinPlayers
+	}
+	return 2
+}
@@ -0,0 +1,274 @@
+package matchmaking
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//fakeClock only moves when the test advances it, every advance wakes up the matcher
+type fakeClock struct {
+	now   time.Time
+	ticks chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC), ticks: make(chan time.Time)}
+}
+
+func (clock *fakeClock) Now() time.Time                        { return clock.now }
+func (clock *fakeClock) Tick(d time.Duration) <-chan time.Time { return clock.ticks }
+
+//advance moves the time forward and returns once the matcher is done with the tick
+func advance(matchmaker *Matchmaker, clock *fakeClock, d time.Duration) {
+	clock.now = clock.now.Add(d)
+	clock.ticks <- clock.now
+	//the matcher handles one request at a time, once it answers the matching is over
+	matchmaker.Status("")
+}
+
+type fakeSessions struct {
+	games map[string]games.Game
+	//fail makes the creation of the sessions fail
+	fail     bool
+	mu       sync.Mutex
+	sessions [][]games.Player
+	invited  [][]games.Player
+}
+
+func (sessions *fakeSessions) GetGame(gameID string) (games.Game, error) {
+	if game, ok := sessions.games[gameID]; ok {
+		return game, nil
+	}
+	return games.Game{}, errors.New("Game Is Not Supported")
+}
+
+func (sessions *fakeSessions) CreateMatchedSession(game games.Game, players []games.Player, invited []games.Player) (string, error) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	if sessions.fail {
+		return "", errors.New("couldn't create the session")
+	}
+	sessions.sessions = append(sessions.sessions, players)
+	sessions.invited = append(sessions.invited, invited)
+	return "session-" + strconv.Itoa(len(sessions.sessions)), nil
+}
+
+func (sessions *fakeSessions) created() [][]games.Player {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	return append([][]games.Player(nil), sessions.sessions...)
+}
+
+type fakeNotifier struct {
+	msgs chan games.GameMsg
+}
+
+func (notifier *fakeNotifier) Notify(msg games.GameMsg) error {
+	notifier.msgs <- msg
+	return nil
+}
+
+func newTestMatchmaker(config Config) (*Matchmaker, *fakeClock, *fakeSessions) {
+	clock := newFakeClock()
+	sessions := &fakeSessions{games: map[string]games.Game{
+		"duel":  {ID: "duel", MinPlayers: 2, MaxPlayers: 2},
+		"party": {ID: "party", MinPlayers: 2, MaxPlayers: 4},
+	}}
+	matchmaker := CreateMatchmaker(sessions, clock, config)
+	go matchmaker.Run()
+	return matchmaker, clock, sessions
+}
+
+//enqueue queues the player with a party of partySize players
+func enqueue(t *testing.T, matchmaker *Matchmaker, name string, gameID string, rating float64, partySize int) *fakeNotifier {
+	t.Helper()
+	notifier := &fakeNotifier{msgs: make(chan games.GameMsg, 1)}
+	ticket := Ticket{
+		Player: games.Player{Name: name, Email: name + "@gmail.com"},
+		GameID: gameID,
+		Rating: rating,
+	}
+	for i := 1; i < partySize; i++ {
+		member := name + "-friend" + strconv.Itoa(i)
+		ticket.Party = append(ticket.Party, games.Player{Name: member, Email: member + "@gmail.com"})
+	}
+	if err := matchmaker.Enqueue(ticket, notifier); err != nil {
+		t.Fatalf("Enqueue(%s) = %v", name, err)
+	}
+	return notifier
+}
+
+//matchFound waits for the match found message, the sessions are created out of the matcher loop
+func matchFound(t *testing.T, notifier *fakeNotifier) *OnMatchFound {
+	t.Helper()
+	select {
+	case msg := <-notifier.msgs:
+		var found OnMatchFound
+		if err := json.Unmarshal([]byte(msg.Data), &found); err != nil {
+			t.Fatal(err)
+		}
+		return &found
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+var testConfig = Config{InitialWindow: 100, WindowGrowth: 10, MaxWindow: 1000}
+
+func TestMatchmaker_MatchesCompatiblePlayers(t *testing.T) {
+	matchmaker, clock, sessions := newTestMatchmaker(testConfig)
+
+	dave := enqueue(t, matchmaker, "dave", "duel", 1500, 1)
+	dan := enqueue(t, matchmaker, "dan", "duel", 1550, 1)
+	advance(matchmaker, clock, time.Second)
+
+	daveMatch, danMatch := matchFound(t, dave), matchFound(t, dan)
+	if daveMatch == nil || danMatch == nil {
+		t.Fatal("compatible players were not matched")
+	}
+	if daveMatch.SessionID != danMatch.SessionID || len(sessions.created()) != 1 {
+		t.Errorf("players were sent to different sessions %s %s", daveMatch.SessionID, danMatch.SessionID)
+	}
+	//the one who waited the most hosts the game
+	if host := sessions.created()[0][0]; host.Name != "dave" {
+		t.Errorf("host = %s, want dave", host.Name)
+	}
+	if matchmaker.Status("dave@gmail.com") != nil {
+		t.Error("matched player is still queued")
+	}
+}
+
+func TestMatchmaker_WidensRatingWindow(t *testing.T) {
+	matchmaker, clock, _ := newTestMatchmaker(testConfig)
+
+	dave := enqueue(t, matchmaker, "dave", "duel", 1500, 1)
+	dan := enqueue(t, matchmaker, "dan", "duel", 1800, 1)
+
+	//a difference of 300 needs the window to grow for 20 seconds
+	advance(matchmaker, clock, 10*time.Second)
+	if matchFound(t, dave) != nil {
+		t.Fatal("players were matched before the window was wide enough")
+	}
+	status := matchmaker.Status("dan@gmail.com")
+	if status == nil || status.Position != 2 || status.Window != 200 {
+		t.Errorf("Status() = %+v, want position 2 and a window of 200", status)
+	}
+
+	advance(matchmaker, clock, 10*time.Second)
+	if matchFound(t, dave) == nil || matchFound(t, dan) == nil {
+		t.Error("players were not matched once the window was wide enough")
+	}
+}
+
+func TestMatchmaker_PartiesFillSeats(t *testing.T) {
+	matchmaker, clock, sessions := newTestMatchmaker(testConfig)
+
+	dave := enqueue(t, matchmaker, "dave", "party", 1500, 3)
+	dan := enqueue(t, matchmaker, "dan", "party", 1500, 2)
+	moti := enqueue(t, matchmaker, "moti", "party", 1500, 1)
+	advance(matchmaker, clock, time.Second)
+
+	//dan's party doesn't fit with dave's, moti completes the four seats
+	found := matchFound(t, dave)
+	if found == nil || matchFound(t, moti) == nil {
+		t.Fatal("party was not matched")
+	}
+	if matchFound(t, dan) != nil {
+		t.Error("too many players were matched")
+	}
+	//the members of dave's party are invited and the players are told about all four
+	if created := sessions.created(); len(created) != 1 || len(created[0]) != 2 || len(sessions.invited[0]) != 2 ||
+		sessions.invited[0][0].Email != "dave-friend1@gmail.com" || len(found.Players) != 4 {
+		t.Errorf("sessions = %v invited %v, found %+v", created, sessions.invited, found)
+	}
+	if matchmaker.Status("dave-friend1@gmail.com") != nil {
+		t.Error("a member of a matched party is still queued")
+	}
+
+	big := Ticket{Player: games.Player{Email: "big@gmail.com"}, GameID: "party"}
+	for i := 0; i < 4; i++ {
+		big.Party = append(big.Party, games.Player{Email: strconv.Itoa(i) + "@gmail.com"})
+	}
+	if err := matchmaker.Enqueue(big, &fakeNotifier{}); err == nil {
+		t.Error("Enqueue() accepted a party bigger than the game")
+	}
+	//a member of a queued party can't be queued again
+	if err := matchmaker.Enqueue(Ticket{Player: games.Player{Email: "dan-friend1@gmail.com"}, GameID: "party"}, &fakeNotifier{}); err == nil {
+		t.Error("Enqueue() accepted a player queued in a party")
+	}
+}
+
+func TestMatchmaker_CreateFails(t *testing.T) {
+	matchmaker, clock, sessions := newTestMatchmaker(testConfig)
+	sessions.mu.Lock()
+	sessions.fail = true
+	sessions.mu.Unlock()
+
+	dave := enqueue(t, matchmaker, "dave", "duel", 1500, 1)
+	dan := enqueue(t, matchmaker, "dan", "duel", 1500, 1)
+	advance(matchmaker, clock, time.Second)
+	if matchFound(t, dave) != nil {
+		t.Fatal("players were told about a session that wasn't created")
+	}
+
+	//the players are queued again in their order and matched once the session can be created
+	status := matchmaker.Status("dan@gmail.com")
+	for deadline := time.Now().Add(time.Second); status == nil && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+		status = matchmaker.Status("dan@gmail.com")
+	}
+	if status == nil || status.Position != 2 {
+		t.Fatalf("Status() after the failure = %+v, want position 2", status)
+	}
+	sessions.mu.Lock()
+	sessions.fail = false
+	sessions.mu.Unlock()
+	advance(matchmaker, clock, time.Second)
+	if matchFound(t, dave) == nil || matchFound(t, dan) == nil {
+		t.Error("players were not matched once the session could be created")
+	}
+}
+
+func TestMatchmaker_FillTimeout(t *testing.T) {
+	config := testConfig
+	config.FillTimeout = time.Minute
+	matchmaker, clock, _ := newTestMatchmaker(config)
+
+	dave := enqueue(t, matchmaker, "dave", "party", 1500, 1)
+	dan := enqueue(t, matchmaker, "dan", "party", 1500, 1)
+
+	advance(matchmaker, clock, 30*time.Second)
+	if matchFound(t, dave) != nil {
+		t.Fatal("players were matched before the game was full")
+	}
+	advance(matchmaker, clock, 30*time.Second)
+	if matchFound(t, dave) == nil || matchFound(t, dan) == nil {
+		t.Error("players were not matched once the fill timeout passed")
+	}
+}
+
+func TestMatchmaker_Cancel(t *testing.T) {
+	matchmaker, clock, _ := newTestMatchmaker(testConfig)
+
+	dave := enqueue(t, matchmaker, "dave", "duel", 1500, 1)
+	if !matchmaker.Cancel("dave@gmail.com") {
+		t.Fatal("Cancel() = false for a queued player")
+	}
+	if matchmaker.Cancel("dave@gmail.com") {
+		t.Error("Cancel() = true for a player no longer queued")
+	}
+
+	enqueue(t, matchmaker, "dan", "duel", 1500, 1)
+	advance(matchmaker, clock, time.Second)
+	if matchFound(t, dave) != nil {
+		t.Error("cancelled player was matched")
+	}
+}
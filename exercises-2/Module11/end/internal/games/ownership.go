@@ -0,0 +1,80 @@
+package games
+
+import (
+	"log"
+)
+
+//SessionOwnership lets the servers sharing the session store know which one runs each session
+type SessionOwnership interface {
+	//ClaimSession makes this server the owner of the session, it fails when another live server runs it
+	ClaimSession(sessionID string) error
+	ReleaseSession(sessionID string) error
+}
+
+type adoption struct {
+	session *GameSession
+	reply   chan *GameSession
+}
+
+//SetOwnership sets how the sessions are claimed when several servers run, it has to be called before Run
+func (manager *GameManager) SetOwnership(ownership SessionOwnership) {
+	manager.ownership = ownership
+}
+
+func (manager *GameManager) claimSession(sessionID string) error {
+	if manager.ownership == nil {
+		return nil
+	}
+	return manager.ownership.ClaimSession(sessionID)
+}
+
+func (manager *GameManager) releaseSession(sessionID string) {
+	if manager.ownership == nil {
+		return
+	}
+	if err := manager.ownership.ReleaseSession(sessionID); err != nil {
+		log.Printf("couldn't release session %s: %v", sessionID, err)
+	}
+}
+
+//AdoptSession brings back a persisted session no server runs anymore, like the sessions of a server that stopped,
+//it returns nil when there is no such session or another server runs it
+func (manager *GameManager) AdoptSession(sessionID string) *GameSession {
+	if manager.store == nil || manager.Draining() {
+		return nil
+	}
+	record, err := manager.store.GetSession(sessionID)
+	if err != nil {
+		if err != ErrNoSessionRecord {
+			log.Printf("couldn't load session %s: %v", sessionID, err)
+		}
+		return nil
+	}
+	game, err := manager.GetGame(record.GameID)
+	if err != nil {
+		return nil
+	}
+	if err := manager.claimSession(sessionID); err != nil {
+		return nil
+	}
+	session := manager.newGameSession(game, record.ID)
+	if err := session.restore(record); err != nil {
+		log.Printf("couldn't restore session %s: %v", record.ID, err)
+		return nil
+	}
+	reply := make(chan *GameSession)
+	manager.adopt <- &adoption{session: session, reply: reply}
+	return <-reply
+}
+
+//adoptSession runs the adopted session unless it is already running
+func (manager *GameManager) adoptSession(adopted *adoption) {
+	if session, ok := manager.activeGames[adopted.session.ID]; ok {
+		adopted.reply <- session
+		return
+	}
+	manager.activeGames[adopted.session.ID] = adopted.session
+	go adopted.session.Run()
+	log.Printf("adopted game session %s", adopted.session.ID)
+	adopted.reply <- adopted.session
+}
@@ -0,0 +1,67 @@
+package games
+
+import (
+	"testing"
+	"time"
+
+	"github.com/someuser/gameserver/internal/cluster"
+)
+
+func newClusterGameManager(store SessionStore, directory cluster.Directory, node cluster.Node) *GameManager {
+	manager := newGameManager()
+	manager.games[counterGame.ID] = counterGame
+	manager.SetSessionStore(store)
+	manager.SetOwnership(cluster.Membership{Directory: directory, Self: node})
+	go manager.Run()
+	return &manager
+}
+
+func TestGameManager_Ownership(t *testing.T) {
+	store := NewMemorySessionStore()
+	directory := cluster.NewMemoryDirectory(300 * time.Millisecond)
+	a := cluster.Node{ID: "a", Addr: "http://a:8080"}
+	b := cluster.Node{ID: "b", Addr: "http://b:8080"}
+	directory.Heartbeat(a)
+	host := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+
+	managerA := newClusterGameManager(store, directory, a)
+	session := managerA.CreateNewGameSession(counterGame)
+	go session.Run()
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{}, host)
+	session.SendToGame <- &startGame
+	waitForRecord(t, store, session.ID, func(SessionRecord) bool { return true })
+
+	if owner, err := directory.Owner(session.ID); err != nil || owner != a {
+		t.Fatalf("Owner() = %v, %v, want node a", owner, err)
+	}
+
+	//another replica starting on the same store leaves the session to the node running it
+	directory.Heartbeat(b)
+	managerB := newClusterGameManager(store, directory, b)
+	if managerB.GetSessionByID(session.ID) != nil {
+		t.Error("a session of a live node was restored by another node")
+	}
+	if managerB.AdoptSession(session.ID) != nil {
+		t.Error("a session of a live node was adopted by another node")
+	}
+
+	//once a stops sending heartbeats b takes its session over
+	time.Sleep(400 * time.Millisecond)
+	directory.Heartbeat(b)
+	adopted := managerB.AdoptSession(session.ID)
+	if adopted == nil {
+		t.Fatal("session of a dead node was not adopted")
+	}
+	if managerB.GetSessionByID(session.ID) != adopted {
+		t.Error("adopted session is not run by the node")
+	}
+	if owner, _ := directory.Owner(session.ID); owner != b {
+		t.Errorf("Owner() after adoption = %v, want node b", owner)
+	}
+	if again := managerB.AdoptSession(session.ID); again != adopted {
+		t.Error("adopting a session twice started it twice")
+	}
+	if managerB.AdoptSession("unknown") != nil {
+		t.Error("a session the store doesn't have was adopted")
+	}
+}
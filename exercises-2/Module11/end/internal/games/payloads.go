@@ -0,0 +1,131 @@
+package games
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+//ProtocolVersion is the version of the messages of this server, the messages of the clients that
+//don't send theirs are read as the first version
+const ProtocolVersion = 1
+
+//ErrUnsupportedVersion is returned for the messages of a newer protocol than the one of the server
+var ErrUnsupportedVersion = NewGameError(ErrCodeProtocolMismatch,
+	fmt.Sprintf("unsupported protocol version, the server speaks up to version %d", ProtocolVersion))
+
+//PayloadFactory creates the value the data of a message is decoded into, a pointer to a struct
+type PayloadFactory func() interface{}
+
+//Validator is implemented by the payloads that check their content once decoded
+type Validator interface {
+	Validate() error
+}
+
+//PayloadError is returned when the data of a message doesn't match the payload of its action
+type PayloadError struct {
+	Action GameAction
+	Err    error
+}
+
+func (err *PayloadError) Error() string {
+	return fmt.Sprintf("invalid %s message: %v", err.Action, err.Err)
+}
+
+type payloadKey struct {
+	gameID string
+	action GameAction
+}
+
+type versionedPayload struct {
+	version int
+	factory PayloadFactory
+}
+
+var (
+	payloadsMu sync.RWMutex
+	payloads   = make(map[payloadKey][]versionedPayload)
+)
+
+//RegisterPayload makes the messages of the action carry a payload of the type created by the factory
+//from the given protocol version on, it is meant to be called from the init function of a package
+func RegisterPayload(action GameAction, version int, factory PayloadFactory) {
+	registerPayload(payloadKey{action: action}, version, factory)
+}
+
+//RegisterGamePayload registers the payload of an action in a single game, it is used instead of
+//the one registered with RegisterPayload for the messages of the sessions of that game
+func RegisterGamePayload(gameID string, action GameAction, version int, factory PayloadFactory) {
+	if gameID == "" {
+		panic("games: RegisterGamePayload game id is empty")
+	}
+	registerPayload(payloadKey{gameID: gameID, action: action}, version, factory)
+}
+
+func registerPayload(key payloadKey, version int, factory PayloadFactory) {
+	payloadsMu.Lock()
+	defer payloadsMu.Unlock()
+
+	if factory == nil {
+		panic("games: RegisterPayload factory is nil")
+	}
+	if version < 1 || version > ProtocolVersion {
+		panic(fmt.Sprintf("games: RegisterPayload version %d of %s is not supported", version, key.action))
+	}
+	for _, registered := range payloads[key] {
+		if registered.version == version {
+			panic(fmt.Sprintf("games: RegisterPayload called twice for %s %s version %d", key.gameID, key.action, version))
+		}
+	}
+	versions := append(payloads[key], versionedPayload{version: version, factory: factory})
+	sort.Slice(versions, func(i, j int) bool { return versions[i].version < versions[j].version })
+	payloads[key] = versions
+}
+
+//payloadFactory returns the payload of the action in the latest version up to the given one
+func payloadFactory(gameID string, action GameAction, version int) PayloadFactory {
+	payloadsMu.RLock()
+	defer payloadsMu.RUnlock()
+
+	for _, key := range []payloadKey{{gameID: gameID, action: action}, {action: action}} {
+		versions := payloads[key]
+		for i := len(versions) - 1; i >= 0; i-- {
+			if versions[i].version <= version {
+				return versions[i].factory
+			}
+		}
+	}
+	return nil
+}
+
+//DecodePayload decodes the data of the message into the payload registered for the action in the game,
+//which is then validated, and sets it as the Payload of the message.
+//The messages of the actions without a payload are left as they are
+func DecodePayload(gameID string, msg *GameMsg) error {
+	version := msg.Version
+	if version == 0 {
+		version = 1
+	}
+	if version < 0 || version > ProtocolVersion {
+		return ErrUnsupportedVersion
+	}
+	factory := payloadFactory(gameID, msg.GameAction, version)
+	if factory == nil {
+		return nil
+	}
+	//a message without data has the zero payload
+	payload := factory()
+	if msg.Data != "" {
+		if err := json.Unmarshal([]byte(msg.Data), payload); err != nil {
+			return &PayloadError{Action: msg.GameAction, Err: err}
+		}
+	}
+	if validator, ok := payload.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return &PayloadError{Action: msg.GameAction, Err: err}
+		}
+	}
+	msg.Payload = payload
+	return nil
+}
@@ -0,0 +1,117 @@
+package games
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testMove struct {
+	Steps int `json:"steps"`
+}
+
+func (move *testMove) Validate() error {
+	if move.Steps <= 0 {
+		return errors.New("steps must be positive")
+	}
+	return nil
+}
+
+type testJump struct {
+	Height int `json:"height"`
+}
+
+func init() {
+	RegisterPayload("TEST_MOVE", 1, func() interface{} { return &testMove{} })
+	RegisterGamePayload("jumping", "TEST_MOVE", 1, func() interface{} { return &testJump{} })
+}
+
+func TestDecodePayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		gameID  string
+		msg     GameMsg
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "registered action", msg: GameMsg{GameAction: "TEST_MOVE", Data: `{"steps":2}`}, want: &testMove{Steps: 2}},
+		{name: "payload of the game", gameID: "jumping", msg: GameMsg{GameAction: "TEST_MOVE", Data: `{"height":3}`}, want: &testJump{Height: 3}},
+		{name: "action without payload", msg: GameMsg{GameAction: "CHAT", Data: "hello"}},
+		{name: "invalid json", msg: GameMsg{GameAction: "TEST_MOVE", Data: `{"steps":`}, wantErr: true},
+		{name: "wrong type", msg: GameMsg{GameAction: "TEST_MOVE", Data: `{"steps":"two"}`}, wantErr: true},
+		{name: "not valid", msg: GameMsg{GameAction: "TEST_MOVE", Data: `{"steps":0}`}, wantErr: true},
+		{name: "newer protocol", msg: GameMsg{GameAction: "CHAT", Version: ProtocolVersion + 1}, wantErr: true},
+		{name: "start game", msg: GameMsg{GameAction: START_GAME, Data: `{"players":[{"email":"dan@gmail.com"}]}`},
+			want: &StartGameMsg{Players: []Player{{Email: "dan@gmail.com"}}}},
+		{name: "start game without email", msg: GameMsg{GameAction: START_GAME, Data: `{"players":[{"name":"dan"}]}`}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			err := DecodePayload(tt.gameID, &msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodePayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(msg.Payload, tt.want) {
+				t.Errorf("Payload = %#v, want %#v", msg.Payload, tt.want)
+			}
+		})
+	}
+}
+
+//expectRejected waits for the message telling the player its message was not accepted with the code
+func expectRejected(t *testing.T, conn *fakeConn, code ErrorCode) {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-conn.written:
+			if msg.GameAction != ON_ERROR {
+				continue
+			}
+			var onError OnError
+			if err := json.Unmarshal([]byte(msg.Data), &onError); err != nil {
+				t.Fatal(err)
+			}
+			if onError.Code != code {
+				t.Errorf("rejected with %s (%s), want %s", onError.Code, onError.Message, code)
+			}
+			return
+		case <-deadline:
+			t.Fatal("the player was not told its message was rejected")
+		}
+	}
+}
+
+func TestGameSession_invalidMessages(t *testing.T) {
+	manager := newTestGameManager(nil)
+	session := manager.CreateNewGameSession(Game{ID: "chat"})
+	go session.Run()
+
+	conn := newFakeConn(false)
+	player := session.CreateNewPlayer(nil, 1, "dave", "dave123@gmail.com")
+	player.Start(conn)
+
+	//a message that isn't json
+	conn.incoming <- []byte("{not json")
+	expectRejected(t, conn, ErrCodeInvalidMessage)
+
+	//a message whose data doesn't match the payload of its action
+	conn.incoming <- []byte(`{"action":"START_GAME","data":"{\"players\":[{\"name\":\"dan\"}]}"}`)
+	expectRejected(t, conn, ErrCodeInvalidMessage)
+
+	//a message of a newer protocol
+	conn.incoming <- []byte(`{"action":"CHAT","version":99,"data":"hi"}`)
+	expectRejected(t, conn, ErrCodeProtocolMismatch)
+
+	//the player is still connected
+	info, _ := session.Info()
+	if len(info.Players) != 1 || !info.Players[0].Connected {
+		t.Errorf("players after the rejected messages = %+v", info.Players)
+	}
+}
@@ -2,17 +2,29 @@ package games
 
 import (
 	"log"
+	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/someuser/gameserver/internal/games/wire"
 )
 
 type Player struct {
-	ID          uint            `json:"id"`
-	Name        string          `json:"name"`
-	Email       string          `json:"email"`
-	Conn        *websocket.Conn `json:"-"`
-	RecvMsgChan chan GameMsg    `json:"-"`
-	GameSession *GameSession    `json:"-"`
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	Email       string       `json:"email"`
+	Conn        PlayerConn   `json:"-"`
+	GameSession *GameSession `json:"-"`
+	//Spectator players only watch the game, what they send is ignored
+	Spectator bool `json:"spectator,omitempty"`
+	//Bot players are played by the server, they are not rated
+	Bot bool `json:"bot,omitempty"`
+	//syncState is set for players joining an existing session, they are sent the state of the game once registered
+	syncState bool
+	//lastSeq is the last message the player got before losing its connection
+	lastSeq uint64
+	//outbox holds the messages waiting to be written to the connection
+	outbox *outbox
 }
 
 func (player *Player) IsConnected() bool {
@@ -21,65 +33,138 @@ func (player *Player) IsConnected() bool {
 	}
 	return false
 }
-func (player *Player) Start(conn *websocket.Conn) {
+func (player *Player) Start(conn PlayerConn) {
 
-	//init  connection and channel
+	//init  connection and queue
+	config := player.GameSession.gameManager.connConfig
 	player.Conn = conn
-	player.RecvMsgChan = make(chan GameMsg)
+	player.outbox = newOutbox(config.QueueSize, config.Overflow)
 
 	//register to session
-	player.GameSession.Register <- player
+	select {
+	case player.GameSession.Register <- player:
+	case <-player.GameSession.done:
+		conn.Close()
+		return
+	}
 
 	//open for recieving and sending
-	go player.handleMessageToPlayer()
-	go player.recieveMessages()
+	go player.handleMessageToPlayer(conn, player.outbox, config)
+	go player.recieveMessages(conn, config)
 
 }
 
+//Join starts a player joining an existing game, lastSeq is the sequence of the last message
+//the player got if it is reconnecting, the messages it missed are then sent again
+func (player *Player) Join(conn PlayerConn, lastSeq uint64) {
+	player.syncState = true
+	player.lastSeq = lastSeq
+	player.Start(conn)
+}
+
+//Stop closes the connection once the messages already queued are written
 func (player *Player) Stop() {
 
-	if player.RecvMsgChan != nil {
-		close(player.RecvMsgChan)
+	if player.outbox != nil {
+		player.outbox.close()
 	}
 }
 
 //RecieveMessages from the players
-func (player *Player) recieveMessages() {
+func (player *Player) recieveMessages(conn PlayerConn, config ConnConfig) {
 	defer func() {
-		player.GameSession.UnRegister <- player
+		select {
+		case player.GameSession.UnRegister <- player:
+		case <-player.GameSession.done:
+		}
 	}()
 
+	//a larger message closes the connection
+	conn.SetReadLimit(player.GameSession.gameManager.rateLimits.MaxMessageSize)
+	limiter := &inboundLimiter{}
+
+	//a peer that stops answering the pings is considered gone
+	conn.SetReadDeadline(time.Now().Add(config.pongWait()))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(config.pongWait()))
+	})
 	for {
 		var gameMsg GameMsg
-		if err := player.Conn.ReadJSON(&gameMsg); err != nil {
+		err := wire.Read(conn, &gameMsg)
+		//the connection is still fine when only the message couldn't be read
+		_, undecodable := err.(*wire.DecodeError)
+		if err != nil && !undecodable {
+			if err == websocket.ErrReadLimit {
+				inboundStats.Add("oversized", 1)
+				player.GameSession.gameManager.strike(player.Email, time.Now())
+			}
 			log.Println(err.Error())
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("error: %v %s %s", err, player.Email, player.GameSession.ID)
 			}
 			break
 		}
+		switch player.checkInbound(limiter, time.Now()) {
+		case inboundDrop:
+			continue
+		case inboundDisconnect:
+			return
+		}
+		if undecodable {
+			player.sendError(err, ErrCodeInvalidMessage, nil)
+			continue
+		}
+		//spectators only watch the game
+		if player.Spectator {
+			continue
+		}
 		//add the current user who sends the message
 		gameMsg.Player = *player
-		player.GameSession.SendToGame <- &gameMsg
+		select {
+		case player.GameSession.SendToGame <- &gameMsg:
+		case <-player.GameSession.done:
+			return
+		}
 	}
 }
+//SendMessage queues the message for the player, it never waits for the player to read it.
+//A player who falls too far behind gets the overflow policy of the session
 func (player *Player) SendMessage(msg *GameMsg) {
-	player.RecvMsgChan <- *msg
+	if player.outbox == nil {
+		return
+	}
+	if ok := player.outbox.push(*msg); !ok {
+		log.Printf("disconnecting %s from %s, too many messages are waiting to be sent", player.Email, player.GameSession.ID)
+		//closing unblocks a write stuck on the connection
+		player.Conn.Close()
+	}
 }
 
-//HandleMessageToPlayer sends the message from the game to the player through a channel
-func (player *Player) handleMessageToPlayer() {
+//HandleMessageToPlayer writes the queued messages to the connection and pings the peer,
+//the connection is closed when a write fails or takes too long
+func (player *Player) handleMessageToPlayer(conn PlayerConn, box *outbox, config ConnConfig) {
+	ticker := time.NewTicker(config.PingInterval)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
 
 	for {
 		select {
-		case msg, ok := <-player.RecvMsgChan:
-			if ok {
-				player.Conn.WriteJSON(msg)
-			} else {
-				if player.Conn != nil {
-					player.Conn.Close()
-					player.Conn = nil
+		case <-box.ready:
+			msgs, ok := box.take()
+			for _, msg := range msgs {
+				conn.SetWriteDeadline(time.Now().Add(config.WriteTimeout))
+				if err := wire.Write(conn, msg); err != nil {
+					log.Printf("error writing to %s: %v", player.Email, err)
+					return
 				}
+			}
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(config.WriteTimeout)); err != nil {
 				return
 			}
 		}
@@ -93,5 +178,7 @@ func (player *Player) SendCurrentGameStateToPlayer() {
 	if err != nil {
 		return
 	}
+	//the state includes everything broadcast so far
+	gameData.Seq = player.GameSession.seq
 	player.SendMessage(&gameData)
 }
@@ -0,0 +1,172 @@
+package games
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games/wire"
+)
+
+//the policies applied when the queue of a player who doesn't read fast enough is full
+const (
+	//OverflowDropOldest drops the oldest message of the queue
+	OverflowDropOldest = "drop-oldest"
+	//OverflowCoalesce keeps only the last of the queued state updates, the player is disconnected
+	//when there is none to drop and will get the whole state when rejoining
+	OverflowCoalesce = "coalesce"
+	//OverflowDisconnect disconnects the player, it will get what it missed when rejoining
+	OverflowDisconnect = "disconnect"
+)
+
+//PlayerConn is the connection of a player, a *websocket.Conn, the messages are encoded
+//with the codec of the subprotocol the player negotiated
+type PlayerConn interface {
+	wire.Conn
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetReadLimit(limit int64)
+	SetWriteDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+//ConnConfig is how the messages are sent to the players
+type ConnConfig struct {
+	//QueueSize is how many messages a player can fall behind before the overflow policy applies
+	QueueSize int
+	Overflow  string
+	//WriteTimeout is how long writing a message to a player can take before it is disconnected
+	WriteTimeout time.Duration
+	//PingInterval is how often the players are pinged, a player that doesn't answer within
+	//two intervals is disconnected
+	PingInterval time.Duration
+}
+
+//DefaultConnConfig type
+var DefaultConnConfig = ConnConfig{
+	QueueSize:    256,
+	Overflow:     OverflowCoalesce,
+	WriteTimeout: 10 * time.Second,
+	PingInterval: 30 * time.Second,
+}
+
+//Validate checks the config can be used
+func (config ConnConfig) Validate() error {
+	if config.QueueSize <= 0 {
+		return errors.New("the queue size of the players must be positive")
+	}
+	if config.WriteTimeout <= 0 || config.PingInterval <= 0 {
+		return errors.New("the write timeout and ping interval of the players must be positive")
+	}
+	switch config.Overflow {
+	case OverflowDropOldest, OverflowCoalesce, OverflowDisconnect:
+		return nil
+	}
+	return errors.New("unknown overflow policy " + config.Overflow)
+}
+
+func (config ConnConfig) pongWait() time.Duration {
+	return 2 * config.PingInterval
+}
+
+//outbox is the bounded queue of the messages waiting to be written to a player,
+//the session adds to it without ever waiting for the player
+type outbox struct {
+	mu       sync.Mutex
+	queue    []GameMsg
+	size     int
+	overflow string
+	//ready is signaled when messages are added, closed when the outbox is closed
+	ready  chan struct{}
+	closed bool
+}
+
+func newOutbox(size int, overflow string) *outbox {
+	return &outbox{
+		queue:    make([]GameMsg, 0, size),
+		size:     size,
+		overflow: overflow,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+//isStateUpdate tells if a message is replaced by the next one of the same action
+func isStateUpdate(msg GameMsg) bool {
+	return msg.GameAction == ON_GAME_STATE_CHANGED || msg.GameAction == UPDATE_GAME_STATE
+}
+
+//push queues the message, it returns false when the queue overflows and the player has to be disconnected
+func (box *outbox) push(msg GameMsg) bool {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+
+	if box.closed {
+		return true
+	}
+	if len(box.queue) >= box.size && !box.makeRoom(msg) {
+		//what is left isn't sent, the player gets it when rejoining
+		box.queue = nil
+		box.closeLocked()
+		return false
+	}
+	box.queue = append(box.queue, msg)
+	select {
+	case box.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+//makeRoom applies the overflow policy to the full queue, it returns false when the player has to be disconnected
+func (box *outbox) makeRoom(msg GameMsg) bool {
+	switch box.overflow {
+	case OverflowDropOldest:
+		box.queue = box.queue[1:]
+		return true
+	case OverflowCoalesce:
+		//only the last state update of each action is kept, it already holds the changes of the previous ones
+		last := make(map[GameAction]int)
+		for i, queued := range box.queue {
+			if isStateUpdate(queued) {
+				last[queued.GameAction] = i
+			}
+		}
+		kept := box.queue[:0]
+		for i, queued := range box.queue {
+			if isStateUpdate(queued) && (queued.GameAction == msg.GameAction || last[queued.GameAction] != i) {
+				continue
+			}
+			kept = append(kept, queued)
+		}
+		box.queue = kept
+		return len(box.queue) < box.size
+	}
+	return false
+}
+
+//take removes the queued messages, it returns false once the outbox is closed and
+//there is nothing left to write
+func (box *outbox) take() ([]GameMsg, bool) {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+
+	msgs := box.queue
+	box.queue = make([]GameMsg, 0, box.size)
+	return msgs, len(msgs) > 0 || !box.closed
+}
+
+//close stops the outbox, the messages already queued are still written
+func (box *outbox) close() {
+	box.mu.Lock()
+	defer box.mu.Unlock()
+
+	box.closeLocked()
+}
+
+func (box *outbox) closeLocked() {
+	if !box.closed {
+		box.closed = true
+		close(box.ready)
+	}
+}
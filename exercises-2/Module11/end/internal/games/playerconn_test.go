@@ -0,0 +1,208 @@
+package games
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+//fakeConn is a connection that records what is written to it and reads what is sent on incoming,
+//a frozen one never returns from a write
+type fakeConn struct {
+	frozen   bool
+	written  chan GameMsg
+	incoming chan []byte
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func newFakeConn(frozen bool) *fakeConn {
+	return &fakeConn{
+		frozen:   frozen,
+		written:  make(chan GameMsg, 1000),
+		incoming: make(chan []byte),
+		closed:   make(chan struct{}),
+	}
+}
+
+var errConnClosed = errors.New("connection closed")
+
+func (conn *fakeConn) Subprotocol() string { return "" }
+func (conn *fakeConn) ReadMessage() (int, []byte, error) {
+	select {
+	case data := <-conn.incoming:
+		return websocket.TextMessage, data, nil
+	case <-conn.closed:
+		return 0, nil, errConnClosed
+	}
+}
+func (conn *fakeConn) WriteMessage(messageType int, data []byte) error {
+	if conn.frozen {
+		<-conn.closed
+		return errConnClosed
+	}
+	var msg GameMsg
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	select {
+	case conn.written <- msg:
+		return nil
+	case <-conn.closed:
+		return errConnClosed
+	}
+}
+func (conn *fakeConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	return nil
+}
+func (conn *fakeConn) SetReadDeadline(t time.Time) error           { return nil }
+func (conn *fakeConn) SetReadLimit(limit int64)                    {}
+func (conn *fakeConn) SetWriteDeadline(t time.Time) error          { return nil }
+func (conn *fakeConn) SetPongHandler(h func(appData string) error) {}
+func (conn *fakeConn) Close() error {
+	conn.once.Do(func() { close(conn.closed) })
+	return nil
+}
+
+func msgsOf(actions ...GameAction) []GameMsg {
+	msgs := make([]GameMsg, len(actions))
+	for i, action := range actions {
+		msgs[i] = GameMsg{GameAction: action, Data: strconv.Itoa(i)}
+	}
+	return msgs
+}
+
+func TestOutbox_overflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		overflow string
+		queued   []GameMsg
+		push     GameMsg
+		wantOK   bool
+		want     []string
+	}{
+		{
+			name:     "drop oldest",
+			overflow: OverflowDropOldest,
+			queued:   msgsOf(GAME_PLAY, GAME_PLAY, GAME_PLAY),
+			push:     GameMsg{GameAction: GAME_PLAY, Data: "new"},
+			wantOK:   true,
+			want:     []string{"1", "2", "new"},
+		},
+		{
+			name:     "coalesce replaces the queued state",
+			overflow: OverflowCoalesce,
+			queued:   msgsOf(ON_GAME_STATE_CHANGED, GAME_PLAY, ON_GAME_STATE_CHANGED),
+			push:     GameMsg{GameAction: ON_GAME_STATE_CHANGED, Data: "new"},
+			wantOK:   true,
+			want:     []string{"1", "new"},
+		},
+		{
+			name:     "coalesce keeps the last state",
+			overflow: OverflowCoalesce,
+			queued:   msgsOf(ON_GAME_STATE_CHANGED, GAME_PLAY, ON_GAME_STATE_CHANGED),
+			push:     GameMsg{GameAction: GAME_PLAY, Data: "new"},
+			wantOK:   true,
+			want:     []string{"1", "2", "new"},
+		},
+		{
+			name:     "coalesce with nothing to drop disconnects",
+			overflow: OverflowCoalesce,
+			queued:   msgsOf(GAME_PLAY, GAME_PLAY, GAME_PLAY),
+			push:     GameMsg{GameAction: GAME_PLAY, Data: "new"},
+			wantOK:   false,
+		},
+		{
+			name:     "disconnect",
+			overflow: OverflowDisconnect,
+			queued:   msgsOf(GAME_PLAY, GAME_PLAY, GAME_PLAY),
+			push:     GameMsg{GameAction: GAME_PLAY, Data: "new"},
+			wantOK:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := newOutbox(len(tt.queued), tt.overflow)
+			for _, msg := range tt.queued {
+				box.push(msg)
+			}
+			if ok := box.push(tt.push); ok != tt.wantOK {
+				t.Fatalf("push() = %v, want %v", ok, tt.wantOK)
+			}
+			msgs, open := box.take()
+			if !tt.wantOK {
+				if open || len(msgs) != 0 {
+					t.Errorf("take() after an overflow = %d messages, %v, want nothing left", len(msgs), open)
+				}
+				return
+			}
+			var got []string
+			for _, msg := range msgs {
+				got = append(got, msg.Data)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("queued %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("queued %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestGameSession_frozenClient(t *testing.T) {
+	manager := newTestGameManager(nil)
+	manager.SetConnConfig(ConnConfig{QueueSize: 8, Overflow: OverflowDisconnect, WriteTimeout: time.Second, PingInterval: time.Minute})
+	session := manager.CreateNewGameSession(Game{ID: "chat"})
+	go session.Run()
+
+	frozenConn, healthyConn := newFakeConn(true), newFakeConn(false)
+	frozen := session.CreateNewPlayer(nil, 1, "dave", "dave123@gmail.com")
+	frozen.Start(frozenConn)
+	healthy := session.CreateNewPlayer(nil, 2, "dan", "dan@gmail.com")
+	healthy.Start(healthyConn)
+
+	//the session keeps broadcasting to the other player even though one of them never reads
+	const sent = 50
+	disconnected := false
+	for i := 0; i < sent; i++ {
+		msg, _ := WrapCommand("CHAT", strconv.Itoa(i), Player{})
+		select {
+		case session.SendToGame <- &msg:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("the session is blocked by the frozen client after %d messages", i)
+		}
+		for received := false; !received; {
+			select {
+			case msg := <-healthyConn.written:
+				received = msg.GameAction == "CHAT" && msg.Data == strconv.Itoa(i)
+				disconnected = disconnected || msg.GameAction == ON_USER_DISCONNECTED
+			case <-time.After(2 * time.Second):
+				t.Fatalf("the other player didn't get message %d", i)
+			}
+		}
+	}
+
+	//the frozen client is disconnected and the others are told
+	select {
+	case <-frozenConn.closed:
+	default:
+		t.Error("the connection of the frozen client was not closed")
+	}
+	for !disconnected {
+		select {
+		case msg := <-healthyConn.written:
+			disconnected = msg.GameAction == ON_USER_DISCONNECTED
+		case <-time.After(2 * time.Second):
+			t.Fatal("the other player was not told the frozen client left")
+		}
+	}
+}
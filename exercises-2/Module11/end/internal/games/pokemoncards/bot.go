@@ -0,0 +1,149 @@
+package pokemoncards
+
+import (
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//how many of the cards it saw a bot remembers for each difficulty, an easy bot flips cards at random
+var botMemory = map[string]int{
+	games.BotEasy:   0,
+	games.BotMedium: 4,
+	games.BotHard:   2 * maxPairs,
+}
+
+//Bot plays the memory game with legal moves, it remembers the faces of the last cards it saw turned over
+type Bot struct {
+	memory int
+	//seen holds the faces of the cards remembered, order the cards from the oldest seen
+	seen  map[int]string
+	order []int
+	//lastSeq is the sequence of the last state played, the same state is sent again to a bot that rejoins
+	lastSeq uint64
+	rand    *rand.Rand
+}
+
+//NewBot creates a bot with the given difficulty
+func NewBot(difficulty string) games.Bot {
+	return newBotWithRand(difficulty, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+func newBotWithRand(difficulty string, r *rand.Rand) *Bot {
+	memory, ok := botMemory[difficulty]
+	if !ok {
+		memory = botMemory[games.BotMedium]
+	}
+	return &Bot{memory: memory, seen: make(map[int]string), rand: r}
+}
+
+//Play turns over a card when the state says it is the turn of the bot
+func (bot *Bot) Play(self games.Player, msg games.GameMsg) []games.GameMsg {
+	if msg.GameAction != games.ON_GAME_STATE_CHANGED && msg.GameAction != games.ON_GAME_INIT {
+		return nil
+	}
+	var state State
+	if err := json.Unmarshal([]byte(msg.Data), &state); err != nil || len(state.Cards) == 0 {
+		return nil
+	}
+	bot.look(state)
+	if state.Turn != self.Email || (msg.Seq != 0 && msg.Seq <= bot.lastSeq) {
+		return nil
+	}
+	bot.lastSeq = msg.Seq
+
+	card := bot.choose(state)
+	if card < 0 {
+		return nil
+	}
+	move, err := games.WrapCommand(games.GAME_PLAY, FlipCard{Card: &card}, games.Player{})
+	if err != nil {
+		return nil
+	}
+	return []games.GameMsg{move}
+}
+
+//look remembers the cards turned over in the state and forgets the ones matched
+func (bot *Bot) look(state State) {
+	for i, card := range state.Cards {
+		if card.FaceUp && card.MatchedBy == "" {
+			bot.remember(i, card.Face)
+		}
+	}
+	for i, card := range state.LastMismatch {
+		if i < len(state.LastFaces) {
+			bot.remember(card, state.LastFaces[i])
+		}
+	}
+	order := bot.order[:0]
+	for _, card := range bot.order {
+		if card < len(state.Cards) && state.Cards[card].MatchedBy == "" {
+			order = append(order, card)
+		} else {
+			delete(bot.seen, card)
+		}
+	}
+	bot.order = order
+}
+
+func (bot *Bot) remember(card int, face string) {
+	if _, ok := bot.seen[card]; ok || bot.memory == 0 {
+		return
+	}
+	bot.seen[card] = face
+	bot.order = append(bot.order, card)
+	if len(bot.order) > bot.memory {
+		delete(bot.seen, bot.order[0])
+		bot.order = bot.order[1:]
+	}
+}
+
+//choose returns the card to turn over: the match of the card already turned over or of a pair it remembers,
+//else a card it hasn't seen, -1 when there is none left
+func (bot *Bot) choose(state State) int {
+	first := -1
+	for i, card := range state.Cards {
+		if card.FaceUp && card.MatchedBy == "" {
+			first = i
+		}
+	}
+	if first >= 0 {
+		for _, card := range bot.order {
+			if card != first && bot.seen[card] == state.Cards[first].Face {
+				return card
+			}
+		}
+		return bot.pick(state, first)
+	}
+	for i, card := range bot.order {
+		for _, other := range bot.order[i+1:] {
+			if bot.seen[card] == bot.seen[other] {
+				return card
+			}
+		}
+	}
+	return bot.pick(state, -1)
+}
+
+//pick returns a random card that can be turned over, preferably one the bot doesn't remember
+func (bot *Bot) pick(state State, first int) int {
+	var unseen, legal []int
+	for i, card := range state.Cards {
+		if card.FaceUp || i == first {
+			continue
+		}
+		legal = append(legal, i)
+		if _, ok := bot.seen[i]; !ok {
+			unseen = append(unseen, i)
+		}
+	}
+	if len(unseen) > 0 {
+		return unseen[bot.rand.Intn(len(unseen))]
+	}
+	if len(legal) > 0 {
+		return legal[bot.rand.Intn(len(legal))]
+	}
+	return -1
+}
@@ -0,0 +1,97 @@
+package pokemoncards
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+func TestBot_Play(t *testing.T) {
+	for _, difficulty := range []string{games.BotEasy, games.BotMedium, games.BotHard} {
+		t.Run(difficulty, func(t *testing.T) {
+			daveBot := games.Player{Name: "Bot 1", Email: "bot1@bots.invalid", Bot: true}
+			danBot := games.Player{Name: "Bot 2", Email: "bot2@bots.invalid", Bot: true}
+			game := newWithRand(rand.New(rand.NewSource(1)))
+			if err := game.Start([]games.Player{daveBot, danBot}, `{"pairs":6}`); err != nil {
+				t.Fatalf("Start() = %v", err)
+			}
+			bots := map[string]*Bot{
+				daveBot.Email: newBotWithRand(difficulty, rand.New(rand.NewSource(2))),
+				danBot.Email:  newBotWithRand(difficulty, rand.New(rand.NewSource(3))),
+			}
+
+			//both bots see every state, only the one whose turn it is plays
+			for moves := 0; moves < 500; moves++ {
+				if over, _ := game.GameOver(); over {
+					return
+				}
+				state := games.GameMsg{GameAction: games.ON_GAME_STATE_CHANGED, Data: game.State()}
+				played := 0
+				for _, player := range []games.Player{daveBot, danBot} {
+					for _, move := range bots[player.Email].Play(player, state) {
+						play(t, game, player, parseCard(t, move))
+						played++
+					}
+				}
+				if played != 1 {
+					t.Fatalf("%d moves were played on state %s", played, state.Data)
+				}
+			}
+			t.Fatal("the bots didn't finish the game")
+		})
+	}
+}
+
+func TestBot_remembers(t *testing.T) {
+	self := games.Player{Name: "Bot 1", Email: "bot1@bots.invalid", Bot: true}
+	//the bot saw the faces of cards 0 and 3 when dave mismatched them, card 5 is turned over
+	state := State{
+		Cards:        make([]Card, 6),
+		Turn:         self.Email,
+		LastMismatch: []int{0, 3},
+		LastFaces:    []string{"pikachu", "eevee"},
+	}
+	state.Cards[5] = Card{Face: "pikachu", FaceUp: true}
+
+	for _, difficulty := range []string{games.BotMedium, games.BotHard} {
+		t.Run(difficulty, func(t *testing.T) {
+			bot := newBotWithRand(difficulty, rand.New(rand.NewSource(1)))
+			data, _ := games.WrapCommand(games.ON_GAME_STATE_CHANGED, state, games.Player{})
+			data.Seq = 7
+			moves := bot.Play(self, data)
+			if len(moves) != 1 {
+				t.Fatalf("moves = %+v, want 1", moves)
+			}
+			if card := parseCard(t, moves[0]); card != 0 {
+				t.Errorf("card turned over = %d, want the pikachu it saw at 0", card)
+			}
+			//the same state sent again is not played twice
+			if moves := bot.Play(self, data); len(moves) != 0 {
+				t.Errorf("moves on the same state = %+v", moves)
+			}
+		})
+	}
+
+	//an easy bot doesn't remember the cards, it never turns over the one already turned over
+	bot := newBotWithRand(games.BotEasy, rand.New(rand.NewSource(1)))
+	data, _ := games.WrapCommand(games.ON_GAME_STATE_CHANGED, state, games.Player{})
+	if moves := bot.Play(self, data); len(moves) != 1 || parseCard(t, moves[0]) == 5 {
+		t.Errorf("moves of the easy bot = %+v", moves)
+	}
+	if len(bot.seen) != 0 {
+		t.Errorf("the easy bot remembers %v", bot.seen)
+	}
+}
+
+func parseCard(t *testing.T, move games.GameMsg) int {
+	t.Helper()
+	if move.GameAction != games.GAME_PLAY {
+		t.Fatalf("move = %+v, want a %s", move, games.GAME_PLAY)
+	}
+	card, err := parseMove(&move)
+	if err != nil {
+		t.Fatalf("move %s: %v", move.Data, err)
+	}
+	return card
+}
@@ -0,0 +1,396 @@
+package pokemoncards
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//GameID is the id of the game as configured in the app.env
+const GameID = "pokemoncards"
+
+const (
+	defaultPairs = 8
+	maxPairs     = 32
+)
+
+var defaultFaces = []string{
+	"pikachu", "bulbasaur", "charmander", "squirtle", "jigglypuff", "meowth", "psyduck", "snorlax",
+	"eevee", "mewtwo", "gengar", "onix", "vulpix", "magikarp", "gyarados", "lapras",
+	"ditto", "dragonite", "machop", "abra", "geodude", "ponyta", "slowpoke", "magnemite",
+	"growlithe", "poliwag", "oddish", "zubat", "pidgey", "rattata", "clefairy", "togepi",
+}
+
+func init() {
+	games.RegisterGameLogic(GameID, New)
+	games.RegisterBot(GameID, NewBot)
+	games.RegisterGamePayload(GameID, games.GAME_PLAY, 1, func() interface{} { return &FlipCard{} })
+}
+
+//Options are sent by the host as the game data of START_GAME
+type Options struct {
+	Pairs int      `json:"pairs"`
+	Faces []string `json:"faces"`
+}
+
+//FlipCard is the data of a GAME_PLAY message, the index of the card to turn over.
+//Card is required, a move without it would turn over the first card
+type FlipCard struct {
+	Card *int `json:"card"`
+}
+
+//Validate checks there is a card and that it can be in a deck
+func (flip *FlipCard) Validate() error {
+	if flip.Card == nil {
+		return errors.New("the card to turn over is missing")
+	}
+	if *flip.Card < 0 {
+		return errors.New("no such card")
+	}
+	return nil
+}
+
+//Card is a card as seen by the players, the face is only visible once the card is turned over
+type Card struct {
+	Face      string `json:"face,omitempty"`
+	FaceUp    bool   `json:"faceUp"`
+	MatchedBy string `json:"matchedBy,omitempty"`
+}
+
+//PlayerScore type
+type PlayerScore struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	//Forfeited players are out of the game
+	Forfeited bool `json:"forfeited,omitempty"`
+}
+
+//State is the state of the game sent to the players after each move
+type State struct {
+	Cards        []Card        `json:"cards"`
+	Players      []PlayerScore `json:"players"`
+	Turn         string        `json:"turn"`
+	LastMismatch []int         `json:"lastMismatch,omitempty"`
+	LastFaces    []string      `json:"lastFaces,omitempty"`
+}
+
+//MemoryGame implements games.GameLogic for the pokemon memory game
+type MemoryGame struct {
+	deck         []string
+	matchedBy    []string
+	flipped      []int
+	lastMismatch []int
+	players      []games.Player
+	scores       map[string]int
+	forfeited    map[string]bool
+	turn         int
+	rand         *rand.Rand
+}
+
+//New creates a new memory game
+func New() games.GameLogic {
+	return newWithRand(rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+func newWithRand(r *rand.Rand) *MemoryGame {
+	return &MemoryGame{rand: r}
+}
+
+//Start deals a shuffled deck of pairs, the host is the first to play
+func (game *MemoryGame) Start(players []games.Player, options string) error {
+	if len(players) == 0 {
+		return errors.New("can't start a game without players")
+	}
+
+	opts := Options{Pairs: defaultPairs}
+	if options != "" {
+		if err := json.Unmarshal([]byte(options), &opts); err != nil {
+			return errors.New("invalid game options")
+		}
+	}
+	faces := opts.Faces
+	if len(faces) == 0 {
+		faces = defaultFaces
+	}
+	if opts.Pairs <= 0 {
+		opts.Pairs = defaultPairs
+	}
+	if opts.Pairs > len(faces) || opts.Pairs > maxPairs {
+		return errors.New("not enough cards for the number of pairs requested")
+	}
+
+	game.deck = make([]string, 0, opts.Pairs*2)
+	for _, face := range faces[:opts.Pairs] {
+		game.deck = append(game.deck, face, face)
+	}
+	game.rand.Shuffle(len(game.deck), func(i, j int) {
+		game.deck[i], game.deck[j] = game.deck[j], game.deck[i]
+	})
+
+	game.matchedBy = make([]string, len(game.deck))
+	game.flipped = nil
+	game.lastMismatch = nil
+	game.players = players
+	game.scores = make(map[string]int)
+	game.forfeited = make(map[string]bool)
+	game.turn = 0
+	return nil
+}
+
+//ValidateMove checks that it is the turn of the player and that the card can be turned over
+func (game *MemoryGame) ValidateMove(player games.Player, move *games.GameMsg) error {
+	if len(game.deck) == 0 {
+		return errors.New("the game has not started")
+	}
+	if game.players[game.turn].Email != player.Email {
+		return errors.New("not your turn")
+	}
+	card, err := parseMove(move)
+	if err != nil {
+		return err
+	}
+	if card < 0 || card >= len(game.deck) {
+		return errors.New("no such card")
+	}
+	if game.matchedBy[card] != "" {
+		return errors.New("card was already matched")
+	}
+	for _, flipped := range game.flipped {
+		if flipped == card {
+			return errors.New("card is already turned over")
+		}
+	}
+	return nil
+}
+
+//ApplyMove turns over the card, on the second card of a turn the pair is either
+//matched and the player plays again, or turned back and the turn moves on
+func (game *MemoryGame) ApplyMove(player games.Player, move *games.GameMsg) error {
+	card, err := parseMove(move)
+	if err != nil {
+		return err
+	}
+
+	if len(game.flipped) == 0 {
+		game.flipped = []int{card}
+		game.lastMismatch = nil
+		return nil
+	}
+
+	first := game.flipped[0]
+	game.flipped = nil
+	if game.deck[first] == game.deck[card] {
+		game.matchedBy[first] = player.Email
+		game.matchedBy[card] = player.Email
+		game.scores[player.Email]++
+		game.lastMismatch = nil
+		return nil
+	}
+
+	game.lastMismatch = []int{first, card}
+	game.nextTurn()
+	return nil
+}
+
+//nextTurn passes the turn to the next player still in the game
+func (game *MemoryGame) nextTurn() {
+	for i := 1; i <= len(game.players); i++ {
+		next := (game.turn + i) % len(game.players)
+		if !game.forfeited[game.players[next].Email] {
+			game.turn = next
+			return
+		}
+	}
+}
+
+//CurrentPlayer returns the player who has to turn over a card
+func (game *MemoryGame) CurrentPlayer() (games.Player, bool) {
+	if over, _ := game.GameOver(); over || len(game.deck) == 0 {
+		return games.Player{}, false
+	}
+	return game.players[game.turn], true
+}
+
+//Players returns the players the game was started with, the ones who forfeited included
+func (game *MemoryGame) Players() []games.Player {
+	return game.players
+}
+
+//SkipTurn turns back the card the current player may have turned over and passes the turn
+func (game *MemoryGame) SkipTurn() error {
+	if _, ok := game.CurrentPlayer(); !ok {
+		return errors.New("no one is playing")
+	}
+	game.flipped = nil
+	game.lastMismatch = nil
+	game.nextTurn()
+	return nil
+}
+
+//Forfeit takes the player out of the game, the last player left wins
+func (game *MemoryGame) Forfeit(player games.Player) error {
+	if len(game.deck) == 0 {
+		return errors.New("the game has not started")
+	}
+	for i, p := range game.players {
+		if p.Email != player.Email {
+			continue
+		}
+		if game.forfeited[p.Email] {
+			return errors.New("player already forfeited")
+		}
+		game.forfeited[p.Email] = true
+		if i == game.turn {
+			game.flipped = nil
+			game.lastMismatch = nil
+			game.nextTurn()
+		}
+		return nil
+	}
+	return errors.New("player is not in the game")
+}
+
+//State hides the faces of the cards that are not turned over
+func (game *MemoryGame) State() string {
+	state := State{
+		Cards:        make([]Card, len(game.deck)),
+		Players:      make([]PlayerScore, 0, len(game.players)),
+		LastMismatch: game.lastMismatch,
+	}
+	for i := range game.deck {
+		if game.matchedBy[i] != "" {
+			state.Cards[i] = Card{Face: game.deck[i], FaceUp: true, MatchedBy: game.matchedBy[i]}
+		}
+	}
+	for _, i := range game.flipped {
+		state.Cards[i] = Card{Face: game.deck[i], FaceUp: true}
+	}
+	for _, i := range game.lastMismatch {
+		state.LastFaces = append(state.LastFaces, game.deck[i])
+	}
+	for _, player := range game.players {
+		state.Players = append(state.Players, PlayerScore{
+			Email:     player.Email,
+			Name:      player.Name,
+			Score:     game.scores[player.Email],
+			Forfeited: game.forfeited[player.Email],
+		})
+	}
+	if len(game.players) > 0 {
+		state.Turn = game.players[game.turn].Email
+	}
+
+	data, _ := json.Marshal(state)
+	return string(data)
+}
+
+//GameOver is reached when all the pairs are matched, the players with the most pairs win,
+//or when the other players forfeited, the last player left wins
+func (game *MemoryGame) GameOver() (bool, games.GameResult) {
+	if len(game.deck) == 0 {
+		return false, games.GameResult{}
+	}
+	left := len(game.players) - len(game.forfeited)
+	over := left == 0 || (left == 1 && len(game.players) > 1)
+	for _, matchedBy := range game.matchedBy {
+		if matchedBy == "" && !over {
+			return false, games.GameResult{}
+		}
+	}
+
+	best := -1
+	for _, player := range game.players {
+		if score := game.scores[player.Email]; score > best && !game.forfeited[player.Email] {
+			best = score
+		}
+	}
+	result := games.GameResult{Scores: make(map[string]int)}
+	for _, player := range game.players {
+		score := game.scores[player.Email]
+		result.Scores[player.Email] = score
+		if score == best && !game.forfeited[player.Email] {
+			result.Winners = append(result.Winners, games.Player{ID: player.ID, Name: player.Name, Email: player.Email})
+		}
+	}
+	if over {
+		result.Message = "the other players forfeited"
+	} else if len(result.Winners) > 1 {
+		result.Message = "it's a tie"
+	}
+	return true, result
+}
+
+//snapshot is the full state of the game including the faces of the hidden cards
+type snapshot struct {
+	Deck         []string        `json:"deck"`
+	MatchedBy    []string        `json:"matchedBy"`
+	Flipped      []int           `json:"flipped"`
+	LastMismatch []int           `json:"lastMismatch"`
+	Players      []games.Player  `json:"players"`
+	Scores       map[string]int  `json:"scores"`
+	Forfeited    map[string]bool `json:"forfeited,omitempty"`
+	Turn         int             `json:"turn"`
+}
+
+//Snapshot saves the game so it can be restored after a restart of the server
+func (game *MemoryGame) Snapshot() (string, error) {
+	data, err := json.Marshal(snapshot{
+		Deck:         game.deck,
+		MatchedBy:    game.matchedBy,
+		Flipped:      game.flipped,
+		LastMismatch: game.lastMismatch,
+		Players:      game.players,
+		Scores:       game.scores,
+		Forfeited:    game.forfeited,
+		Turn:         game.turn,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+//Restore resumes the game from a snapshot
+func (game *MemoryGame) Restore(state string) error {
+	var saved snapshot
+	if err := json.Unmarshal([]byte(state), &saved); err != nil {
+		return err
+	}
+	if len(saved.MatchedBy) != len(saved.Deck) || (len(saved.Players) > 0 && saved.Turn >= len(saved.Players)) {
+		return errors.New("invalid memory game snapshot")
+	}
+	if saved.Scores == nil {
+		saved.Scores = make(map[string]int)
+	}
+	if saved.Forfeited == nil {
+		saved.Forfeited = make(map[string]bool)
+	}
+	game.deck = saved.Deck
+	game.matchedBy = saved.MatchedBy
+	game.flipped = saved.Flipped
+	game.lastMismatch = saved.LastMismatch
+	game.players = saved.Players
+	game.scores = saved.Scores
+	game.forfeited = saved.Forfeited
+	game.turn = saved.Turn
+	return nil
+}
+
+func parseMove(move *games.GameMsg) (int, error) {
+	//the session decodes the move, the moves given directly to the game are decoded here
+	if flip, ok := move.Payload.(*FlipCard); ok {
+		return *flip.Card, nil
+	}
+	var flip FlipCard
+	if err := json.Unmarshal([]byte(move.Data), &flip); err != nil {
+		return 0, errors.New("invalid move, expected a card to flip")
+	}
+	if err := flip.Validate(); err != nil {
+		return 0, err
+	}
+	return *flip.Card, nil
+}
@@ -0,0 +1,221 @@
+package pokemoncards
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+var (
+	dave = games.Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan  = games.Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+)
+
+func flip(card int) *games.GameMsg {
+	return &games.GameMsg{GameAction: games.GAME_PLAY, Data: `{"card":` + strconv.Itoa(card) + `}`}
+}
+
+//pairOf finds the other card with the same face
+func pairOf(game *MemoryGame, card int) int {
+	for i, face := range game.deck {
+		if i != card && face == game.deck[card] {
+			return i
+		}
+	}
+	return -1
+}
+
+//mismatchOf finds a card still in play with a different face
+func mismatchOf(game *MemoryGame, card int) int {
+	for i, face := range game.deck {
+		if face != game.deck[card] && game.matchedBy[i] == "" {
+			return i
+		}
+	}
+	return -1
+}
+
+func play(t *testing.T, game *MemoryGame, player games.Player, card int) {
+	t.Helper()
+	if err := game.ValidateMove(player, flip(card)); err != nil {
+		t.Fatalf("ValidateMove(%s, %d) = %v", player.Name, card, err)
+	}
+	if err := game.ApplyMove(player, flip(card)); err != nil {
+		t.Fatalf("ApplyMove(%s, %d) = %v", player.Name, card, err)
+	}
+}
+
+func TestMemoryGame_Start(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		cards   int
+		wantErr bool
+	}{
+		{name: "default options", options: "", cards: defaultPairs * 2},
+		{name: "custom pairs", options: `{"pairs":3}`, cards: 6},
+		{name: "custom faces", options: `{"pairs":2,"faces":["a","b"]}`, cards: 4},
+		{name: "too many pairs", options: `{"pairs":3,"faces":["a","b"]}`, wantErr: true},
+		{name: "invalid options", options: `pairs`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newWithRand(rand.New(rand.NewSource(1)))
+			err := game.Start([]games.Player{dave, dan}, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Start() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(game.deck) != tt.cards {
+				t.Errorf("Start() dealt %d cards, want %d", len(game.deck), tt.cards)
+			}
+		})
+	}
+}
+
+func TestMemoryGame_Turns(t *testing.T) {
+	game := newWithRand(rand.New(rand.NewSource(1)))
+	if err := game.Start([]games.Player{dave, dan}, `{"pairs":4}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := game.ValidateMove(dan, flip(0)); err == nil {
+		t.Error("ValidateMove() accepted a move out of turn")
+	}
+
+	//a match keeps the turn
+	play(t, game, dave, 0)
+	if err := game.ValidateMove(dave, flip(0)); err == nil {
+		t.Error("ValidateMove() accepted a card that is already turned over")
+	}
+	play(t, game, dave, pairOf(game, 0))
+	if game.scores[dave.Email] != 1 {
+		t.Errorf("score = %d, want 1", game.scores[dave.Email])
+	}
+	if err := game.ValidateMove(dave, flip(0)); err == nil {
+		t.Error("ValidateMove() accepted a card that was already matched")
+	}
+
+	//a mismatch passes the turn
+	first := -1
+	for i := range game.deck {
+		if game.matchedBy[i] == "" {
+			first = i
+			break
+		}
+	}
+	play(t, game, dave, first)
+	play(t, game, dave, mismatchOf(game, first))
+
+	var state State
+	if err := json.Unmarshal([]byte(game.State()), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Turn != dan.Email {
+		t.Errorf("turn = %s, want %s", state.Turn, dan.Email)
+	}
+	if len(state.LastMismatch) != 2 || len(state.LastFaces) != 2 {
+		t.Errorf("last mismatch = %v %v, want both cards", state.LastMismatch, state.LastFaces)
+	}
+	for i, card := range state.Cards {
+		if game.matchedBy[i] == "" && card.Face != "" {
+			t.Errorf("card %d face is visible before being turned over", i)
+		}
+	}
+}
+
+func TestMemoryGame_GameOver(t *testing.T) {
+	game := newWithRand(rand.New(rand.NewSource(7)))
+	if err := game.Start([]games.Player{dave, dan}, `{"pairs":3}`); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range game.deck {
+		if over, _ := game.GameOver(); over {
+			break
+		}
+		if game.matchedBy[i] != "" {
+			continue
+		}
+		play(t, game, dave, i)
+		play(t, game, dave, pairOf(game, i))
+	}
+
+	over, result := game.GameOver()
+	if !over {
+		t.Fatal("GameOver() = false after all pairs were matched")
+	}
+	if len(result.Winners) != 1 || result.Winners[0].Email != dave.Email {
+		t.Errorf("winners = %v, want %s", result.Winners, dave.Email)
+	}
+	if result.Scores[dave.Email] != 3 || result.Scores[dan.Email] != 0 {
+		t.Errorf("scores = %v", result.Scores)
+	}
+}
+
+func TestMemoryGame_SkipAndForfeit(t *testing.T) {
+	ann := games.Player{ID: 3, Name: "ann", Email: "ann@gmail.com"}
+	game := newWithRand(rand.New(rand.NewSource(1)))
+	if err := game.Start([]games.Player{dave, dan, ann}, `{"pairs":3}`); err != nil {
+		t.Fatal(err)
+	}
+
+	//the card turned over by dave is turned back when the turn is skipped
+	play(t, game, dave, 0)
+	if err := game.SkipTurn(); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := game.CurrentPlayer(); current.Email != dan.Email || len(game.flipped) != 0 {
+		t.Fatalf("after skipping dave it is the turn of %s with %v turned over", current.Name, game.flipped)
+	}
+
+	//the turn of a player who forfeits goes to the next one still in the game
+	game.Forfeit(dan)
+	if current, _ := game.CurrentPlayer(); current.Email != ann.Email {
+		t.Errorf("after dan forfeited it is the turn of %s, want ann", current.Name)
+	}
+	if err := game.Forfeit(dan); err == nil {
+		t.Error("Forfeit() twice succeeded")
+	}
+	game.SkipTurn()
+	if current, _ := game.CurrentPlayer(); current.Email != dave.Email {
+		t.Errorf("dan was not skipped, it is the turn of %s", current.Name)
+	}
+
+	game.Forfeit(ann)
+	over, result := game.GameOver()
+	if !over || len(result.Winners) != 1 || result.Winners[0].Email != dave.Email {
+		t.Errorf("GameOver() = %v, %+v, want dave to win as the last player left", over, result)
+	}
+	if _, ok := game.CurrentPlayer(); ok {
+		t.Error("CurrentPlayer() returned a player after the game ended")
+	}
+}
+
+func TestFlipCard_decode(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantCard int
+		wantErr  bool
+	}{
+		{name: "first card", data: `{"card":0}`, wantCard: 0},
+		{name: "no data", data: "", wantErr: true},
+		{name: "no card", data: `{}`, wantErr: true},
+		{name: "negative card", data: `{"card":-1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			move := &games.GameMsg{GameAction: games.GAME_PLAY, Data: tt.data}
+			err := games.DecodePayload(GameID, move)
+			if _, invalid := err.(*games.PayloadError); invalid != tt.wantErr {
+				t.Fatalf("DecodePayload() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if card, err := parseMove(move); (err != nil) != tt.wantErr || (err == nil && card != tt.wantCard) {
+				t.Errorf("parseMove() = %d, %v", card, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package games
+
+import (
+	"errors"
+	"expvar"
+	"log"
+	"sync"
+	"time"
+)
+
+//RateLimitConfig is how much the players can send to their session. A player over its limit is warned
+//and its messages are dropped, after DisconnectAfter dropped messages it is disconnected and after
+//BanAfter disconnections it is kept out of new sessions for a while
+type RateLimitConfig struct {
+	//PlayerRate is how many messages per second a player can send once it sent a burst of PlayerBurst
+	PlayerRate  float64
+	PlayerBurst int
+	//SessionRate and SessionBurst limit the messages of all the players of a session together
+	SessionRate  float64
+	SessionBurst int
+	//MaxMessageSize is the largest message in bytes, a player sending a larger one is disconnected
+	MaxMessageSize int64
+	//DisconnectAfter is how many messages of a connection are dropped before it is closed, 0 never closes it
+	DisconnectAfter int
+	//BanAfter disconnections within BanDuration ban the player from new sessions for BanDuration, 0 never bans
+	BanAfter    int
+	BanDuration time.Duration
+}
+
+//DefaultRateLimitConfig type
+var DefaultRateLimitConfig = RateLimitConfig{
+	PlayerRate:      20,
+	PlayerBurst:     40,
+	SessionRate:     100,
+	SessionBurst:    200,
+	MaxMessageSize:  64 * 1024,
+	DisconnectAfter: 50,
+	BanAfter:        3,
+	BanDuration:     10 * time.Minute,
+}
+
+//Validate checks the config can be used
+func (config RateLimitConfig) Validate() error {
+	if config.PlayerRate <= 0 || config.PlayerBurst <= 0 || config.SessionRate <= 0 || config.SessionBurst <= 0 {
+		return errors.New("the inbound rates and bursts must be positive")
+	}
+	if config.MaxMessageSize <= 0 {
+		return errors.New("the max message size must be positive")
+	}
+	if config.DisconnectAfter < 0 || config.BanAfter < 0 {
+		return errors.New("the inbound disconnect and ban thresholds can't be negative")
+	}
+	if config.BanAfter > 0 && config.BanDuration <= 0 {
+		return errors.New("the ban duration must be positive")
+	}
+	return nil
+}
+
+var (
+	//ErrRateLimited is sent to the players whose messages are dropped as they send too many
+	ErrRateLimited = NewGameError(ErrCodeRateLimited, "too many messages, they are dropped")
+	//ErrSessionBusy is sent to the players whose messages are dropped as the session gets too many
+	ErrSessionBusy = NewGameError(ErrCodeRateLimited, "the game session gets too many messages, they are dropped")
+	//ErrFlooding is sent to the players disconnected for sending too many messages
+	ErrFlooding = NewGameError(ErrCodeRateLimited, "disconnected for sending too many messages")
+	//ErrBanned is returned to the banned users starting or joining a session
+	ErrBanned = NewGameError(ErrCodeRateLimited, "banned from new game sessions for sending too many messages, try again later")
+)
+
+//inboundStats counts what the players send for the monitoring, it is published by expvar
+var inboundStats = expvar.NewMap("games_inbound")
+
+//what is done of a message received from a player
+const (
+	inboundAllow = iota
+	inboundDrop
+	inboundDisconnect
+)
+
+//SetRateLimits sets how much the players can send, it has to be called before Run
+func (manager *GameManager) SetRateLimits(config RateLimitConfig) {
+	manager.rateLimits = config
+}
+
+//inboundLimiter limits the messages of a connection, it is only used by the goroutine reading it
+type inboundLimiter struct {
+	bucket  tokenBucket
+	dropped int
+	//limited and busy are set while the messages are dropped, the player is warned once each time it starts
+	limited bool
+	busy    bool
+}
+
+//sessionLimiter limits the messages of all the players of a session
+type sessionLimiter struct {
+	mu     sync.Mutex
+	bucket tokenBucket
+}
+
+func (limiter *sessionLimiter) allow(config RateLimitConfig, now time.Time) bool {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	return limiter.bucket.allow(config.SessionRate, config.SessionBurst, now)
+}
+
+//checkInbound tells what to do of a message received from the player
+func (player *Player) checkInbound(limiter *inboundLimiter, now time.Time) int {
+	manager := player.GameSession.gameManager
+	config := manager.rateLimits
+	inboundStats.Add("received", 1)
+
+	if !limiter.bucket.allow(config.PlayerRate, config.PlayerBurst, now) {
+		limiter.dropped++
+		inboundStats.Add("dropped", 1)
+		if config.DisconnectAfter > 0 && limiter.dropped >= config.DisconnectAfter {
+			log.Printf("disconnecting %s from %s, too many messages were sent", player.Email, player.GameSession.ID)
+			inboundStats.Add("disconnected", 1)
+			player.sendError(ErrFlooding, ErrCodeRateLimited, nil)
+			manager.strike(player.Email, now)
+			return inboundDisconnect
+		}
+		if !limiter.limited {
+			limiter.limited = true
+			inboundStats.Add("warned", 1)
+			player.sendError(ErrRateLimited, ErrCodeRateLimited, nil)
+		}
+		return inboundDrop
+	}
+	limiter.limited = false
+
+	//the messages of the spectators are dropped anyway, they don't take from what the players can send
+	if player.Spectator {
+		return inboundAllow
+	}
+	if !player.GameSession.inbound.allow(config, now) {
+		inboundStats.Add("dropped", 1)
+		if !limiter.busy {
+			limiter.busy = true
+			player.sendError(ErrSessionBusy, ErrCodeRateLimited, nil)
+		}
+		return inboundDrop
+	}
+	limiter.busy = false
+	return inboundAllow
+}
+
+//BanStore keeps the disconnections of the players and who is banned, it is shared by the servers
+//running the sessions so a ban holds on all of them
+type BanStore interface {
+	//Strike records a disconnection of the user at now and returns how many it had since then
+	Strike(email string, now time.Time, since time.Time) (int, error)
+	//Ban keeps the user out of new sessions until the time and forgets its disconnections
+	Ban(email string, until time.Time) error
+	//BannedUntil returns when the ban of the user ends, the zero time when it isn't banned at now
+	BannedUntil(email string, now time.Time) (time.Time, error)
+}
+
+//SetBanStore sets where the bans are kept, it has to be called before Run
+func (manager *GameManager) SetBanStore(store BanStore) {
+	manager.bans = store
+}
+
+//strike records that the user was disconnected for abusing the server, it is banned once it had too many strikes
+func (manager *GameManager) strike(email string, now time.Time) {
+	config := manager.rateLimits
+	if config.BanAfter == 0 {
+		return
+	}
+	strikes, err := manager.bans.Strike(email, now, now.Add(-config.BanDuration))
+	if err != nil {
+		log.Printf("couldn't record the disconnection of %s: %v", email, err)
+		return
+	}
+	if strikes < config.BanAfter {
+		return
+	}
+	until := now.Add(config.BanDuration)
+	if err := manager.bans.Ban(email, until); err != nil {
+		log.Printf("couldn't ban %s: %v", email, err)
+		return
+	}
+	inboundStats.Add("banned", 1)
+	log.Printf("%s is banned from new sessions until %s", email, until.Format(time.RFC3339))
+}
+
+//CheckBan returns ErrBanned when the user can't start or join new sessions
+func (manager *GameManager) CheckBan(email string) error {
+	until, err := manager.bans.BannedUntil(email, time.Now())
+	if err != nil {
+		//the players are not kept out because the store is unavailable
+		log.Printf("couldn't check the ban of %s: %v", email, err)
+		return nil
+	}
+	if until.IsZero() {
+		return nil
+	}
+	return ErrBanned
+}
+
+//MemoryBanStore keeps the bans in memory, it is meant for tests and single server runs
+type MemoryBanStore struct {
+	mu      sync.Mutex
+	strikes map[string][]time.Time
+	banned  map[string]time.Time
+}
+
+//NewMemoryBanStore type
+func NewMemoryBanStore() *MemoryBanStore {
+	return &MemoryBanStore{
+		strikes: make(map[string][]time.Time),
+		banned:  make(map[string]time.Time),
+	}
+}
+
+func (store *MemoryBanStore) Strike(email string, now time.Time, since time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	recent := store.strikes[email][:0]
+	for _, at := range store.strikes[email] {
+		if at.After(since) {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	store.strikes[email] = recent
+	return len(recent), nil
+}
+
+func (store *MemoryBanStore) Ban(email string, until time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.strikes, email)
+	store.banned[email] = until
+	return nil
+}
+
+func (store *MemoryBanStore) BannedUntil(email string, now time.Time) (time.Time, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	until, ok := store.banned[email]
+	if !ok {
+		return time.Time{}, nil
+	}
+	if !now.Before(until) {
+		delete(store.banned, email)
+		return time.Time{}, nil
+	}
+	return until, nil
+}
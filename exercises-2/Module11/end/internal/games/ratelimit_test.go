@@ -0,0 +1,130 @@
+package games
+
+import (
+	"testing"
+	"time"
+)
+
+//rateLimitedManager is a manager whose players can send a burst of two messages then one per second
+func rateLimitedManager() *GameManager {
+	manager := newTestGameManager(nil)
+	manager.rateLimits = RateLimitConfig{
+		PlayerRate:      1,
+		PlayerBurst:     2,
+		SessionRate:     1,
+		SessionBurst:    3,
+		MaxMessageSize:  1024,
+		DisconnectAfter: 3,
+		BanAfter:        2,
+		BanDuration:     time.Minute,
+	}
+	return manager
+}
+
+func TestPlayer_checkInbound(t *testing.T) {
+	manager := rateLimitedManager()
+	session := manager.newGameSession(counterGame, "session")
+	dave := session.CreateNewPlayer(nil, 1, "dave", "dave123@gmail.com")
+	dan := session.CreateNewPlayer(nil, 2, "dan", "dan@gmail.com")
+	now := time.Now()
+
+	daveLimiter, danLimiter := &inboundLimiter{}, &inboundLimiter{}
+	want := []int{inboundAllow, inboundAllow, inboundDrop, inboundDrop, inboundDisconnect}
+	for i, verdict := range want {
+		if got := dave.checkInbound(daveLimiter, now); got != verdict {
+			t.Fatalf("message %d of dave = %d, want %d", i, got, verdict)
+		}
+	}
+	//a spectator is only limited on its own
+	spectator := session.CreateNewPlayer(nil, 3, "bob", "bob@gmail.com")
+	spectator.Spectator = true
+	spectatorLimiter := &inboundLimiter{}
+	for i := 0; i < 2; i++ {
+		if got := spectator.checkInbound(spectatorLimiter, now); got != inboundAllow {
+			t.Fatalf("message %d of the spectator = %d, want %d", i, got, inboundAllow)
+		}
+	}
+	//dave used two messages of the session, dan is only let through once
+	if got := dan.checkInbound(danLimiter, now); got != inboundAllow {
+		t.Errorf("first message of dan = %d, want %d", got, inboundAllow)
+	}
+	if got := dan.checkInbound(danLimiter, now); got != inboundDrop {
+		t.Errorf("message of dan past the session burst = %d, want %d", got, inboundDrop)
+	}
+
+	//dave is banned the second time it is disconnected
+	if err := manager.CheckBan(dave.Email); err != nil {
+		t.Fatalf("CheckBan() after one disconnection = %v", err)
+	}
+	daveLimiter = &inboundLimiter{}
+	for i := 0; i < 5; i++ {
+		dave.checkInbound(daveLimiter, now)
+	}
+	if err := manager.CheckBan(dave.Email); err != ErrBanned {
+		t.Errorf("CheckBan() after two disconnections = %v, want %v", err, ErrBanned)
+	}
+	if err := manager.CheckBan(dan.Email); err != nil {
+		t.Errorf("CheckBan() of dan = %v", err)
+	}
+}
+
+func TestPlayer_flooding(t *testing.T) {
+	tests := []struct {
+		name  string
+		flood []byte
+		//firstError is what the player is told about first
+		firstError ErrorCode
+	}{
+		{name: "messages", flood: []byte(`{"action":"CHAT_MESSAGE","data":"{\"text\":\"hi\"}"}`), firstError: ErrCodeRateLimited},
+		//the frames that can't be decoded count as much as the others
+		{name: "malformed frames", flood: []byte(`not a message`), firstError: ErrCodeInvalidMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := rateLimitedManager()
+			session := manager.CreateNewGameSession(counterGame)
+			go session.Run()
+
+			conn := newFakeConn(false)
+			session.CreateNewPlayer(nil, 1, "dave", "dave123@gmail.com").Start(conn)
+
+			for i := 0; i < 5; i++ {
+				select {
+				case conn.incoming <- tt.flood:
+				case <-time.After(2 * time.Second):
+					t.Fatalf("message %d was not read", i)
+				}
+			}
+			expectRejected(t, conn, tt.firstError)
+			select {
+			case <-conn.closed:
+			case <-time.After(2 * time.Second):
+				t.Fatal("the flooding player was not disconnected")
+			}
+		})
+	}
+}
+
+func TestGameManager_sharedBans(t *testing.T) {
+	store := NewMemoryBanStore()
+	a, b := rateLimitedManager(), rateLimitedManager()
+	a.SetBanStore(store)
+	b.SetBanStore(store)
+
+	//the disconnections on both servers add up and the ban holds on both
+	now := time.Now()
+	a.strike("dave123@gmail.com", now)
+	b.strike("dave123@gmail.com", now.Add(time.Second))
+	for _, manager := range []*GameManager{a, b} {
+		if err := manager.CheckBan("dave123@gmail.com"); err != ErrBanned {
+			t.Errorf("CheckBan() = %v, want %v", err, ErrBanned)
+		}
+	}
+
+	//the disconnections older than the ban duration are forgotten
+	a.strike("dan@gmail.com", now.Add(-2*time.Minute))
+	b.strike("dan@gmail.com", now)
+	if err := a.CheckBan("dan@gmail.com"); err != nil {
+		t.Errorf("CheckBan() of dan = %v", err)
+	}
+}
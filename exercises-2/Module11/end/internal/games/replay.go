@@ -0,0 +1,300 @@
+package games
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	//ReplayIn is a message a player sent to the session
+	ReplayIn = "in"
+	//ReplayOut is a message the session broadcast to the players
+	ReplayOut = "out"
+)
+
+//ErrNoReplay is returned for the sessions that have nothing recorded
+var ErrNoReplay = errors.New("no replay for this session")
+
+//ReplayEntry is a message recorded in the replay log of a session, the sender is the player of the message
+//and the broadcast messages keep the sequence they were sent with
+type ReplayEntry struct {
+	Index     int       `json:"index"`
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Msg       GameMsg   `json:"msg"`
+}
+
+//ReplayLog is an append-only log of the messages of the sessions
+type ReplayLog interface {
+	Append(sessionID string, entry ReplayEntry) error
+	Read(sessionID string) ([]ReplayEntry, error)
+}
+
+//SetReplayLog sets where the messages of the sessions are recorded, it has to be called before Run
+func (manager *GameManager) SetReplayLog(replays ReplayLog) {
+	manager.replays = replays
+}
+
+//recordReplay appends the message to the replay log of the session
+func (gameSession *GameSession) recordReplay(direction string, msg *GameMsg) {
+	replays := gameSession.gameManager.replays
+	if replays == nil {
+		return
+	}
+	entry := ReplayEntry{
+		Index:     gameSession.replayed,
+		Time:      time.Now(),
+		Direction: direction,
+		Msg:       *msg,
+	}
+	gameSession.replayed++
+	if err := replays.Append(gameSession.ID, entry); err != nil {
+		log.Printf("couldn't record the replay of session %s: %v", gameSession.ID, err)
+	}
+}
+
+//ReplayMember tells if the user took part in the session of the replay, it sent or was the subject of one
+//of its messages. The replays are only read by the members of their session
+func ReplayMember(entries []ReplayEntry, email string) bool {
+	if email == "" {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.Msg.Player.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+//flushReplay writes what the replay log buffered for the session
+func (gameSession *GameSession) flushReplay() {
+	flusher, ok := gameSession.gameManager.replays.(ReplayFlusher)
+	if !ok {
+		return
+	}
+	if err := flusher.Flush(gameSession.ID); err != nil {
+		log.Printf("couldn't write the replay of session %s: %v", gameSession.ID, err)
+	}
+}
+
+//MemoryReplayLog keeps the replays in memory, it is meant for tests and local runs
+type MemoryReplayLog struct {
+	mu      sync.Mutex
+	entries map[string][]ReplayEntry
+}
+
+//NewMemoryReplayLog type
+func NewMemoryReplayLog() *MemoryReplayLog {
+	return &MemoryReplayLog{entries: make(map[string][]ReplayEntry)}
+}
+
+func (replays *MemoryReplayLog) Append(sessionID string, entry ReplayEntry) error {
+	replays.mu.Lock()
+	defer replays.mu.Unlock()
+
+	replays.entries[sessionID] = append(replays.entries[sessionID], entry)
+	return nil
+}
+
+func (replays *MemoryReplayLog) Read(sessionID string) ([]ReplayEntry, error) {
+	replays.mu.Lock()
+	defer replays.mu.Unlock()
+
+	entries, ok := replays.entries[sessionID]
+	if !ok {
+		return nil, ErrNoReplay
+	}
+	return append([]ReplayEntry(nil), entries...), nil
+}
+
+//replayBufferSize is how many bytes of a session the FileReplayLog holds before writing them
+const replayBufferSize = 32 * 1024
+
+//FileReplayLog writes the replay of each session as a file of json lines in a directory. The lines of a
+//session are buffered and written once the buffer is full, when the replay is read and when the session
+//stops, so a crash loses the last ones
+type FileReplayLog struct {
+	Dir string
+	mu  sync.Mutex
+	//buffered holds the lines of each session waiting to be written
+	buffered map[string][]byte
+	//writing keeps the lines of a session in order when they are written
+	writing sync.Mutex
+}
+
+//ReplayFlusher is implemented by the replay logs buffering the entries, a session flushes its replay when it stops
+type ReplayFlusher interface {
+	Flush(sessionID string) error
+}
+
+//NewFileReplayLog creates the directory of the replays if needed
+func NewFileReplayLog(dir string) (*FileReplayLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileReplayLog{Dir: dir, buffered: make(map[string][]byte)}, nil
+}
+
+func (replays *FileReplayLog) path(sessionID string) (string, error) {
+	if sessionID == "" || filepath.Base(sessionID) != sessionID || sessionID == ".." {
+		return "", ErrNoReplay
+	}
+	return filepath.Join(replays.Dir, sessionID+".jsonl"), nil
+}
+
+func (replays *FileReplayLog) Append(sessionID string, entry ReplayEntry) error {
+	if _, err := replays.path(sessionID); err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	replays.mu.Lock()
+	replays.buffered[sessionID] = append(append(replays.buffered[sessionID], line...), '\n')
+	full := len(replays.buffered[sessionID]) >= replayBufferSize
+	replays.mu.Unlock()
+
+	if full {
+		return replays.Flush(sessionID)
+	}
+	return nil
+}
+
+//Flush writes the buffered lines of the session to its file
+func (replays *FileReplayLog) Flush(sessionID string) error {
+	path, err := replays.path(sessionID)
+	if err != nil {
+		return err
+	}
+	replays.writing.Lock()
+	defer replays.writing.Unlock()
+
+	replays.mu.Lock()
+	lines := replays.buffered[sessionID]
+	delete(replays.buffered, sessionID)
+	replays.mu.Unlock()
+	if len(lines) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(lines); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (replays *FileReplayLog) Read(sessionID string) ([]ReplayEntry, error) {
+	path, err := replays.path(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := replays.Flush(sessionID); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoReplay
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []ReplayEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry ReplayEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			//a line cut short by a crash ends the replay
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+//PlaybackControl is sent by the client of a playback to change its speed or jump in time
+type PlaybackControl struct {
+	Speed float64 `json:"speed,omitempty"`
+	//Seek jumps to the messages recorded that many milliseconds after the first one
+	Seek *int64 `json:"seek,omitempty"`
+}
+
+//Playback sends the entries with the delays they were recorded with, divided by the speed,
+//until all were sent, sending fails or the controls channel is closed
+func Playback(entries []ReplayEntry, speed float64, controls <-chan PlaybackControl, send func(ReplayEntry) error) error {
+	if speed <= 0 {
+		speed = 1
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	start := entries[0].Time
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	//at is the position in the recording the playback had reached at the time resumed,
+	//next is the entry to send
+	at, resumed := start, time.Now()
+	next := 0
+	for next < len(entries) {
+		select {
+		case <-timer.C:
+			at, resumed = entries[next].Time, time.Now()
+			if err := send(entries[next]); err != nil {
+				return err
+			}
+			next++
+			if next < len(entries) {
+				timer.Reset(time.Duration(float64(entries[next].Time.Sub(at)) / speed))
+			}
+
+		case control, ok := <-controls:
+			if !ok {
+				return nil
+			}
+			now := time.Now()
+			at = at.Add(time.Duration(float64(now.Sub(resumed)) * speed))
+			resumed = now
+			if control.Speed > 0 {
+				speed = control.Speed
+			}
+			if control.Seek != nil {
+				at = start.Add(time.Duration(*control.Seek) * time.Millisecond)
+				next = 0
+				for next < len(entries) && entries[next].Time.Before(at) {
+					next++
+				}
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			if next < len(entries) {
+				wait := time.Duration(float64(entries[next].Time.Sub(at)) / speed)
+				if wait < 0 {
+					wait = 0
+				}
+				timer.Reset(wait)
+			}
+		}
+	}
+	return nil
+}
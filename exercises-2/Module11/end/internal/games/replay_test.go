@@ -0,0 +1,123 @@
+package games
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGameSession_recordReplay(t *testing.T) {
+	replays := NewMemoryReplayLog()
+	manager := newGameManager()
+	manager.SetReplayLog(replays)
+	session := manager.newGameSession(Game{ID: "chess"}, "session")
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	move, _ := WrapCommand(GAME_PLAY, "e4", dave)
+	session.recordReplay(ReplayIn, &move)
+	session.sendMsgToPlayers(&move)
+
+	entries, err := replays.Read(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("recorded %d entries, want 2", len(entries))
+	}
+	if entries[0].Direction != ReplayIn || entries[0].Msg.Player != dave || entries[0].Msg.Seq != 0 {
+		t.Errorf("first entry = %+v, want the move sent by dave", entries[0])
+	}
+	if entries[1].Direction != ReplayOut || entries[1].Index != 1 || entries[1].Msg.Seq != 1 {
+		t.Errorf("second entry = %+v, want the broadcast with its sequence", entries[1])
+	}
+}
+
+func TestReplayMember(t *testing.T) {
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	connected, _ := WrapCommand(ON_USER_CONNECTED, dave, dave)
+	state, _ := WrapCommand(ON_GAME_STATE_CHANGED, "state", Player{})
+	entries := []ReplayEntry{{Index: 0, Direction: ReplayOut, Msg: connected}, {Index: 1, Direction: ReplayOut, Msg: state}}
+
+	if !ReplayMember(entries, dave.Email) {
+		t.Error("dave who joined the session can't read its replay")
+	}
+	for _, email := range []string{"dan@gmail.com", ""} {
+		if ReplayMember(entries, email) {
+			t.Errorf("%q who wasn't in the session can read its replay", email)
+		}
+	}
+}
+
+func TestFileReplayLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replays")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	replays, err := NewFileReplayLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := replays.Read("session"); err != ErrNoReplay {
+		t.Errorf("Read() of a session without replay = %v, want %v", err, ErrNoReplay)
+	}
+	for i := 0; i < 3; i++ {
+		msg, _ := WrapCommand(GAME_PLAY, "move", Player{})
+		if err := replays.Append("session", ReplayEntry{Index: i, Time: time.Now(), Direction: ReplayIn, Msg: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//the entries are buffered until they are read or flushed
+	other, _ := NewFileReplayLog(dir)
+	if _, err := other.Read("session"); err != ErrNoReplay {
+		t.Errorf("Read() of the buffered entries from another log = %v, want %v", err, ErrNoReplay)
+	}
+	entries, err := replays.Read("session")
+	if err != nil || len(entries) != 3 || entries[2].Index != 2 {
+		t.Errorf("Read() = %+v, %v", entries, err)
+	}
+	if entries, err := other.Read("session"); err != nil || len(entries) != 3 {
+		t.Errorf("Read() from another log once written = %d entries, %v", len(entries), err)
+	}
+	if err := replays.Append("../session", ReplayEntry{}); err == nil {
+		t.Error("Append() wrote outside the replay directory")
+	}
+}
+
+func TestPlayback(t *testing.T) {
+	start := time.Now()
+	var entries []ReplayEntry
+	for i := 0; i < 5; i++ {
+		entries = append(entries, ReplayEntry{Index: i, Time: start.Add(time.Duration(i) * 20 * time.Millisecond)})
+	}
+
+	play := func(speed float64, controls chan PlaybackControl) ([]int, time.Duration) {
+		var sent []int
+		began := time.Now()
+		Playback(entries, speed, controls, func(entry ReplayEntry) error {
+			sent = append(sent, entry.Index)
+			return nil
+		})
+		return sent, time.Since(began)
+	}
+
+	sent, took := play(1, nil)
+	if len(sent) != 5 || took < 80*time.Millisecond {
+		t.Errorf("playback at 1x sent %v in %v, want all in at least 80ms", sent, took)
+	}
+	sent, took = play(4, nil)
+	if len(sent) != 5 || took >= 80*time.Millisecond {
+		t.Errorf("playback at 4x sent %v in %v, want all in less than 80ms", sent, took)
+	}
+
+	//seeking past the middle skips the first messages
+	controls := make(chan PlaybackControl, 1)
+	seek := int64(50)
+	controls <- PlaybackControl{Seek: &seek}
+	sent, _ = play(0.1, controls)
+	if len(sent) == 0 || sent[len(sent)-1] != 4 || len(sent) > 3 {
+		t.Errorf("playback after seek sent %v, want the messages after 50ms", sent)
+	}
+}
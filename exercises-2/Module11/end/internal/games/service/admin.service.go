@@ -0,0 +1,189 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	clusterService "github.com/someuser/gameserver/internal/cluster/service"
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//sessionSummary is an active session as listed to the operators, with the node running it
+type sessionSummary struct {
+	games.SessionInfo
+	AgeSeconds int64  `json:"ageSeconds"`
+	Node       string `json:"node"`
+}
+
+//adminRequest is the body of the operator actions on a session
+type adminRequest struct {
+	Email   string `json:"email"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": false, "message": message})
+}
+
+//nodeClient asks the other nodes for their sessions
+var nodeClient = &http.Client{Timeout: 5 * time.Second}
+
+//adminSession finds the session of the request and decodes the body of the action if there is one,
+//the requests for the sessions of the other nodes are forwarded to them
+func adminSession(w http.ResponseWriter, r *http.Request, body *adminRequest) *games.GameSession {
+	params := mux.Vars(r)
+	if forwardToOwner(w, r, params["gametoken"]) {
+		return nil
+	}
+	gameSession := gameManager.GetSessionByID(params["gametoken"])
+	if gameSession == nil {
+		writeAdminError(w, http.StatusNotFound, games.ErrSessionEnded.Error())
+		return nil
+	}
+	if body != nil {
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid request")
+			return nil
+		}
+	}
+	return gameSession
+}
+
+//ListActiveSessions returns the sessions running on the live nodes with their players and how long they have been running,
+//a request forwarded by another node gets the sessions of this node only
+func ListActiveSessions(w http.ResponseWriter, r *http.Request) {
+
+	cs := clusterService.Get()
+	now := time.Now()
+	summaries := []sessionSummary{}
+	for _, gameSession := range gameManager.GetActiveSessions() {
+		info, err := gameSession.Info()
+		if err != nil {
+			continue
+		}
+		summaries = append(summaries, sessionSummary{
+			SessionInfo: info,
+			AgeSeconds:  int64(now.Sub(info.CreatedAt).Seconds()),
+			Node:        cs.Self.ID,
+		})
+	}
+	if r.Header.Get(forwardedHeader) == "" {
+		nodes, err := cs.Directory.Nodes()
+		if err != nil {
+			log.Print("error occued listing the cluster nodes ", err.Error())
+		}
+		for _, node := range nodes {
+			if node.ID == cs.Self.ID {
+				continue
+			}
+			sessions, err := nodeSessions(r, node.Addr)
+			if err != nil {
+				log.Printf("couldn't list the sessions of node %s: %v", node.ID, err)
+				continue
+			}
+			summaries = append(summaries, sessions...)
+		}
+	}
+	var resp = map[string]interface{}{"status": true, "message": summaries}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//nodeSessions asks the node at addr for the sessions it runs with the credentials of the request
+func nodeSessions(r *http.Request, addr string) ([]sessionSummary, error) {
+	req, err := http.NewRequest(http.MethodGet, addr+r.URL.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header.Clone()
+	req.Header.Set(forwardedHeader, clusterService.Get().Self.ID)
+	resp, err := nodeClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var body struct {
+		Message []sessionSummary `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Message, nil
+}
+
+//GetActiveSession returns the detail of a running session including its game data
+func GetActiveSession(w http.ResponseWriter, r *http.Request) {
+
+	gameSession := adminSession(w, r, nil)
+	if gameSession == nil {
+		return
+	}
+	detail, err := gameSession.Detail()
+	if err != nil {
+		writeAdminError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": detail}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//KickPlayer removes the player with the email of the body from the session
+func KickPlayer(w http.ResponseWriter, r *http.Request) {
+
+	var body adminRequest
+	gameSession := adminSession(w, r, &body)
+	if gameSession == nil {
+		return
+	}
+	if err := gameSession.Kick(body.Email, body.Reason); err != nil {
+		writeAdminError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": true, "message": "player kicked"})
+}
+
+//BroadcastSystemMessage sends the message of the body to everyone in the session
+func BroadcastSystemMessage(w http.ResponseWriter, r *http.Request) {
+
+	var body adminRequest
+	gameSession := adminSession(w, r, &body)
+	if gameSession == nil {
+		return
+	}
+	if body.Message == "" {
+		writeAdminError(w, http.StatusBadRequest, "message is missing")
+		return
+	}
+	if err := gameSession.Broadcast(body.Message); err != nil {
+		writeAdminError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": true, "message": "message sent"})
+}
+
+//EndSession ends the session, the players get ON_GAME_OVER with the reason of the body
+func EndSession(w http.ResponseWriter, r *http.Request) {
+
+	var body adminRequest
+	gameSession := adminSession(w, r, &body)
+	if gameSession == nil {
+		return
+	}
+	if body.Reason == "" {
+		body.Reason = "the game was ended by an operator"
+	}
+	if err := gameSession.End(body.Reason); err != nil {
+		writeAdminError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": true, "message": "session ended"})
+}
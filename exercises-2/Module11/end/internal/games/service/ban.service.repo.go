@@ -0,0 +1,55 @@
+package service
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+	database "github.com/someuser/gameserver/internal/users/db"
+)
+
+type BansDB struct {
+	*sql.DB
+}
+
+func GetBansDataStore() games.BanStore {
+	return &BansDB{database.Get()}
+}
+
+//Strike records the disconnection and counts the ones of the user since then, the older ones are forgotten
+func (db *BansDB) Strike(email string, now time.Time, since time.Time) (int, error) {
+
+	_, err := db.Exec("insert into player_strikes(email,struck_at)values(?,?)", email, now)
+	if err != nil {
+		return 0, err
+	}
+	_, err = db.Exec("delete from player_strikes where email = ? and struck_at <= ?", email, since)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = db.QueryRow("select count(*) from player_strikes where email = ?", email).Scan(&count)
+	return count, err
+}
+
+func (db *BansDB) Ban(email string, until time.Time) error {
+
+	_, err := db.Exec(`insert into player_bans(email,banned_until)values(?,?)
+						on duplicate key update banned_until = values(banned_until)`, email, until)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("delete from player_strikes where email = ?", email)
+	return err
+}
+
+func (db *BansDB) BannedUntil(email string, now time.Time) (time.Time, error) {
+
+	var until time.Time
+	err := db.QueryRow("select banned_until from player_bans where email = ? and banned_until > ?", email, now).Scan(&until)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return until, err
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/someuser/gameserver/internal/cluster"
+	clusterService "github.com/someuser/gameserver/internal/cluster/service"
+	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/invitations"
+)
+
+const (
+	//forwardedHeader marks the requests a node forwarded to the owner of their session, they are never forwarded again
+	forwardedHeader = "X-Gameserver-Forwarded-By"
+
+	invitationsTopic = "invitations"
+	declinesTopic    = "sessions.declined"
+)
+
+//declined is published when an invited user declines, the node running the session removes them
+type declined struct {
+	SessionID string `json:"sessionId"`
+	Email     string `json:"email"`
+}
+
+//publishedInvitations delivers the invitations through the pubsub to the node the invited user is connected to
+type publishedInvitations struct {
+	pubsub cluster.PubSub
+}
+
+func (channel publishedInvitations) Deliver(invitation invitations.Invitation) error {
+	data, err := json.Marshal(invitation)
+	if err != nil {
+		return err
+	}
+	return channel.pubsub.Publish(invitationsTopic, data)
+}
+
+//joinCluster makes the game manager claim its sessions in the directory and listens to the other nodes
+func joinCluster(cs *clusterService.ClusterService) error {
+	gameManager.SetOwnership(cs.Membership())
+
+	_, err := cs.PubSub.Subscribe(invitationsTopic, func(data []byte) {
+		var invitation invitations.Invitation
+		if err := json.Unmarshal(data, &invitation); err != nil {
+			return
+		}
+		invitationHub.Deliver(invitation)
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cs.PubSub.Subscribe(declinesTopic, func(data []byte) {
+		var msg declined
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		if gameSession := gameManager.GetSessionByID(msg.SessionID); gameSession != nil {
+			go gameSession.DeclineInvitation(msg.Email)
+		}
+	})
+	return err
+}
+
+//publishDeclined lets the node running the session know the user declined
+func publishDeclined(sessionID string, email string) {
+	data, _ := json.Marshal(declined{SessionID: sessionID, Email: email})
+	if err := clusterService.Get().PubSub.Publish(declinesTopic, data); err != nil {
+		log.Print("error occued during decline publish ", err.Error())
+	}
+}
+
+//findSession returns the session when this node runs it, a session no live node runs is adopted.
+//When another node runs it the request is forwarded or redirected there and forwarded is true
+func findSession(w http.ResponseWriter, r *http.Request, sessionID string) (gameSession *games.GameSession, forwarded bool) {
+	if gameSession = gameManager.GetSessionByID(sessionID); gameSession != nil {
+		return gameSession, false
+	}
+	if forwardToOwner(w, r, sessionID) {
+		return nil, true
+	}
+	return gameManager.AdoptSession(sessionID), false
+}
+
+//forwardToOwner proxies, or redirects with CLUSTER_JOIN_MODE=redirect, the request to the node running the session,
+//it returns false when no other live node runs it
+func forwardToOwner(w http.ResponseWriter, r *http.Request, sessionID string) bool {
+	cs := clusterService.Get()
+	if r.Header.Get(forwardedHeader) != "" {
+		return false
+	}
+	owner, err := cs.Directory.Owner(sessionID)
+	if err != nil || owner.ID == cs.Self.ID {
+		return false
+	}
+	target, err := url.Parse(owner.Addr)
+	if err != nil {
+		log.Printf("invalid address %s of node %s: %v", owner.Addr, owner.ID, err)
+		return false
+	}
+
+	if cs.Redirect {
+		redirect := *r.URL
+		redirect.Scheme, redirect.Host = target.Scheme, target.Host
+		http.Redirect(w, r, redirect.String(), http.StatusTemporaryRedirect)
+		return true
+	}
+	r.Header.Set(forwardedHeader, cs.Self.ID)
+	httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+	return true
+}
+
+//sessionRunning tells if a node of the cluster runs the session
+func sessionRunning(sessionID string) bool {
+	if gameManager.GetSessionByID(sessionID) != nil {
+		return true
+	}
+	if _, err := clusterService.Get().Directory.Owner(sessionID); err == nil {
+		return true
+	}
+	return gameManager.AdoptSession(sessionID) != nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//requestError is a request that failed before its websocket was opened, it is answered with the
+//status and a json body holding the error code
+type requestError struct {
+	status  int
+	code    games.ErrorCode
+	message string
+}
+
+func (err *requestError) Error() string {
+	return err.message
+}
+
+func newRequestError(status int, code games.ErrorCode, message string) *requestError {
+	return &requestError{status: status, code: code, message: message}
+}
+
+var (
+	errUnauthorized    = newRequestError(http.StatusUnauthorized, games.ErrCodeUnauthorized, "not a valid user")
+	errSessionNotFound = newRequestError(http.StatusNotFound, games.ErrCodeSessionNotFound, "no such game session exists")
+	errGameIDMissing   = newRequestError(http.StatusBadRequest, games.ErrCodeInvalidRequest, "couldnt find gameid in query")
+)
+
+//upgradeError is a failed websocket upgrade, the upgrader already answered the request
+type upgradeError struct {
+	err error
+}
+
+func (err *upgradeError) Error() string {
+	return err.err.Error()
+}
+
+//writeError answers the request with the status and code of the error, the errors of the websocket
+//upgrade are not written as the upgrader already answered the request and the unexpected ones are
+//answered as internal errors
+func writeError(w http.ResponseWriter, err error) {
+	var reqErr *requestError
+	switch e := err.(type) {
+	case *upgradeError:
+		return
+	case *requestError:
+		reqErr = e
+	case *games.GameError:
+		reqErr = newRequestError(statusOf(e.Code), e.Code, e.Message)
+	default:
+		log.Print("request failed ", err.Error())
+		reqErr = newRequestError(http.StatusInternalServerError, games.ErrCodeInternal, "internal error")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(reqErr.status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": false, "message": reqErr.message, "code": reqErr.code})
+}
+
+//statusOf is the http status of the requests failing with the code
+func statusOf(code games.ErrorCode) int {
+	switch code {
+	case games.ErrCodeUnauthorized:
+		return http.StatusUnauthorized
+	case games.ErrCodeNotInvited, games.ErrCodeNotAllowed, games.ErrCodeWrongPassphrase:
+		return http.StatusForbidden
+	case games.ErrCodeGameNotFound, games.ErrCodeSessionNotFound:
+		return http.StatusNotFound
+	case games.ErrCodeRateLimited:
+		return http.StatusTooManyRequests
+	case games.ErrCodeUnavailable:
+		return http.StatusServiceUnavailable
+	case games.ErrCodeInternal:
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+//checkProtocol rejects the clients asking in the version query for a newer protocol than the one of the server
+func checkProtocol(r *http.Request) error {
+	value := r.URL.Query().Get("version")
+	if value == "" {
+		return nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return newRequestError(http.StatusBadRequest, games.ErrCodeInvalidRequest, "invalid version")
+	}
+	if version < 1 || version > games.ProtocolVersion {
+		return games.ErrUnsupportedVersion
+	}
+	return nil
+}
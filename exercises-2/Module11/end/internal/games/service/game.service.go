@@ -1,21 +1,37 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
 
+	clusterService "github.com/someuser/gameserver/internal/cluster/service"
 	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/games/matchmaking"
+	"github.com/someuser/gameserver/internal/games/wire"
+	"github.com/someuser/gameserver/internal/ratings"
+	ratingsService "github.com/someuser/gameserver/internal/ratings/service"
+	//games with their rules implemented on the server
+	_ "github.com/someuser/gameserver/internal/games/pokemoncards"
 
 	"github.com/someuser/gameserver/internal/users"
+	webhooksService "github.com/someuser/gameserver/internal/webhooks/service"
 )
 
+//upgrader lets the clients pick the wire format with the subprotocol and compress the messages
+//with permessage-deflate when WS_COMPRESSION is set
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	Subprotocols:    wire.Subprotocols(),
 }
 
 var gameManager games.GameManager
@@ -24,23 +40,168 @@ var gameManager games.GameManager
 func init() {
 
 	gameManager = games.CreateGameManager()
+	upgrader.EnableCompression = viper.GetBool("WS_COMPRESSION")
+	gameManager.SetSessionStore(getSessionStore())
+	gameManager.SetResultRecorder(ratings.NewRecorder(ratingsService.Get().DB))
+	inviter = createInviter()
+	gameManager.SetInviter(inviter)
+	if err := joinCluster(clusterService.Get()); err != nil {
+		log.Fatalf("Error joining the cluster, %s", err)
+	}
+	replays = getReplayLog()
+	gameManager.SetReplayLog(replays)
+	gameManager.SetConnConfig(getConnConfig())
+	gameManager.SetRateLimits(getRateLimitConfig())
+	gameManager.SetBanStore(getBanStore())
+	gameManager.SetEventPublisher(webhooksService.Get().Dispatcher)
+	chatConfig, chatFilters := getChatConfig()
+	gameManager.SetChatConfig(chatConfig, chatFilters...)
+	gameManager.SetJoinCodes(getJoinCodeStore(), getJoinCodeTTL())
 	go gameManager.Run()
 
+	matchmaker = matchmaking.CreateMatchmaker(&gameManager, matchmaking.RealClock, matchmaking.DefaultConfig)
+	go matchmaker.Run()
+
+}
+
+//getConnConfig returns how the messages are sent to the players, PLAYER_QUEUE_SIZE, PLAYER_QUEUE_OVERFLOW,
+//PLAYER_WRITE_TIMEOUT and PLAYER_PING_INTERVAL override the defaults
+func getConnConfig() games.ConnConfig {
+	config := games.DefaultConnConfig
+	if size := viper.GetInt("PLAYER_QUEUE_SIZE"); size != 0 {
+		config.QueueSize = size
+	}
+	if overflow := viper.GetString("PLAYER_QUEUE_OVERFLOW"); overflow != "" {
+		config.Overflow = overflow
+	}
+	if timeout := viper.GetDuration("PLAYER_WRITE_TIMEOUT"); timeout != 0 {
+		config.WriteTimeout = timeout
+	}
+	if interval := viper.GetDuration("PLAYER_PING_INTERVAL"); interval != 0 {
+		config.PingInterval = interval
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Error in the player connection config, %s", err)
+	}
+	return config
+}
+
+//getRateLimitConfig returns how much the players can send, INBOUND_PLAYER_RATE, INBOUND_PLAYER_BURST,
+//INBOUND_SESSION_RATE, INBOUND_SESSION_BURST, INBOUND_MAX_MESSAGE_SIZE, INBOUND_DISCONNECT_AFTER,
+//INBOUND_BAN_AFTER and INBOUND_BAN_DURATION override the defaults
+func getRateLimitConfig() games.RateLimitConfig {
+	config := games.DefaultRateLimitConfig
+	if rate := viper.GetFloat64("INBOUND_PLAYER_RATE"); rate != 0 {
+		config.PlayerRate = rate
+	}
+	if burst := viper.GetInt("INBOUND_PLAYER_BURST"); burst != 0 {
+		config.PlayerBurst = burst
+	}
+	if rate := viper.GetFloat64("INBOUND_SESSION_RATE"); rate != 0 {
+		config.SessionRate = rate
+	}
+	if burst := viper.GetInt("INBOUND_SESSION_BURST"); burst != 0 {
+		config.SessionBurst = burst
+	}
+	if size := viper.GetInt64("INBOUND_MAX_MESSAGE_SIZE"); size != 0 {
+		config.MaxMessageSize = size
+	}
+	if viper.IsSet("INBOUND_DISCONNECT_AFTER") {
+		config.DisconnectAfter = viper.GetInt("INBOUND_DISCONNECT_AFTER")
+	}
+	if viper.IsSet("INBOUND_BAN_AFTER") {
+		config.BanAfter = viper.GetInt("INBOUND_BAN_AFTER")
+	}
+	if duration := viper.GetDuration("INBOUND_BAN_DURATION"); duration != 0 {
+		config.BanDuration = duration
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Error in the inbound rate limits, %s", err)
+	}
+	return config
+}
+
+//getChatConfig returns how the players chat, CHAT_SCROLLBACK, CHAT_RATE and CHAT_BURST override the defaults
+//and the messages are checked against CHAT_MAX_LENGTH, CHAT_BLOCK_LINKS and the comma separated CHAT_BLOCKED_WORDS
+func getChatConfig() (games.ChatConfig, []games.ChatFilter) {
+	config := games.DefaultChatConfig
+	if viper.IsSet("CHAT_SCROLLBACK") {
+		config.Scrollback = viper.GetInt("CHAT_SCROLLBACK")
+	}
+	if rate := viper.GetFloat64("CHAT_RATE"); rate != 0 {
+		config.Rate = rate
+	}
+	if burst := viper.GetInt("CHAT_BURST"); burst != 0 {
+		config.Burst = burst
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Error in the chat config, %s", err)
+	}
+
+	var filters []games.ChatFilter
+	if max := viper.GetInt("CHAT_MAX_LENGTH"); max > 0 {
+		filters = append(filters, games.MaxLengthFilter(max))
+	}
+	if viper.GetBool("CHAT_BLOCK_LINKS") {
+		filters = append(filters, games.LinkFilter)
+	}
+	if words := viper.GetString("CHAT_BLOCKED_WORDS"); words != "" {
+		filters = append(filters, games.WordListFilter(strings.Split(words, ",")))
+	}
+	return config, filters
+}
+
+//getSessionStore returns where sessions are persisted, GAME_SESSION_STORE=memory keeps them in the process only
+func getSessionStore() games.SessionStore {
+	if viper.GetString("GAME_SESSION_STORE") == "memory" {
+		return games.NewMemorySessionStore()
+	}
+	return GetSessionsDataStore()
+}
+
+//getJoinCodeStore returns where the join codes are kept, they follow the sessions with GAME_SESSION_STORE=memory
+func getJoinCodeStore() games.JoinCodeStore {
+	if viper.GetString("GAME_SESSION_STORE") == "memory" {
+		return games.NewMemoryJoinCodeStore()
+	}
+	return GetJoinCodesDataStore()
 }
 
+//getBanStore returns where the bans are kept, they follow the sessions with GAME_SESSION_STORE=memory
+func getBanStore() games.BanStore {
+	if viper.GetString("GAME_SESSION_STORE") == "memory" {
+		return games.NewMemoryBanStore()
+	}
+	return GetBansDataStore()
+}
+
+//getJoinCodeTTL returns how long the join codes can be used, JOIN_CODE_TTL overrides the default
+func getJoinCodeTTL() time.Duration {
+	if ttl := viper.GetDuration("JOIN_CODE_TTL"); ttl > 0 {
+		return ttl
+	}
+	return games.DefaultJoinCodeTTL
+}
+
+//openWebSocket upgrades the request once the protocol version the client asked for is checked
 func openWebSocket(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	if err := checkProtocol(r); err != nil {
+		return nil, err
+	}
 
 	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
 	conn, err := upgrader.Upgrade(w, r, nil)
 
 	if err != nil {
-		return nil, err
+		return nil, &upgradeError{err: err}
 	}
 	return conn, nil
 }
 
-//HandleUserJoinedGame type
-func HandleUserJoinedGame(w http.ResponseWriter, r *http.Request, SessionID string) error {
+//HandleUserJoinedGame type, a player reconnecting passes the sequence of the last message it got in lastseq.
+//The users who are not in the session yet pass its passphrase in the passphrase query, and its join code
+//in byCode when it is private
+func HandleUserJoinedGame(w http.ResponseWriter, r *http.Request, SessionID string, byCode bool) error {
 
 	//first lets validate that the user is authenticated
 	var user *users.User
@@ -48,33 +209,77 @@ func HandleUserJoinedGame(w http.ResponseWriter, r *http.Request, SessionID stri
 		if val, ok := m.(*users.User); ok {
 			user = val
 		} else {
-			return errors.New("not a valid user")
+			return errUnauthorized
 		}
 	}
 
+	//the session is looked up before upgrading so the join can be forwarded to the node running it
+	gameSession, forwarded := findSession(w, r, SessionID)
+	if forwarded {
+		return nil
+	}
+	if gameSession == nil {
+		return errSessionNotFound
+	}
+	if err := gameSession.CheckAccess(user.Email, r.URL.Query().Get("passphrase"), byCode); err == games.ErrSessionEnded {
+		return errSessionNotFound
+	} else if err != nil {
+		return err
+	}
+
 	conn, err := openWebSocket(w, r)
 	if err != nil {
 		return err
 	}
 
-	gameSession := gameManager.GetSessionByID(SessionID)
+	player := gameSession.CreateNewPlayer(conn, user.ID, user.Name, user.Email)
 
-	if gameSession == nil {
-		return errors.New("no such game session exists")
+	var lastSeq uint64
+	if keys, ok := r.URL.Query()["lastseq"]; ok {
+		lastSeq, _ = strconv.ParseUint(keys[0], 10, 64)
 	}
 
-	player := gameSession.CreateNewPlayer(conn, user.ID, user.Name, user.Email)
+	player.Join(conn, lastSeq)
+
+	return nil
+
+}
 
-	if player == nil {
-		return errors.New("Invalid user for game")
+//HandleSpectatorJoinedGame connects a user watching the game session, the access to it is checked
+//as for the players joining
+func HandleSpectatorJoinedGame(w http.ResponseWriter, r *http.Request, SessionID string, byCode bool) error {
+
+	var user *users.User
+	if m := r.Context().Value("user"); m != nil {
+		if val, ok := m.(*users.User); ok {
+			user = val
+		} else {
+			return errUnauthorized
+		}
 	}
 
-	player.Start(conn)
+	gameSession, forwarded := findSession(w, r, SessionID)
+	if forwarded {
+		return nil
+	}
+	if gameSession == nil {
+		return errSessionNotFound
+	}
+	if err := gameSession.CheckAccess(user.Email, r.URL.Query().Get("passphrase"), byCode); err == games.ErrSessionEnded {
+		return errSessionNotFound
+	} else if err != nil {
+		return err
+	}
 
-	player.SendCurrentGameStateToPlayer()
+	conn, err := openWebSocket(w, r)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	spectator := gameSession.CreateNewSpectator(conn, user.ID, user.Name, user.Email)
+	spectator.Join(conn, 0)
 
+	return nil
 }
 
 func validatGame(w http.ResponseWriter, r *http.Request) (games.Game, error) {
@@ -84,10 +289,11 @@ func validatGame(w http.ResponseWriter, r *http.Request) (games.Game, error) {
 		return gameManager.GetGame(id)
 	}
 
-	return games.Game{}, errors.New("couldnt find gameid in query")
+	return games.Game{}, errGameIDMissing
 }
 
-//HandleStartGame type
+//HandleStartGame type, the host makes the session private with private=true
+//and asks for a passphrase to join it with the passphrase query
 func HandleStartGame(w http.ResponseWriter, r *http.Request) error {
 	//first lets validate that the user is authenticated
 	var user *users.User
@@ -95,80 +301,179 @@ func HandleStartGame(w http.ResponseWriter, r *http.Request) error {
 		if val, ok := m.(*users.User); ok {
 			user = val
 		} else {
-			return errors.New("not a valid user")
+			return errUnauthorized
 		}
 	}
 
+	if gameManager.Draining() {
+		return games.ErrShuttingDown
+	}
+	if err := gameManager.CheckBan(user.Email); err != nil {
+		return err
+	}
 	g, err := validatGame(w, r)
 	if err != nil {
 		return err
 	}
+	query := r.URL.Query()
+	options := games.SessionOptions{Passphrase: query.Get("passphrase")}
+	if value := query.Get("private"); value != "" {
+		if options.Private, err = strconv.ParseBool(value); err != nil {
+			return newRequestError(http.StatusBadRequest, games.ErrCodeInvalidRequest, "invalid private")
+		}
+	}
+	//the session is created before upgrading so the host is told when it can't be
+	gameSession, err := gameManager.CreateSession(g, options)
+	if err != nil {
+		log.Print("couldn't create the game session ", err.Error())
+		return games.NewGameError(games.ErrCodeInternal, "couldn't create the game session")
+	}
+	go gameSession.Run()
 	conn, err := openWebSocket(w, r)
 	if err != nil {
+		gameSession.End("the host couldn't connect")
 		return err
 	}
 
-	gameSession := gameManager.CreateNewGameSession()
-	go gameSession.Run()
-
 	player := gameSession.CreateNewPlayer(conn, user.ID, user.Name, user.Email)
-	if player == nil {
-		return errors.New("Invalid user for game")
-	}
 
 	player.Start(conn)
 
-	//for simplicity we always returns the same game type,
-	// and do not use the id potentially passed to see if the game is supported
 	var msgPlay = games.OnNewGameSessionCreated{
 		SessionID: gameSession.ID,
 		Game:      g,
+		JoinCode:  gameSession.JoinCode,
+		Private:   gameSession.Private,
 	}
 
 	//send back a message to the host updating him that the game sesion is created
-	// and that he can send invitation to players
+	// and that he can send invitation to players, the players listed in START_GAME get invited
+	//the websocket is already open so the error can't be written as the answer to the request
 	gameMsg, err := games.WrapCommand(games.ON_GAME_SESSION_CREATED, &msgPlay, *player)
 	if err != nil {
-		return err
+		log.Print("couldn't tell the host the game session was created ", err.Error())
+		return nil
 	}
 	player.SendMessage(&gameMsg)
 
 	return nil
-	//SendGameInvitation(gameSession.ID, user.Email, gameSession.Players)
 
 }
 
+//Shutdown stops starting new game sessions and waits for the running ones to end until the context is done,
+//the sessions still running then are saved to be resumed by another server
+func Shutdown(ctx context.Context) error {
+	return gameManager.Shutdown(ctx)
+}
+
 //JoinGame called for joining a user to a game session
 func JoinGame(w http.ResponseWriter, r *http.Request) {
 
 	params := mux.Vars(r)
 	var id = params["gametoken"]
 	if id == "" {
-		w.WriteHeader(http.StatusForbidden)
+		writeError(w, errSessionNotFound)
+		return
+	}
+	if err := HandleUserJoinedGame(w, r, id, false); err != nil {
+		writeError(w, err)
+		return
+	}
+
+}
+
+//SpectateGame called for watching a game session
+func SpectateGame(w http.ResponseWriter, r *http.Request) {
+
+	params := mux.Vars(r)
+	var id = params["gametoken"]
+	if id == "" {
+		writeError(w, errSessionNotFound)
+		return
+	}
+	if err := HandleSpectatorJoinedGame(w, r, id, false); err != nil {
+		writeError(w, err)
+		return
+	}
+}
+
+//JoinGameByCode called for joining a user to the game session of a join code
+func JoinGameByCode(w http.ResponseWriter, r *http.Request) {
+
+	id, err := gameManager.ResolveJoinCode(mux.Vars(r)["code"])
+	if err != nil {
+		writeError(w, err)
 		return
 	}
-	if err := HandleUserJoinedGame(w, r, id); err != nil {
-		w.WriteHeader(http.StatusForbidden)
+	if err := HandleUserJoinedGame(w, r, id, true); err != nil {
+		writeError(w, err)
 		return
 	}
+}
+
+//SpectateGameByCode called for watching the game session of a join code
+func SpectateGameByCode(w http.ResponseWriter, r *http.Request) {
 
+	id, err := gameManager.ResolveJoinCode(mux.Vars(r)["code"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := HandleSpectatorJoinedGame(w, r, id, true); err != nil {
+		writeError(w, err)
+		return
+	}
+}
+
+//GetSessionInfo returns who is playing and watching a game session to the users who can join it
+func GetSessionInfo(w http.ResponseWriter, r *http.Request) {
+
+	params := mux.Vars(r)
+	gameSession, forwarded := findSession(w, r, params["gametoken"])
+	if forwarded {
+		return
+	}
+	if gameSession == nil {
+		writeError(w, errSessionNotFound)
+		return
+	}
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		writeError(w, errUnauthorized)
+		return
+	}
+	//the players of a session are only shown to the users who could join it
+	if err := gameSession.CheckAccess(user.Email, r.URL.Query().Get("passphrase"), false); err == games.ErrSessionEnded {
+		writeError(w, errSessionNotFound)
+		return
+	} else if err != nil {
+		writeError(w, err)
+		return
+	}
+	info, err := gameSession.Info()
+	if err != nil {
+		writeError(w, errSessionNotFound)
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": info}
+	json.NewEncoder(w).Encode(resp)
 }
 
 //StartNewGame called for creating a new game session
 func StartNewGame(w http.ResponseWriter, r *http.Request) {
 	if err := HandleStartGame(w, r); err != nil {
-		w.WriteHeader(http.StatusForbidden)
+		writeError(w, err)
 		return
 	}
 }
 
-//StartNewGame called for creating a new game session
+//GetGameInfo returns the definition of the game passed in the gameid query
 func GetGameInfo(w http.ResponseWriter, r *http.Request) {
 
 	g, err := validatGame(w, r)
 
 	if err != nil {
-		w.WriteHeader(http.StatusForbidden)
+		writeError(w, err)
 		return
 	}
 	var resp = map[string]interface{}{"status": true, "message": g}
@@ -176,3 +481,10 @@ func GetGameInfo(w http.ResponseWriter, r *http.Request) {
 	return
 
 }
+
+//ListGames returns the catalog of the supported games
+func ListGames(w http.ResponseWriter, r *http.Request) {
+
+	var resp = map[string]interface{}{"status": true, "message": gameManager.GetGames()}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+
+	"github.com/someuser/gameserver/internal/games"
+	database "github.com/someuser/gameserver/internal/users/db"
+)
+
+type SessionsDB struct {
+	*sql.DB
+}
+
+func GetSessionsDataStore() games.SessionStore {
+	return &SessionsDB{database.Get()}
+}
+
+//SaveSession inserts the session or updates it if it already exists
+func (db *SessionsDB) SaveSession(record games.SessionRecord) error {
+
+	players, err := json.Marshal(record.Players)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`insert into game_sessions(id,game_id,players,game_data,logic_state,private,passphrase,join_code,host,created_at,updated_at)
+						values(?,?,?,?,?,?,?,?,?,?,?)
+						on duplicate key update players = values(players), game_data = values(game_data),
+						logic_state = values(logic_state), host = values(host), updated_at = values(updated_at)`,
+		record.ID, record.GameID, string(players), record.InitialGameData, record.LogicState,
+		record.Private, record.Passphrase, record.JoinCode, record.Host, record.CreatedAt, record.UpdatedAt)
+
+	return err
+}
+
+func (db *SessionsDB) DeleteSession(id string) error {
+
+	_, err := db.Exec("delete from game_sessions where id = ?", id)
+	if err != nil {
+		log.Print("error occued during session delete ", err.Error())
+		return err
+	}
+	return nil
+}
+
+func (db *SessionsDB) GetSession(id string) (games.SessionRecord, error) {
+	var record games.SessionRecord
+	var players string
+
+	row := db.QueryRow(`select id,game_id,players,game_data,logic_state,private,passphrase,join_code,host,created_at,updated_at
+						from game_sessions where id = ?`, id)
+	err := row.Scan(&record.ID, &record.GameID, &players, &record.InitialGameData, &record.LogicState,
+		&record.Private, &record.Passphrase, &record.JoinCode, &record.Host, &record.CreatedAt, &record.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return games.SessionRecord{}, games.ErrNoSessionRecord
+	}
+	if err != nil {
+		return games.SessionRecord{}, err
+	}
+	if err := json.Unmarshal([]byte(players), &record.Players); err != nil {
+		log.Printf("invalid players for session %s: %v", record.ID, err)
+	}
+	return record, nil
+}
+
+func (db *SessionsDB) GetSessions() ([]games.SessionRecord, error) {
+	var records []games.SessionRecord
+
+	rows, err := db.Query(`select id,game_id,players,game_data,logic_state,private,passphrase,join_code,host,created_at,updated_at
+						from game_sessions`)
+	if err != nil {
+		log.Print("error occued during sessions fetch ", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record games.SessionRecord
+		var players string
+		if err := rows.Scan(&record.ID, &record.GameID, &players, &record.InitialGameData, &record.LogicState,
+			&record.Private, &record.Passphrase, &record.JoinCode, &record.Host, &record.CreatedAt, &record.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(players), &record.Players); err != nil {
+			log.Printf("invalid players for session %s: %v", record.ID, err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
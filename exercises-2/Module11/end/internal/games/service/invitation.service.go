@@ -0,0 +1,153 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+
+	clusterService "github.com/someuser/gameserver/internal/cluster/service"
+	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/invitations"
+	"github.com/someuser/gameserver/internal/users"
+)
+
+var (
+	inviter *invitations.Inviter
+	//invitationHub notifies the users listening on /games/invitations/live
+	invitationHub = invitations.NewHub()
+)
+
+//createInviter delivers the invitations in app and, when EMAIL_OUTBOX_DIR is set, as emails written to that directory
+func createInviter() *invitations.Inviter {
+	var store invitations.InvitationStore
+	if viper.GetString("GAME_SESSION_STORE") == "memory" {
+		store = invitations.NewMemoryInvitationStore()
+	} else {
+		store = GetInvitationsDataStore()
+	}
+
+	//the invitations reach the hub of every node through the pubsub
+	channels := []invitations.Channel{publishedInvitations{clusterService.Get().PubSub}}
+	if dir := viper.GetString("EMAIL_OUTBOX_DIR"); dir != "" {
+		channels = append(channels, invitations.EmailChannel{
+			Sender:  invitations.FileEmailSender{Dir: dir},
+			BaseURL: viper.GetString("GAME_SERVER_URL"),
+		})
+	}
+	return invitations.NewInviter(store, viper.GetDuration("INVITATION_TTL"), channels...)
+}
+
+func writeInvitationError(w http.ResponseWriter, err error) {
+	switch err {
+	case invitations.ErrNotFound:
+		writeError(w, newRequestError(http.StatusNotFound, games.ErrCodeInvitationNotFound, err.Error()))
+	case invitations.ErrNotInvited:
+		writeError(w, newRequestError(http.StatusForbidden, games.ErrCodeNotInvited, err.Error()))
+	case invitations.ErrNotPending:
+		writeError(w, newRequestError(http.StatusConflict, games.ErrCodeNotAllowed, err.Error()))
+	default:
+		log.Print("error occued during invitation update ", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+//ListInvitations returns the invitations of the user that can still be answered
+func ListInvitations(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	pending, err := inviter.Pending(user.Email)
+	if err != nil {
+		log.Print("error occued during invitations fetch ", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": pending}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//ListenInvitations sends the pending invitations of the user on a websocket and then the new ones as they are sent
+func ListenInvitations(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	conn, err := openWebSocket(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer conn.Close()
+
+	notifier := &wsNotifier{conn: conn}
+	unsubscribe := invitationHub.Subscribe(user.Email, invitations.NotifierFunc(notifier.send))
+	defer unsubscribe()
+
+	pending, _ := inviter.Pending(user.Email)
+	for _, invitation := range pending {
+		msg, _ := games.WrapCommand(games.ON_GAME_INVITATION, invitation, games.Player{})
+		if err := notifier.send(msg); err != nil {
+			return
+		}
+	}
+
+	//nothing is expected from the user, reading only tells when the connection is closed
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+//AcceptInvitation accepts the invitation and returns where to join the game session
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	params := mux.Vars(r)
+	invitation, err := inviter.Accept(params["id"], user.Email)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+	if !sessionRunning(invitation.SessionID) {
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": false, "message": "the game session has ended"})
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": map[string]interface{}{
+		"invitation": invitation,
+		"joinUrl":    "/games/joingame/" + invitation.SessionID,
+	}}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//DeclineInvitation declines the invitation, the players of the session are told the user won't join
+func DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	params := mux.Vars(r)
+	invitation, err := inviter.Decline(params["id"], user.Email)
+	if err != nil {
+		writeInvitationError(w, err)
+		return
+	}
+	publishDeclined(invitation.SessionID, user.Email)
+	var resp = map[string]interface{}{"status": true, "message": invitation}
+	json.NewEncoder(w).Encode(resp)
+}
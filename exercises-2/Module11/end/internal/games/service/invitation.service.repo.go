@@ -0,0 +1,84 @@
+package service
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/someuser/gameserver/internal/invitations"
+	database "github.com/someuser/gameserver/internal/users/db"
+)
+
+type InvitationsDB struct {
+	*sql.DB
+}
+
+func GetInvitationsDataStore() invitations.InvitationStore {
+	return &InvitationsDB{database.Get()}
+}
+
+const invitationColumns = "id,session_id,game_id,game_name,from_email,from_name,to_email,status,created_at,expires_at,responded_at"
+
+func scanInvitation(scanner interface{ Scan(...interface{}) error }) (invitations.Invitation, error) {
+	var invitation invitations.Invitation
+	var respondedAt sql.NullTime
+	err := scanner.Scan(&invitation.ID, &invitation.SessionID, &invitation.GameID, &invitation.GameName,
+		&invitation.FromEmail, &invitation.FromName, &invitation.ToEmail, &invitation.Status,
+		&invitation.CreatedAt, &invitation.ExpiresAt, &respondedAt)
+	invitation.RespondedAt = respondedAt.Time
+	return invitation, err
+}
+
+func (db *InvitationsDB) CreateInvitation(invitation invitations.Invitation) error {
+
+	_, err := db.Exec(`insert into invitations(id,session_id,game_id,game_name,from_email,from_name,to_email,status,created_at,expires_at)
+						values(?,?,?,?,?,?,?,?,?,?)`,
+		invitation.ID, invitation.SessionID, invitation.GameID, invitation.GameName, invitation.FromEmail,
+		invitation.FromName, invitation.ToEmail, invitation.Status, invitation.CreatedAt, invitation.ExpiresAt)
+
+	return err
+}
+
+func (db *InvitationsDB) GetInvitation(id string) (invitations.Invitation, error) {
+
+	invitation, err := scanInvitation(db.QueryRow("select "+invitationColumns+" from invitations where id = ?", id))
+	if err == sql.ErrNoRows {
+		return invitation, invitations.ErrNotFound
+	}
+	return invitation, err
+}
+
+func (db *InvitationsDB) GetInvitationsFor(email string, now time.Time) ([]invitations.Invitation, error) {
+	pending := []invitations.Invitation{}
+
+	rows, err := db.Query("select "+invitationColumns+` from invitations
+						where to_email = ? and status = ? and expires_at >= ? order by created_at desc`,
+		email, invitations.Pending, now)
+	if err != nil {
+		log.Print("error occued during invitations fetch ", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, invitation)
+	}
+	return pending, rows.Err()
+}
+
+func (db *InvitationsDB) UpdateStatus(id string, status invitations.Status, at time.Time) error {
+
+	result, err := db.Exec("update invitations set status = ?, responded_at = ? where id = ?", status, at, id)
+	if err != nil {
+		log.Print("error occued during invitation update ", err.Error())
+		return err
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return invitations.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+	database "github.com/someuser/gameserver/internal/users/db"
+)
+
+type JoinCodesDB struct {
+	*sql.DB
+}
+
+func GetJoinCodesDataStore() games.JoinCodeStore {
+	return &JoinCodesDB{database.Get()}
+}
+
+//Reserve maps the code to the session unless another session has it and it hasn't expired
+func (db *JoinCodesDB) Reserve(code string, sessionID string, expires time.Time) error {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var expiresAt time.Time
+	err = tx.QueryRow("select expires_at from join_codes where code = ? for update", code).Scan(&expiresAt)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && time.Now().Before(expiresAt) {
+		return games.ErrJoinCodeTaken
+	}
+
+	_, err = tx.Exec(`insert into join_codes(code,session_id,expires_at)values(?,?,?)
+						on duplicate key update session_id = values(session_id), expires_at = values(expires_at)`,
+		code, sessionID, expires)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *JoinCodesDB) Resolve(code string, now time.Time) (string, error) {
+
+	var sessionID string
+	err := db.QueryRow("select session_id from join_codes where code = ? and expires_at > ?", code, now).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return "", games.ErrJoinCodeNotFound
+	}
+	return sessionID, err
+}
+
+func (db *JoinCodesDB) Delete(code string) error {
+
+	_, err := db.Exec("delete from join_codes where code = ?", code)
+	return err
+}
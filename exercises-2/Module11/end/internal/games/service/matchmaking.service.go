@@ -0,0 +1,198 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/someuser/gameserver/internal/cluster"
+	clusterService "github.com/someuser/gameserver/internal/cluster/service"
+	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/games/matchmaking"
+	"github.com/someuser/gameserver/internal/games/wire"
+	ratingsService "github.com/someuser/gameserver/internal/ratings/service"
+	"github.com/someuser/gameserver/internal/users"
+)
+
+const (
+	notifyWriteWait = 10 * time.Second
+
+	//matchmakingOwner is the entry of the cluster directory naming the node running the matchmaking,
+	//the players of all the nodes are queued there so they can be matched together
+	matchmakingOwner = "matchmaking"
+)
+
+var matchmaker *matchmaking.Matchmaker
+
+//wsNotifier sends the match found message on the websocket the player queued with
+type wsNotifier struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (notifier *wsNotifier) send(msg games.GameMsg) error {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	notifier.conn.SetWriteDeadline(time.Now().Add(notifyWriteWait))
+	return wire.Write(notifier.conn, msg)
+}
+
+//Notify sends the match and closes the queue connection, the player then joins the session
+func (notifier *wsNotifier) Notify(msg games.GameMsg) error {
+	err := notifier.send(msg)
+	notifier.conn.Close()
+	return err
+}
+
+//forwardToMatchmaker sends the request to the node running the matchmaking, this node takes the matchmaking
+//over when no live node runs it. It returns false when the request is handled here
+func forwardToMatchmaker(w http.ResponseWriter, r *http.Request) bool {
+	cs := clusterService.Get()
+	if r.Header.Get(forwardedHeader) != "" {
+		return false
+	}
+	//a node shutting down hands the matchmaking over to the others
+	if gameManager.Draining() {
+		cs.Directory.Release(matchmakingOwner, cs.Self)
+		return forwardToOwner(w, r, matchmakingOwner)
+	}
+	err := cs.Directory.Claim(matchmakingOwner, cs.Self)
+	if err == cluster.ErrOwnedElsewhere {
+		return forwardToOwner(w, r, matchmakingOwner)
+	}
+	if err != nil {
+		log.Print("error occued claiming the matchmaking ", err.Error())
+	}
+	return false
+}
+
+//HandleEnqueue puts the user in the matchmaking queue of the game until a match is found or the websocket is closed
+func HandleEnqueue(w http.ResponseWriter, r *http.Request) error {
+
+	var user *users.User
+	if m := r.Context().Value("user"); m != nil {
+		if val, ok := m.(*users.User); ok {
+			user = val
+		} else {
+			return errUnauthorized
+		}
+	}
+
+	if gameManager.Draining() {
+		return games.ErrShuttingDown
+	}
+	if err := gameManager.CheckBan(user.Email); err != nil {
+		return err
+	}
+	g, err := validatGame(w, r)
+	if err != nil {
+		return err
+	}
+
+	ticket := matchmaking.Ticket{
+		Player: games.Player{ID: user.ID, Name: user.Name, Email: user.Email},
+		GameID: g.ID,
+	}
+	query := r.URL.Query()
+	if rating := query.Get("rating"); rating != "" {
+		if ticket.Rating, err = strconv.ParseFloat(rating, 64); err != nil {
+			return newRequestError(http.StatusBadRequest, games.ErrCodeInvalidRequest, "invalid rating")
+		}
+	} else if rating, err := ratingsService.Get().DB.GetRating(user.ID, g.ID); err == nil {
+		//players are matched by their rating in the game unless they ask otherwise
+		ticket.Rating = rating.Rating
+	}
+	//the party query lists the emails of the other members of the party, they are invited to the session
+	if party := query.Get("party"); party != "" {
+		for _, email := range strings.Split(party, ",") {
+			if email = strings.TrimSpace(email); email == "" || email == user.Email {
+				return newRequestError(http.StatusBadRequest, games.ErrCodeInvalidRequest, "invalid party")
+			}
+			ticket.Party = append(ticket.Party, games.Player{Email: email})
+		}
+	}
+
+	conn, err := openWebSocket(w, r)
+	if err != nil {
+		return err
+	}
+
+	notifier := &wsNotifier{conn: conn}
+	if err := matchmaker.Enqueue(ticket, notifier); err != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()), time.Now().Add(notifyWriteWait))
+		conn.Close()
+		return nil
+	}
+
+	queued, _ := games.WrapCommand(games.ON_MATCHMAKING_QUEUED, matchmaker.Status(user.Email), games.Player{})
+	notifier.send(queued)
+
+	//the player leaves the queue when it closes the connection, after a match it is closed by the notifier
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				matchmaker.Cancel(user.Email)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+//EnqueueForMatch called for waiting for a match in the game passed as gameid
+func EnqueueForMatch(w http.ResponseWriter, r *http.Request) {
+	if forwardToMatchmaker(w, r) {
+		return
+	}
+	if err := HandleEnqueue(w, r); err != nil {
+		writeError(w, err)
+		return
+	}
+}
+
+//GetMatchmakingStatus returns where the user stands in the matchmaking queue
+func GetMatchmakingStatus(w http.ResponseWriter, r *http.Request) {
+
+	if forwardToMatchmaker(w, r) {
+		return
+	}
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	status := matchmaker.Status(user.Email)
+	if status == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": status}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//CancelMatchmaking takes the user out of the matchmaking queue
+func CancelMatchmaking(w http.ResponseWriter, r *http.Request) {
+
+	if forwardToMatchmaker(w, r) {
+		return
+	}
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if !matchmaker.Cancel(user.Email) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": "left the queue"}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+
+	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/games/wire"
+	"github.com/someuser/gameserver/internal/users"
+)
+
+var replays games.ReplayLog
+
+//getReplayLog returns where the messages of the sessions are recorded, without GAME_REPLAY_DIR they are kept in memory
+func getReplayLog() games.ReplayLog {
+	dir := viper.GetString("GAME_REPLAY_DIR")
+	if dir == "" {
+		return games.NewMemoryReplayLog()
+	}
+	fileLog, err := games.NewFileReplayLog(dir)
+	if err != nil {
+		log.Fatalf("Error opening the replay log, %s", err)
+	}
+	return fileLog
+}
+
+//readReplay returns the entries of the replay of the session when the user took part in it,
+//or else answers the request
+func readReplay(w http.ResponseWriter, r *http.Request, sessionID string) ([]games.ReplayEntry, bool) {
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		writeError(w, errUnauthorized)
+		return nil, false
+	}
+	entries, err := replays.Read(sessionID)
+	if err == games.ErrNoReplay {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Print("error occued during replay fetch ", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	//the others are not told the session exists
+	if !games.ReplayMember(entries, user.Email) {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	return entries, true
+}
+
+//GetReplay returns all the messages recorded for a game session to the users who took part in it
+func GetReplay(w http.ResponseWriter, r *http.Request) {
+
+	params := mux.Vars(r)
+	//a session still running is recorded by the node running it
+	if forwardToOwner(w, r, params["session"]) {
+		return
+	}
+	entries, ok := readReplay(w, r, params["session"])
+	if !ok {
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": map[string]interface{}{
+		"sessionId": params["session"],
+		"entries":   entries,
+	}}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//PlayReplay sends the recorded messages of a game session on a websocket as they happened,
+//the speed query sets how fast and the client can send {"speed":2} or {"seek":<ms>} while it plays
+func PlayReplay(w http.ResponseWriter, r *http.Request) {
+
+	params := mux.Vars(r)
+	//a session still running is recorded by the node running it
+	if forwardToOwner(w, r, params["session"]) {
+		return
+	}
+	entries, ok := readReplay(w, r, params["session"])
+	if !ok {
+		return
+	}
+	speed := 1.0
+	if value := r.URL.Query().Get("speed"); value != "" {
+		var err error
+		if speed, err = strconv.ParseFloat(value, 64); err != nil || speed <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := openWebSocket(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer conn.Close()
+	notifier := &wsNotifier{conn: conn}
+
+	//the controls are closed when the client goes away, which stops the playback
+	controls := make(chan games.PlaybackControl)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(controls)
+		for {
+			var control games.PlaybackControl
+			if err := wire.Read(conn, &control); err != nil {
+				if _, ok := err.(*wire.DecodeError); ok {
+					continue
+				}
+				return
+			}
+			select {
+			case controls <- control:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = games.Playback(entries, speed, controls, func(entry games.ReplayEntry) error {
+		msg, err := games.WrapCommand(games.ON_REPLAY_MESSAGE, entry, games.Player{})
+		if err != nil {
+			return err
+		}
+		return notifier.send(msg)
+	})
+	if err == nil {
+		ended, _ := games.WrapCommand(games.ON_REPLAY_ENDED, len(entries), games.Player{})
+		notifier.send(ended)
+	}
+}
@@ -0,0 +1,97 @@
+package games
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+//SessionRecord is what is kept of a session so it can be brought back after a restart
+type SessionRecord struct {
+	ID              string    `json:"id"`
+	GameID          string    `json:"gameId"`
+	Players         []Player  `json:"players"`
+	InitialGameData string    `json:"gamedata"`
+	LogicState      string    `json:"logicState,omitempty"`
+	Private         bool      `json:"private,omitempty"`
+	Passphrase      string    `json:"passphrase,omitempty"`
+	JoinCode        string    `json:"joinCode,omitempty"`
+	Host            string    `json:"host,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+//SessionStore persists the sessions of the game manager
+type SessionStore interface {
+	SaveSession(record SessionRecord) error
+	DeleteSession(id string) error
+	GetSessions() ([]SessionRecord, error)
+	//GetSession returns the record of the session, ErrNoSessionRecord when the store doesn't have it
+	GetSession(id string) (SessionRecord, error)
+}
+
+//ErrNoSessionRecord is returned by the stores for the sessions they don't have
+var ErrNoSessionRecord = errors.New("no such session in the store")
+
+//PersistentGameLogic is implemented by game logics that can save their full state,
+//including what is hidden from the players, and resume from it
+type PersistentGameLogic interface {
+	GameLogic
+	Snapshot() (string, error)
+	Restore(snapshot string) error
+}
+
+//MemorySessionStore keeps the sessions in memory, it is meant for tests and local runs
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]SessionRecord
+}
+
+//NewMemorySessionStore creates an empty in memory store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]SessionRecord)}
+}
+
+func (store *MemorySessionStore) SaveSession(record SessionRecord) error {
+	if record.ID == "" {
+		return errors.New("session id is missing")
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if old, ok := store.sessions[record.ID]; ok {
+		record.CreatedAt = old.CreatedAt
+	}
+	store.sessions[record.ID] = record
+	return nil
+}
+
+func (store *MemorySessionStore) DeleteSession(id string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.sessions, id)
+	return nil
+}
+
+func (store *MemorySessionStore) GetSession(id string) (SessionRecord, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	record, ok := store.sessions[id]
+	if !ok {
+		return SessionRecord{}, ErrNoSessionRecord
+	}
+	return record, nil
+}
+
+func (store *MemorySessionStore) GetSessions() ([]SessionRecord, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	records := make([]SessionRecord, 0, len(store.sessions))
+	for _, record := range store.sessions {
+		records = append(records, record)
+	}
+	return records, nil
+}
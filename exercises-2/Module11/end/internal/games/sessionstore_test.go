@@ -0,0 +1,134 @@
+package games
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+//counterLogic is a game in which every move adds to a counter, the game is over at 3
+type counterLogic struct {
+	count int
+}
+
+func (logic *counterLogic) Start(players []Player, options string) error { return nil }
+func (logic *counterLogic) ValidateMove(player Player, move *GameMsg) error {
+	if move.Data != "+1" {
+		return errors.New("invalid move")
+	}
+	return nil
+}
+func (logic *counterLogic) ApplyMove(player Player, move *GameMsg) error {
+	logic.count++
+	return nil
+}
+func (logic *counterLogic) State() string { return strconv.Itoa(logic.count) }
+func (logic *counterLogic) GameOver() (bool, GameResult) {
+	return logic.count >= 3, GameResult{}
+}
+func (logic *counterLogic) Snapshot() (string, error) { return strconv.Itoa(logic.count), nil }
+func (logic *counterLogic) Restore(snapshot string) error {
+	count, err := strconv.Atoi(snapshot)
+	logic.count = count
+	return err
+}
+
+var counterGame = Game{ID: "counter", Name: "counter"}
+
+func init() {
+	RegisterGameLogic(counterGame.ID, func() GameLogic { return &counterLogic{} })
+}
+
+func newTestGameManager(store SessionStore) *GameManager {
+	manager := newGameManager()
+	manager.games[counterGame.ID] = counterGame
+	manager.SetSessionStore(store)
+	go manager.Run()
+	return &manager
+}
+
+//waitForRecord polls the store until the session record satisfies the condition
+func waitForRecord(t *testing.T, store SessionStore, id string, cond func(SessionRecord) bool) SessionRecord {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		records, _ := store.GetSessions()
+		for _, record := range records {
+			if record.ID == id && cond(record) {
+				return record
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("session %s was not persisted as expected", id)
+	return SessionRecord{}
+}
+
+func TestGameManager_RestoreSessions(t *testing.T) {
+	store := NewMemorySessionStore()
+	host := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	guest := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+
+	manager := newTestGameManager(store)
+	session := manager.CreateNewGameSession(counterGame)
+	go session.Run()
+
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{guest}}, host)
+	session.SendToGame <- &startGame
+	move, _ := WrapCommand(GAME_PLAY, "+1", host)
+	session.SendToGame <- &move
+
+	record := waitForRecord(t, store, session.ID, func(record SessionRecord) bool {
+		return record.LogicState == "1"
+	})
+	if record.GameID != counterGame.ID || len(record.Players) != 1 || record.Players[0].Email != guest.Email ||
+		record.Host != host.Email {
+		t.Errorf("persisted record = %+v", record)
+	}
+
+	//a new manager on the same store brings the session back with its state
+	restarted := newTestGameManager(store)
+	restored := restarted.GetSessionByID(session.ID)
+	if restored == nil {
+		t.Fatal("session was not restored")
+	}
+	if restored.InitialGameData != "1" {
+		t.Errorf("restored game data = %q, want %q", restored.InitialGameData, "1")
+	}
+	//the host can still mute the others
+	restored.control(func(gameSession *GameSession) (bool, error) {
+		if gameSession.host != host.Email {
+			t.Errorf("restored host = %q, want %q", gameSession.host, host.Email)
+		}
+		return false, nil
+	})
+
+	//the restored rules carry on from where they stopped and the finished game is removed
+	restored.SendToGame <- &move
+	restored.SendToGame <- &move
+	deadline := time.Now().Add(2 * time.Second)
+	for restarted.GetSessionByID(session.ID) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("finished session was not removed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if records, _ := store.GetSessions(); len(records) != 0 {
+		t.Errorf("store still has %d sessions after the game ended", len(records))
+	}
+}
+
+func TestGameManager_RestoreSessionsSkipsFailedRecords(t *testing.T) {
+	store := NewMemorySessionStore()
+	store.SaveSession(SessionRecord{ID: "unknown-game", GameID: "unknown"})
+
+	manager := newTestGameManager(store)
+	//the record is looked up once the manager restored the sessions
+	if manager.GetSessionByID("unknown-game") != nil {
+		t.Fatal("the session of an unknown game was restored")
+	}
+	if _, err := store.GetSession("unknown-game"); err != nil {
+		t.Errorf("the record that couldn't be restored was removed: %v", err)
+	}
+}
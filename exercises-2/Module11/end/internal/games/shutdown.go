@@ -0,0 +1,77 @@
+package games
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+//ErrShuttingDown is returned for the new sessions once the server is shutting down
+var ErrShuttingDown = NewGameError(ErrCodeUnavailable, "the server is shutting down")
+
+//OnServerShutdown is sent to everyone in the sessions when the server is shutting down, the sessions
+//still running at the deadline are saved and the players can join them again on another server
+type OnServerShutdown struct {
+	Message  string    `json:"message"`
+	Deadline time.Time `json:"deadline,omitempty"`
+}
+
+//Draining tells if the server is shutting down, no new session is started then
+func (manager *GameManager) Draining() bool {
+	select {
+	case <-manager.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+//Shutdown stops starting new sessions, lets the players know and waits for the running sessions to end
+//until the context is done, the sessions still running then are saved in the store and stopped
+func (manager *GameManager) Shutdown(ctx context.Context) error {
+	manager.drainOnce.Do(func() { close(manager.draining) })
+
+	deadline, _ := ctx.Deadline()
+	notice := OnServerShutdown{Message: "the server is shutting down", Deadline: deadline}
+	sessions := manager.GetActiveSessions()
+	for _, session := range sessions {
+		session.notifyShutdown(notice)
+	}
+	log.Printf("waiting for %d game sessions to end", len(sessions))
+
+	for i, session := range sessions {
+		select {
+		case <-session.done:
+		case <-ctx.Done():
+			for _, running := range sessions[i:] {
+				if err := running.suspend(); err == nil {
+					log.Printf("saved game session %s", running.ID)
+				}
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (gameSession *GameSession) notifyShutdown(notice OnServerShutdown) {
+	gameSession.control(func(gameSession *GameSession) (bool, error) {
+		msg, err := WrapCommand(ON_SERVER_SHUTDOWN, notice, Player{})
+		if err != nil {
+			return false, err
+		}
+		gameSession.sendMsgToPlayers(&msg)
+		return false, nil
+	})
+}
+
+//suspend ends the session without deleting it from the store, it is released so that another
+//server adopts it when the players join again
+func (gameSession *GameSession) suspend() error {
+	return gameSession.control(func(gameSession *GameSession) (bool, error) {
+		gameSession.suspended = true
+		gameSession.persist()
+		gameSession.gameManager.releaseSession(gameSession.ID)
+		return true, nil
+	})
+}
@@ -0,0 +1,84 @@
+package games
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/someuser/gameserver/internal/cluster"
+)
+
+//expectAction waits for a message with the action to be written to the connection
+func expectAction(t *testing.T, conn *fakeConn, action GameAction) GameMsg {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-conn.written:
+			if msg.GameAction == action {
+				return msg
+			}
+		case <-deadline:
+			t.Fatalf("no %s message was sent", action)
+		}
+	}
+}
+
+func TestGameManager_Shutdown(t *testing.T) {
+	store := NewMemorySessionStore()
+	directory := cluster.NewMemoryDirectory(time.Minute)
+	a := cluster.Node{ID: "a", Addr: "http://a:8080"}
+	b := cluster.Node{ID: "b", Addr: "http://b:8080"}
+	directory.Heartbeat(a)
+	directory.Heartbeat(b)
+
+	manager := newClusterGameManager(store, directory, a)
+	//the sessions are created once the manager restored the stored ones
+	manager.GetActiveSessions()
+	running := manager.CreateNewGameSession(counterGame)
+	go running.Run()
+	ending := manager.CreateNewGameSession(counterGame)
+	go ending.Run()
+
+	conn := newFakeConn(false)
+	player := running.CreateNewPlayer(nil, 1, "dave", "dave123@gmail.com")
+	player.Start(conn)
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{}, Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"})
+	running.SendToGame <- &startGame
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() { result <- manager.Shutdown(ctx) }()
+
+	expectAction(t, conn, ON_SERVER_SHUTDOWN)
+	if !manager.Draining() {
+		t.Error("the manager is not draining")
+	}
+	if _, err := manager.CreateMatchedSession(counterGame, []Player{{Email: "dan@gmail.com"}}, nil); err != ErrShuttingDown {
+		t.Errorf("CreateMatchedSession() while draining = %v, want %v", err, ErrShuttingDown)
+	}
+	ending.End("game over")
+
+	if err := <-result; err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-running.done:
+	case <-time.After(time.Second):
+		t.Fatal("the session still running at the deadline was not stopped")
+	}
+
+	//the session that ended is gone, the one still running is saved for another node to resume
+	records, _ := store.GetSessions()
+	if len(records) != 1 || records[0].ID != running.ID {
+		t.Fatalf("sessions in the store = %+v, want the one that was running", records)
+	}
+	if _, err := directory.Owner(running.ID); err == nil {
+		t.Error("the saved session was not released")
+	}
+	other := newClusterGameManager(store, directory, b)
+	if adopted := other.AdoptSession(running.ID); adopted == nil {
+		t.Error("the saved session couldn't be resumed by another node")
+	}
+}
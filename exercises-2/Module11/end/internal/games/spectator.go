@@ -0,0 +1,103 @@
+package games
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+//delayedMsg is a broadcast held back from a spectator until it is due
+type delayedMsg struct {
+	due       time.Time
+	msg       *GameMsg
+	spectator *Player
+}
+
+//CreateNewSpectator creates a spectator, it gets the broadcasts of the session
+//but the messages it sends are ignored
+func (gameSession *GameSession) CreateNewSpectator(conn *websocket.Conn, id uint, name string, email string) *Player {
+	spectator := gameSession.CreateNewPlayer(conn, id, name, email)
+	spectator.Spectator = true
+	return spectator
+}
+
+//spectatorDelay is how long broadcasts are held back from spectators so they can't help the players
+func (gameSession *GameSession) spectatorDelay() time.Duration {
+	return time.Duration(gameSession.Game.SpectatorDelay) * time.Second
+}
+
+func (gameSession *GameSession) addSpectator(spectator *Player) {
+	if val, ok := gameSession.Spectators[spectator.Email]; ok {
+		val.Stop()
+	}
+	gameSession.Spectators[spectator.Email] = spectator
+
+	history, hasHistory := gameSession.chatHistory()
+	if gameSession.spectatorDelay() == 0 {
+		spectator.SendCurrentGameStateToPlayer()
+		if hasHistory {
+			spectator.SendMessage(&history)
+		}
+		return
+	}
+	//the state is delayed as well, the broadcasts that follow it will be delayed by as much
+	gameData, err := WrapCommand(ON_GAME_INIT, gameSession.InitialGameData, *spectator)
+	if err != nil {
+		return
+	}
+	gameData.Seq = gameSession.seq
+	gameSession.delayForSpectator(spectator, &gameData)
+	if hasHistory {
+		gameSession.delayForSpectator(spectator, &history)
+	}
+}
+
+func (gameSession *GameSession) removeSpectator(spectator *Player) {
+	if val, ok := gameSession.Spectators[spectator.Email]; ok && val == spectator {
+		spectator.Stop()
+		delete(gameSession.Spectators, spectator.Email)
+	}
+}
+
+func (gameSession *GameSession) sendMsgToSpectators(gameMsg *GameMsg) {
+	for _, spectator := range gameSession.Spectators {
+		if !spectator.IsConnected() {
+			continue
+		}
+		if gameSession.spectatorDelay() == 0 {
+			spectator.SendMessage(gameMsg)
+		} else {
+			gameSession.delayForSpectator(spectator, gameMsg)
+		}
+	}
+}
+
+func (gameSession *GameSession) delayForSpectator(spectator *Player, gameMsg *GameMsg) {
+	delay := gameSession.spectatorDelay()
+	if len(gameSession.delayed) == 0 {
+		gameSession.spectatorTimer.Reset(delay)
+	}
+	gameSession.delayed = append(gameSession.delayed, delayedMsg{
+		due:       time.Now().Add(delay),
+		msg:       gameMsg,
+		spectator: spectator,
+	})
+}
+
+//sendDelayedMsgs sends the messages that are due, the delay being the same for all
+//the queue is ordered and the timer is set for the next one
+func (gameSession *GameSession) sendDelayedMsgs() {
+	now := time.Now()
+	for len(gameSession.delayed) > 0 {
+		next := gameSession.delayed[0]
+		if next.due.After(now) {
+			gameSession.spectatorTimer.Reset(next.due.Sub(now))
+			return
+		}
+		gameSession.delayed = gameSession.delayed[1:]
+		//the spectator might have left or reconnected since
+		if spectator, ok := gameSession.Spectators[next.spectator.Email]; ok && spectator == next.spectator && spectator.IsConnected() {
+			spectator.SendMessage(next.msg)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package games
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+//watch adds a connected spectator to the session without running it, its messages are kept in its outbox
+func watch(session *GameSession, id uint, name string, email string) *Player {
+	spectator := session.CreateNewSpectator(nil, id, name, email)
+	spectator.Conn = newFakeConn(false)
+	spectator.outbox = newOutbox(10, OverflowDisconnect)
+	session.Spectators[email] = spectator
+	return spectator
+}
+
+//spectated returns the data of the messages the spectator got
+func spectated(spectator *Player) []string {
+	msgs, _ := spectator.outbox.take()
+	var data []string
+	for _, msg := range msgs {
+		data = append(data, msg.Data)
+	}
+	return data
+}
+
+//makeDue makes the first count delayed messages due and sends them
+func makeDue(session *GameSession, count int) {
+	for i := 0; i < count && i < len(session.delayed); i++ {
+		session.delayed[i].due = time.Now().Add(-time.Second)
+	}
+	session.sendDelayedMsgs()
+}
+
+func TestGameSession_delayedSpectators(t *testing.T) {
+	manager := newGameManager()
+	session := manager.newGameSession(Game{ID: "chess", SpectatorDelay: 60}, "session")
+	defer session.spectatorTimer.Stop()
+	ana := watch(session, 3, "ana", "ana@gmail.com")
+
+	for _, move := range []string{"e4", "e5", "Nf3"} {
+		msg, _ := WrapCommand(GAME_PLAY, move, Player{})
+		session.sendMsgToPlayers(&msg)
+	}
+	if got := spectated(ana); len(got) != 0 {
+		t.Fatalf("the spectator got %v before the delay", got)
+	}
+
+	//the messages are sent in the order they were broadcast once they are due, the others wait
+	makeDue(session, 2)
+	if got := spectated(ana); len(got) != 2 || got[0] != "e4" || got[1] != "e5" {
+		t.Errorf("the spectator got %v, want e4 then e5", got)
+	}
+	makeDue(session, 1)
+	if got := spectated(ana); len(got) != 1 || got[0] != "Nf3" || len(session.delayed) != 0 {
+		t.Errorf("the spectator got %v with %d messages left", got, len(session.delayed))
+	}
+}
+
+func TestGameSession_spectatorLeaves(t *testing.T) {
+	manager := newGameManager()
+	session := manager.newGameSession(Game{ID: "chess", SpectatorDelay: 60}, "session")
+	defer session.spectatorTimer.Stop()
+	ana := watch(session, 3, "ana", "ana@gmail.com")
+	bob := watch(session, 4, "bob", "bob@gmail.com")
+
+	msg, _ := WrapCommand(GAME_PLAY, "e4", Player{})
+	session.sendMsgToPlayers(&msg)
+	//bob leaves while the move is queued and comes back with a new connection
+	session.removeSpectator(bob)
+	rejoined := watch(session, 4, "bob", "bob@gmail.com")
+
+	makeDue(session, 1)
+	if got := spectated(ana); len(got) != 1 {
+		t.Errorf("ana got %v, want the move", got)
+	}
+	//what was queued for the connection that left is not sent to the new one
+	if got := spectated(rejoined); len(got) != 0 {
+		t.Errorf("bob got %v once rejoined", got)
+	}
+}
+
+func TestGameSession_spectatorMessagesIgnored(t *testing.T) {
+	manager := newTestGameManager(nil)
+	session := manager.CreateNewGameSession(Game{ID: "chess"})
+	go session.Run()
+
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	daveConn := newFakeConn(false)
+	danConn := newFakeConn(false)
+	spectatorConn := newFakeConn(false)
+	session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email).Start(daveConn)
+	session.CreateNewPlayer(nil, dan.ID, dan.Name, dan.Email).Start(danConn)
+	session.CreateNewSpectator(nil, 3, "ana", "ana@gmail.com").Start(spectatorConn)
+
+	//the spectator's move is read before dan's, it is dropped rather than relayed
+	spectatorMove, _ := json.Marshal(GameMsg{GameAction: GAME_PLAY, Data: "spectator move"})
+	spectatorConn.incoming <- spectatorMove
+	spectatorConn.incoming <- spectatorMove
+	danMove, _ := json.Marshal(GameMsg{GameAction: GAME_PLAY, Data: "dan move"})
+	danConn.incoming <- danMove
+
+	if msg := expectAction(t, daveConn, GAME_PLAY); msg.Data != "dan move" {
+		t.Errorf("dave got %s from %s, want the move of dan", msg.Data, msg.Player.Email)
+	}
+	if msg := expectAction(t, spectatorConn, GAME_PLAY); msg.Data != "dan move" {
+		t.Errorf("the spectator got %s, want the move of dan", msg.Data)
+	}
+}
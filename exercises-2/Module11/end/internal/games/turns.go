@@ -0,0 +1,231 @@
+package games
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	//TurnTimeoutSkip passes the turn of a player who ran out of time to the next one
+	TurnTimeoutSkip = "skip"
+	//TurnTimeoutForfeit removes a player who ran out of time from the game
+	TurnTimeoutForfeit = "forfeit"
+)
+
+//TurnBasedGameLogic is implemented by the game logics in which the players play one after the other,
+//the session then rejects the moves made out of turn and times the turns when the game has a TurnConfig
+type TurnBasedGameLogic interface {
+	GameLogic
+	//CurrentPlayer returns the player whose turn it is, false when no one is to play
+	CurrentPlayer() (Player, bool)
+	//SkipTurn passes the turn of the current player to the next one
+	SkipTurn() error
+	//Forfeit takes the player out of the game, the game may be over as a result
+	Forfeit(player Player) error
+}
+
+//turnClock times the turns of the players
+type turnClock struct {
+	config TurnConfig
+	//player is the one whose turn is running, it is empty when no turn is timed
+	player   Player
+	started  time.Time
+	deadline time.Time
+	warnAt   time.Time
+	//banks is the time left to each player of a chess clock
+	banks map[string]time.Duration
+	timer *time.Timer
+}
+
+//newTurnClock returns nil when the game has no time limit on its turns
+func newTurnClock(config *TurnConfig) *turnClock {
+	if config == nil || (config.SecondsPerTurn <= 0 && config.BankSeconds <= 0) {
+		return nil
+	}
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	return &turnClock{
+		config: *config,
+		banks:  make(map[string]time.Duration),
+		timer:  timer,
+	}
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+//C fires when the player to play has to be warned or has run out of time, it is nil without a clock
+func (clock *turnClock) C() <-chan time.Time {
+	if clock == nil {
+		return nil
+	}
+	return clock.timer.C
+}
+
+//start starts the turn of the player and returns how much time it has
+func (clock *turnClock) start(player Player, now time.Time) time.Duration {
+	clock.stop(now)
+
+	allowed := seconds(clock.config.SecondsPerTurn)
+	if clock.config.BankSeconds > 0 {
+		bank, ok := clock.banks[player.Email]
+		if ok {
+			bank += allowed
+		} else {
+			bank = seconds(clock.config.BankSeconds)
+		}
+		clock.banks[player.Email] = bank
+		allowed = bank
+	}
+
+	clock.player = player
+	clock.started = now
+	clock.deadline = now.Add(allowed)
+	clock.warnAt = time.Time{}
+	if warn := seconds(clock.config.WarnSeconds); warn > 0 && allowed > warn {
+		clock.warnAt = clock.deadline.Add(-warn)
+	}
+	clock.schedule(now)
+	return allowed
+}
+
+//stop ends the running turn, with a chess clock the time spent is taken from the bank of the player
+func (clock *turnClock) stop(now time.Time) {
+	if clock.player == (Player{}) {
+		return
+	}
+	if clock.config.BankSeconds > 0 {
+		bank := clock.banks[clock.player.Email] - now.Sub(clock.started)
+		if bank < 0 {
+			bank = 0
+		}
+		clock.banks[clock.player.Email] = bank
+	}
+	clock.player = Player{}
+	if !clock.timer.Stop() {
+		select {
+		case <-clock.timer.C:
+		default:
+		}
+	}
+}
+
+//schedule sets the timer for the warning if there is one to send, or else for the deadline
+func (clock *turnClock) schedule(now time.Time) {
+	next := clock.deadline
+	if !clock.warnAt.IsZero() {
+		next = clock.warnAt
+	}
+	clock.timer.Reset(next.Sub(now))
+}
+
+//timeoutAction is what happens to the player who ran out of time, a player with an empty bank
+//and nothing added to it at every turn can't play anymore and forfeits
+func (clock *turnClock) timeoutAction() string {
+	if clock.config.OnTimeout == TurnTimeoutForfeit ||
+		(clock.config.BankSeconds > 0 && clock.config.SecondsPerTurn <= 0) {
+		return TurnTimeoutForfeit
+	}
+	return TurnTimeoutSkip
+}
+
+func (clock *turnClock) bankSeconds() map[string]float64 {
+	if clock.config.BankSeconds <= 0 {
+		return nil
+	}
+	banks := make(map[string]float64, len(clock.banks))
+	for email, bank := range clock.banks {
+		banks[email] = bank.Seconds()
+	}
+	return banks
+}
+
+//checkTurn rejects the moves of the players whose turn it is not
+func (gameSession *GameSession) checkTurn(player Player) error {
+	logic, ok := gameSession.logic.(TurnBasedGameLogic)
+	if !ok {
+		return nil
+	}
+	current, ok := logic.CurrentPlayer()
+	if !ok || current.Email != player.Email {
+		return ErrNotYourTurn
+	}
+	return nil
+}
+
+//updateTurn starts the clock of the player whose turn it is and lets everyone know how long it has.
+//A player keeping the turn keeps its clock running. The turn of an invited player who didn't join yet
+//is timed once it is connected, the one of a player who left is timed so it is skipped or forfeited
+func (gameSession *GameSession) updateTurn() {
+	logic, ok := gameSession.logic.(TurnBasedGameLogic)
+	clock := gameSession.turns
+	if !ok || clock == nil {
+		return
+	}
+	now := time.Now()
+	player, ok := logic.CurrentPlayer()
+	if ok && clock.player != (Player{}) && clock.player.Email == player.Email {
+		return
+	}
+	if !ok {
+		clock.stop(now)
+		return
+	}
+	//the players who left are no longer in the session, the invited ones are until they join
+	if invited, found := gameSession.Players[player.Email]; found && !invited.IsConnected() {
+		clock.stop(now)
+		return
+	}
+	allowed := clock.start(Player{ID: player.ID, Name: player.Name, Email: player.Email}, now)
+
+	turn := OnTurnChanged{
+		Player:      clock.player,
+		SecondsLeft: allowed.Seconds(),
+		Deadline:    clock.deadline,
+		Banks:       clock.bankSeconds(),
+	}
+	msg, _ := WrapCommand(ON_TURN_CHANGED, turn, Player{})
+	gameSession.sendMsgToPlayers(&msg)
+}
+
+//onTurnTimer warns the players that the time of the turn is running out, or when it is out
+//skips the turn or makes the player forfeit, it returns true when the game is over
+func (gameSession *GameSession) onTurnTimer() bool {
+	clock := gameSession.turns
+	if clock.player == (Player{}) {
+		return false
+	}
+	now := time.Now()
+	if now.Before(clock.deadline) {
+		clock.warnAt = time.Time{}
+		clock.schedule(now)
+		warning := OnTurnChanged{
+			Player:      clock.player,
+			SecondsLeft: clock.deadline.Sub(now).Seconds(),
+			Deadline:    clock.deadline,
+		}
+		msg, _ := WrapCommand(ON_TURN_TIME_WARNING, warning, Player{})
+		gameSession.sendMsgToPlayers(&msg)
+		return false
+	}
+
+	player := clock.player
+	action := clock.timeoutAction()
+	clock.stop(now)
+
+	logic := gameSession.logic.(TurnBasedGameLogic)
+	var err error
+	if action == TurnTimeoutForfeit {
+		err = logic.Forfeit(player)
+	} else {
+		err = logic.SkipTurn()
+	}
+	if err != nil {
+		log.Printf("couldn't %s the turn of %s in session %s: %v", action, player.Email, gameSession.ID, err)
+		return false
+	}
+	msg, _ := WrapCommand(ON_TURN_TIMEOUT, OnTurnTimeout{Player: player, Action: action}, Player{})
+	gameSession.sendMsgToPlayers(&msg)
+	return gameSession.broadcastGameState()
+}
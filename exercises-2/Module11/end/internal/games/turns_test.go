@@ -0,0 +1,223 @@
+package games
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+//turnsLogic lets the players play one after the other, the state is the index of the player to play
+type turnsLogic struct {
+	players   []Player
+	turn      int
+	forfeited bool
+}
+
+var turnsGame = Game{ID: "turns", Turns: &TurnConfig{SecondsPerTurn: 1}}
+
+func (logic *turnsLogic) Start(players []Player, options string) error {
+	logic.players = players
+	return nil
+}
+func (logic *turnsLogic) ValidateMove(player Player, move *GameMsg) error { return nil }
+func (logic *turnsLogic) ApplyMove(player Player, move *GameMsg) error {
+	//the player playing again keeps the turn
+	if move.Data == "again" {
+		return nil
+	}
+	return logic.SkipTurn()
+}
+func (logic *turnsLogic) State() string { return strconv.Itoa(logic.turn) }
+func (logic *turnsLogic) GameOver() (bool, GameResult) {
+	return logic.forfeited, GameResult{}
+}
+func (logic *turnsLogic) CurrentPlayer() (Player, bool) {
+	if len(logic.players) == 0 || logic.forfeited {
+		return Player{}, false
+	}
+	return logic.players[logic.turn], true
+}
+func (logic *turnsLogic) SkipTurn() error {
+	logic.turn = (logic.turn + 1) % len(logic.players)
+	return nil
+}
+func (logic *turnsLogic) Forfeit(player Player) error {
+	logic.forfeited = true
+	return nil
+}
+func (logic *turnsLogic) Snapshot() (string, error) { return logic.State(), nil }
+func (logic *turnsLogic) Restore(snapshot string) error {
+	return errors.New("not restorable")
+}
+
+func init() {
+	RegisterGameLogic(turnsGame.ID, func() GameLogic { return &turnsLogic{} })
+}
+
+func TestTurnClock(t *testing.T) {
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	now := time.Now()
+
+	clock := newTurnClock(&TurnConfig{SecondsPerTurn: 2, BankSeconds: 10, WarnSeconds: 3})
+	if allowed := clock.start(dave, now); allowed != 10*time.Second {
+		t.Errorf("first turn of dave = %v, want the whole bank", allowed)
+	}
+	if want := now.Add(7 * time.Second); !clock.warnAt.Equal(want) {
+		t.Errorf("warning at %v, want %v", clock.warnAt, want)
+	}
+	clock.start(dan, now.Add(4*time.Second))
+	//dave spent 4 seconds of the bank and gets 2 more for the new turn
+	if allowed := clock.start(dave, now.Add(5*time.Second)); allowed != 8*time.Second {
+		t.Errorf("second turn of dave = %v, want 8s", allowed)
+	}
+	if banks := clock.bankSeconds(); banks[dan.Email] != 9 {
+		t.Errorf("banks = %v, want 9 seconds left to dan", banks)
+	}
+	clock.stop(now.Add(time.Minute))
+	if clock.banks[dave.Email] != 0 {
+		t.Errorf("bank of dave = %v after running out of time", clock.banks[dave.Email])
+	}
+
+	if action := clock.timeoutAction(); action != TurnTimeoutSkip {
+		t.Errorf("timeoutAction() = %s, want %s", action, TurnTimeoutSkip)
+	}
+	noIncrement := newTurnClock(&TurnConfig{BankSeconds: 60})
+	if action := noIncrement.timeoutAction(); action != TurnTimeoutForfeit {
+		t.Errorf("timeoutAction() without increment = %s, want %s", action, TurnTimeoutForfeit)
+	}
+	if newTurnClock(&TurnConfig{OnTimeout: TurnTimeoutForfeit}) != nil {
+		t.Error("a game without time limit shouldn't have a clock")
+	}
+}
+
+func TestGameSession_turns(t *testing.T) {
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+
+	store := NewMemorySessionStore()
+	manager := newTestGameManager(store)
+	session := manager.CreateNewGameSession(turnsGame)
+	go session.Run()
+	session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email).Start(newFakeConn(false))
+	session.CreateNewPlayer(nil, dan.ID, dan.Name, dan.Email).Start(newFakeConn(false))
+
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}}, dave)
+	session.SendToGame <- &startGame
+	//dan can't play before dave
+	outOfTurn, _ := WrapCommand(GAME_PLAY, "move", dan)
+	session.SendToGame <- &outOfTurn
+	move, _ := WrapCommand(GAME_PLAY, "move", dave)
+	session.SendToGame <- &move
+
+	waitForRecord(t, store, session.ID, func(record SessionRecord) bool {
+		return record.LogicState == "1"
+	})
+	//dan doesn't play and the turn goes back to dave when the time is out
+	waitForRecord(t, store, session.ID, func(record SessionRecord) bool {
+		return record.LogicState == "0"
+	})
+}
+
+func TestGameSession_updateTurn(t *testing.T) {
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	game := turnsGame
+	game.Turns = &TurnConfig{SecondsPerTurn: 60}
+	clockOf := func(session *GameSession) (player Player, started time.Time) {
+		session.control(func(gameSession *GameSession) (bool, error) {
+			player, started = gameSession.turns.player, gameSession.turns.started
+			return false, nil
+		})
+		return player, started
+	}
+
+	manager := newTestGameManager(nil)
+	session := manager.CreateNewGameSession(game)
+	go session.Run()
+	daveConn := newFakeConn(false)
+	session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email).Start(daveConn)
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}}, dave)
+	session.SendToGame <- &startGame
+	expectAction(t, daveConn, ON_TURN_CHANGED)
+
+	//the turn of dan is timed once dan is connected
+	move, _ := WrapCommand(GAME_PLAY, "move", dave)
+	session.SendToGame <- &move
+	expectAction(t, daveConn, ON_GAME_STATE_CHANGED)
+	if player, _ := clockOf(session); player != (Player{}) {
+		t.Errorf("the turn of %+v is timed before dan is connected", player)
+	}
+	session.CreateNewPlayer(nil, dan.ID, dan.Name, dan.Email).Start(newFakeConn(false))
+	var turn OnTurnChanged
+	json.Unmarshal([]byte(expectAction(t, daveConn, ON_TURN_CHANGED).Data), &turn)
+	if turn.Player.Email != dan.Email {
+		t.Errorf("turn changed to %+v, want dan", turn.Player)
+	}
+
+	//dan keeps the turn and its clock
+	_, started := clockOf(session)
+	again, _ := WrapCommand(GAME_PLAY, "again", dan)
+	session.SendToGame <- &again
+	expectAction(t, daveConn, ON_GAME_STATE_CHANGED)
+	if player, restarted := clockOf(session); player.Email != dan.Email || !restarted.Equal(started) {
+		t.Errorf("the clock of %+v started at %v, want the one of dan at %v", player, restarted, started)
+	}
+	select {
+	case msg := <-daveConn.written:
+		t.Errorf("%s was sent when dan kept the turn", msg.GameAction)
+	default:
+	}
+}
+
+func TestGameSession_turnOfPlayerWhoLeft(t *testing.T) {
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+
+	manager := newTestGameManager(nil)
+	session := manager.CreateNewGameSession(turnsGame)
+	go session.Run()
+	daveConn := newFakeConn(false)
+	danConn := newFakeConn(false)
+	session.CreateNewPlayer(nil, dave.ID, dave.Name, dave.Email).Start(daveConn)
+	session.CreateNewPlayer(nil, dan.ID, dan.Name, dan.Email).Start(danConn)
+	startGame, _ := WrapCommand(START_GAME, StartGameMsg{Players: []Player{dan}}, dave)
+	session.SendToGame <- &startGame
+	expectAction(t, daveConn, ON_TURN_CHANGED)
+
+	//dan leaves before it is his turn, the turn is still timed and skipped once the time is out
+	danConn.Close()
+	expectAction(t, daveConn, ON_USER_DISCONNECTED)
+	move, _ := WrapCommand(GAME_PLAY, "move", dave)
+	session.SendToGame <- &move
+	var timeout OnTurnTimeout
+	json.Unmarshal([]byte(expectAction(t, daveConn, ON_TURN_TIMEOUT).Data), &timeout)
+	if timeout.Player.Email != dan.Email || timeout.Action != TurnTimeoutSkip {
+		t.Errorf("turn timeout = %+v, want the turn of dan skipped", timeout)
+	}
+	var turn OnTurnChanged
+	json.Unmarshal([]byte(expectAction(t, daveConn, ON_TURN_CHANGED).Data), &turn)
+	if turn.Player.Email != dave.Email {
+		t.Errorf("turn changed to %+v, want dave", turn.Player)
+	}
+}
+
+func TestGameSession_checkTurn(t *testing.T) {
+	dave := Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+
+	manager := newGameManager()
+	session := manager.newGameSession(turnsGame, "session")
+	if err := session.checkTurn(dave); err == nil {
+		t.Error("checkTurn() accepted a move before the game started")
+	}
+	session.logic.Start([]Player{dave, dan}, "")
+	if err := session.checkTurn(dave); err != nil {
+		t.Errorf("checkTurn() = %v for the player to play", err)
+	}
+	if err := session.checkTurn(dan); err == nil {
+		t.Error("checkTurn() accepted a move out of turn")
+	}
+}
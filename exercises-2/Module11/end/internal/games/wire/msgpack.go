@@ -0,0 +1,564 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+//msgPackCodec encodes the values as MessagePack maps with the names of their json tags, the structs are
+//encoded field by field and the types with their own json encoding, like time.Time, as that json
+type msgPackCodec struct{}
+
+//RawFielder is implemented by the messages that carry json text in a string field, MessagePack carries
+//the json objects and arrays of that field as native maps and arrays rather than as escaped strings.
+//A client can send that field either way, what isn't a string is read back as its json text
+type RawFielder interface {
+	//RawField is the json name of the field
+	RawField() string
+}
+
+func (msgPackCodec) Subprotocol() string { return MsgPackSubprotocol }
+func (msgPackCodec) MessageType() int    { return websocket.BinaryMessage }
+
+func (msgPackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgPackCodec) Unmarshal(data []byte, v interface{}) error {
+	reader := &msgPackReader{data: data}
+	value, err := reader.decode()
+	if err != nil {
+		return err
+	}
+	if reader.pos != len(data) {
+		return errors.New("msgpack: extra data after the value")
+	}
+	if fielder, ok := v.(RawFielder); ok {
+		if object, ok := value.(map[string]interface{}); ok {
+			if raw, ok := object[fielder.RawField()]; ok && raw != nil {
+				if _, text := raw.(string); !text {
+					rawJSON, err := json.Marshal(raw)
+					if err != nil {
+						return err
+					}
+					object[fielder.RawField()] = string(rawJSON)
+				}
+			}
+		}
+	}
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonData, v)
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	rawFielderType    = reflect.TypeOf((*RawFielder)(nil)).Elem()
+)
+
+//encodeValue writes the value as encoding/json would encode it
+func encodeValue(buf *bytes.Buffer, value reflect.Value) error {
+	if !value.IsValid() {
+		buf.WriteByte(0xc0)
+		return nil
+	}
+	if value.Type().Implements(jsonMarshalerType) && !((value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil()) {
+		return encodeJSON(buf, value.Interface())
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+		return encodeValue(buf, value.Elem())
+	case reflect.Bool:
+		return encodeMsgPack(buf, value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		encodeInt(buf, value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := value.Uint(); u > math.MaxInt64 {
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, u)
+		} else {
+			encodeInt(buf, int64(u))
+		}
+	case reflect.Float32, reflect.Float64:
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(value.Float()))
+	case reflect.String:
+		return encodeMsgPack(buf, value.String())
+	case reflect.Slice:
+		if value.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+		//the bytes are a base64 string in json
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return encodeJSON(buf, value.Interface())
+		}
+		return encodeArray(buf, value)
+	case reflect.Array:
+		return encodeArray(buf, value)
+	case reflect.Map:
+		if value.IsNil() {
+			buf.WriteByte(0xc0)
+			return nil
+		}
+		if value.Type().Key().Kind() != reflect.String {
+			return encodeJSON(buf, value.Interface())
+		}
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		encodeLength(buf, len(keys), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			encodeMsgPack(buf, key.String())
+			if err := encodeValue(buf, value.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return encodeStruct(buf, value)
+	default:
+		return fmt.Errorf("msgpack: can't encode %s", value.Type())
+	}
+	return nil
+}
+
+func encodeArray(buf *bytes.Buffer, value reflect.Value) error {
+	encodeLength(buf, value.Len(), 0x90, 15, 0, 0xdc, 0xdd)
+	for i := 0; i < value.Len(); i++ {
+		if err := encodeValue(buf, value.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeStruct(buf *bytes.Buffer, value reflect.Value) error {
+	type field struct {
+		name  string
+		value reflect.Value
+		raw   bool
+	}
+	var fields []field
+	for _, f := range structFields(value.Type()) {
+		fieldValue, ok := fieldByIndex(value, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fieldValue)) {
+			continue
+		}
+		fields = append(fields, field{name: f.name, value: fieldValue, raw: f.raw})
+	}
+	encodeLength(buf, len(fields), 0x80, 15, 0, 0xde, 0xdf)
+	for _, f := range fields {
+		encodeMsgPack(buf, f.name)
+		if f.raw {
+			if err := encodeRaw(buf, f.value.String()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := encodeValue(buf, f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//encodeRaw writes the json objects and arrays as native values, anything else as the string it is
+func encodeRaw(buf *bytes.Buffer, text string) error {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') || !json.Valid([]byte(trimmed)) {
+		return encodeMsgPack(buf, text)
+	}
+	return decodeJSON(buf, []byte(trimmed))
+}
+
+//encodeJSON writes the value the way it encodes itself to json
+func encodeJSON(buf *bytes.Buffer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(buf, data)
+}
+
+func decodeJSON(buf *bytes.Buffer, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	return encodeMsgPack(buf, value)
+}
+
+//msgPackField is a field of a struct as encoding/json sees it
+type msgPackField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	raw       bool
+}
+
+var msgPackFields sync.Map
+
+//structFields returns the fields encoded of the struct type, the fields of the structs it embeds without
+//a name included
+func structFields(t reflect.Type) []msgPackField {
+	if fields, ok := msgPackFields.Load(t); ok {
+		return fields.([]msgPackField)
+	}
+	raw := ""
+	if t.Implements(rawFielderType) {
+		raw = reflect.Zero(t).Interface().(RawFielder).RawField()
+	}
+	var fields []msgPackField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, options = tag[:comma], tag[comma+1:]
+		}
+		embedded := f.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if f.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+			for _, inner := range structFields(embedded) {
+				inner.index = append([]int{i}, inner.index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, msgPackField{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+			raw:       name == raw && f.Type.Kind() == reflect.String,
+		})
+	}
+	msgPackFields.Store(t, fields)
+	return fields
+}
+
+//fieldByIndex returns the field, false when it is in an embedded struct the pointer to which is nil
+func fieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, n := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(n)
+	}
+	return value, true
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	return false
+}
+
+//encodeMsgPack writes the values encoding/json decodes to
+func encodeMsgPack(buf *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if value {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			encodeInt(buf, i)
+			return nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		encodeLength(buf, len(value), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(value)
+	case []interface{}:
+		encodeLength(buf, len(value), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range value {
+			if err := encodeMsgPack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		encodeLength(buf, len(value), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			encodeMsgPack(buf, key)
+			if err := encodeMsgPack(buf, value[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: can't encode %T", value)
+	}
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 127:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+//encodeLength writes the header of a string, array or map, the short form holds the length in the
+//first byte, the 8 bit form doesn't exist for arrays and maps
+func encodeLength(buf *bytes.Buffer, n int, fix byte, fixMax int, code8 byte, code16 byte, code32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+//maxMsgPackDepth is how deep arrays and maps can be nested in a message
+const maxMsgPackDepth = 64
+
+var (
+	errMsgPackShort = errors.New("msgpack: unexpected end of data")
+	errMsgPackDeep  = errors.New("msgpack: too deeply nested")
+)
+
+//msgPackReader decodes MessagePack into the values encoding/json encodes
+type msgPackReader struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func (reader *msgPackReader) next(n int) ([]byte, error) {
+	if n < 0 || reader.pos+n > len(reader.data) {
+		return nil, errMsgPackShort
+	}
+	b := reader.data[reader.pos : reader.pos+n]
+	reader.pos += n
+	return b, nil
+}
+
+//uint reads a big endian unsigned integer of n bytes
+func (reader *msgPackReader) uint(n int) (uint64, error) {
+	b, err := reader.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, c := range b {
+		value = value<<8 | uint64(c)
+	}
+	return value, nil
+}
+
+func (reader *msgPackReader) decode() (interface{}, error) {
+	b, err := reader.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return reader.str(int(code & 0x1f))
+	case code&0xf0 == 0x90:
+		return reader.array(int(code & 0x0f))
+	case code&0xf0 == 0x80:
+		return reader.object(int(code & 0x0f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		value, err := reader.uint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if value > math.MaxInt64 {
+			return float64(value), nil
+		}
+		return int64(value), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		value, err := reader.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		//sign extend from the size of the integer
+		shift := uint(64 - 8*size)
+		return int64(value<<shift) >> shift, nil
+	case 0xca:
+		value, err := reader.uint(4)
+		return float64(math.Float32frombits(uint32(value))), err
+	case 0xcb:
+		value, err := reader.uint(8)
+		return math.Float64frombits(value), err
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		//binary data is read as a string
+		var size int
+		if code >= 0xd9 {
+			size = 1 << (code - 0xd9)
+		} else {
+			size = 1 << (code - 0xc4)
+		}
+		n, err := reader.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		return reader.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := reader.uint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return reader.array(int(n))
+	case 0xde, 0xdf:
+		n, err := reader.uint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return reader.object(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type 0x%x", code)
+}
+
+func (reader *msgPackReader) str(n int) (interface{}, error) {
+	b, err := reader.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+//enter checks the n items of an array or map can be read, every item takes at least a byte
+func (reader *msgPackReader) enter(n int) error {
+	if n > len(reader.data)-reader.pos {
+		return errMsgPackShort
+	}
+	if reader.depth++; reader.depth > maxMsgPackDepth {
+		return errMsgPackDeep
+	}
+	return nil
+}
+
+func (reader *msgPackReader) array(n int) (interface{}, error) {
+	if err := reader.enter(n); err != nil {
+		return nil, err
+	}
+	defer func() { reader.depth-- }()
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := reader.decode()
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (reader *msgPackReader) object(n int) (interface{}, error) {
+	if err := reader.enter(n); err != nil {
+		return nil, err
+	}
+	defer func() { reader.depth-- }()
+	object := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := reader.decode()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, errors.New("msgpack: map keys must be strings")
+		}
+		if object[name], err = reader.decode(); err != nil {
+			return nil, err
+		}
+	}
+	return object, nil
+}
@@ -0,0 +1,94 @@
+//Package wire encodes the messages sent on the websockets in the format the client negotiated
+package wire
+
+import (
+	"encoding/json"
+
+	"github.com/gorilla/websocket"
+)
+
+//the websocket subprotocols a client can ask for, a client asking for none gets JSON
+const (
+	JSONSubprotocol    = "gameserver.json"
+	MsgPackSubprotocol = "gameserver.msgpack"
+)
+
+//Codec encodes the messages of a websocket
+type Codec interface {
+	Subprotocol() string
+	//MessageType is the websocket message type the encoded messages are sent with
+	MessageType() int
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+//Conn is a websocket, a *websocket.Conn
+type Conn interface {
+	Subprotocol() string
+	ReadMessage() (messageType int, data []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+}
+
+//DecodeError is returned by Read when a message was received but couldn't be decoded,
+//the connection can still be used
+type DecodeError struct {
+	Err error
+}
+
+func (err *DecodeError) Error() string {
+	return "invalid message: " + err.Err.Error()
+}
+
+var (
+	//JSON is the text format the clients have always used
+	JSON Codec = jsonCodec{}
+	//MsgPack is MessagePack, it is smaller and the game data isn't escaped
+	MsgPack Codec = msgPackCodec{}
+
+	codecs = map[string]Codec{
+		JSONSubprotocol:    JSON,
+		MsgPackSubprotocol: MsgPack,
+	}
+)
+
+//Subprotocols are the subprotocols the server accepts in its order of preference
+func Subprotocols() []string {
+	return []string{MsgPackSubprotocol, JSONSubprotocol}
+}
+
+//ForSubprotocol returns the codec of the subprotocol, JSON when there is none
+func ForSubprotocol(subprotocol string) Codec {
+	if codec, ok := codecs[subprotocol]; ok {
+		return codec
+	}
+	return JSON
+}
+
+//Write encodes the value with the codec negotiated on the connection and sends it
+func Write(conn Conn, v interface{}) error {
+	codec := ForSubprotocol(conn.Subprotocol())
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(codec.MessageType(), data)
+}
+
+//Read receives the next message and decodes it with the codec negotiated on the connection
+func Read(conn Conn, v interface{}) error {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	if err := ForSubprotocol(conn.Subprotocol()).Unmarshal(data, v); err != nil {
+		return &DecodeError{Err: err}
+	}
+	return nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Subprotocol() string                        { return JSONSubprotocol }
+func (jsonCodec) MessageType() int                           { return websocket.TextMessage }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,214 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+type testPlayer struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+}
+
+type testMsg struct {
+	Action  string             `json:"action"`
+	Data    string             `json:"data"`
+	Player  testPlayer         `json:"player"`
+	Seq     uint64             `json:"seq,omitempty"`
+	Score   float64            `json:"score"`
+	Delta   int                `json:"delta"`
+	Flags   []bool             `json:"flags"`
+	Extra   map[string]*string `json:"extra"`
+	Payload []byte             `json:"payload"`
+}
+
+func TestMsgPack_roundTrip(t *testing.T) {
+	long := strings.Repeat("x", 70000)
+	tests := []testMsg{
+		{Action: "GAME_PLAY", Data: `{"card":3}`, Player: testPlayer{ID: 1, Email: "dave123@gmail.com"}, Seq: 42},
+		{Action: "ON_GAME_INIT", Data: long, Seq: 1 << 40, Score: 2.5, Delta: -1000, Flags: []bool{true, false}},
+		{Action: strings.Repeat("a", 200), Delta: -5, Extra: map[string]*string{"none": nil}, Payload: []byte{0, 1, 2}},
+	}
+	for _, msg := range tests {
+		data, err := MsgPack.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got testMsg
+		if err := MsgPack.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("Unmarshal(Marshal(%.40v)) = %.40v", msg, got)
+		}
+	}
+}
+
+//rawMsg carries its data as json text
+type rawMsg struct {
+	Action string `json:"action"`
+	Data   string `json:"data"`
+}
+
+func (rawMsg) RawField() string { return "data" }
+
+func TestMsgPack_rawField(t *testing.T) {
+	tests := []struct {
+		data       string
+		wantNative bool
+	}{
+		{data: `{"card":3,"faces":["a","b"],"big":12345678901234567}`, wantNative: true},
+		{data: `[1,2.5,null]`, wantNative: true},
+		{data: `"quoted"`},
+		{data: `3`},
+		{data: `{"card":`},
+		{data: ""},
+	}
+	for _, tt := range tests {
+		data, err := MsgPack.Marshal(rawMsg{Action: "GAME_PLAY", Data: tt.data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, _ := (&msgPackReader{data: data}).decode()
+		if _, text := value.(map[string]interface{})["data"].(string); text == tt.wantNative {
+			t.Errorf("data %s sent as %#v", tt.data, value)
+		}
+		var got rawMsg
+		if err := MsgPack.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if tt.wantNative {
+			//the json read back is the same value, not always the same text
+			var want, back interface{}
+			json.Unmarshal([]byte(tt.data), &want)
+			json.Unmarshal([]byte(got.Data), &back)
+			if !reflect.DeepEqual(back, want) {
+				t.Errorf("data %s read back as %s", tt.data, got.Data)
+			}
+		} else if got.Data != tt.data {
+			t.Errorf("data %s read back as %s", tt.data, got.Data)
+		}
+	}
+
+	//a client sends the data as a native map
+	data, _ := MsgPack.Marshal(map[string]interface{}{"action": "GAME_PLAY", "data": map[string]interface{}{"card": 3}})
+	var got rawMsg
+	if err := MsgPack.Unmarshal(data, &got); err != nil || got.Data != `{"card":3}` {
+		t.Errorf("Unmarshal() = %+v, %v", got, err)
+	}
+}
+
+func TestMsgPack_encoding(t *testing.T) {
+	data, err := MsgPack.Marshal(map[string]interface{}{"a": 1, "b": "hi", "c": -1, "d": 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x84, 0xa1, 'a', 0x01, 0xa1, 'b', 0xa2, 'h', 'i', 0xa1, 'c', 0xff, 0xa1, 'd', 0xd1, 0x01, 0x2c}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Marshal() = % x, want % x", data, want)
+	}
+
+	//the game data is not escaped as it is in json
+	msg := testMsg{Action: "GAME_PLAY", Data: `{"cards":[1,2,3],"name":"pikachu"}`}
+	packed, _ := MsgPack.Marshal(msg)
+	text, _ := JSON.Marshal(msg)
+	if len(packed) >= len(text) {
+		t.Errorf("msgpack message is %d bytes, json is %d", len(packed), len(text))
+	}
+}
+
+func TestMsgPack_invalid(t *testing.T) {
+	tests := map[string][]byte{
+		"truncated string": {0xa5, 'a'},
+		"truncated map":    {0x81, 0xa1, 'a'},
+		"huge array":       {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"integer key":      {0x81, 0x01, 0x01},
+		"extension":        {0xd4, 0x01, 0x01},
+		"extra data":       {0x01, 0x02},
+		"too deep":         bytes.Repeat([]byte{0x91}, 100),
+	}
+	for name, data := range tests {
+		var v interface{}
+		if err := MsgPack.Unmarshal(data, &v); err == nil {
+			t.Errorf("%s: Unmarshal() = %v, want an error", name, v)
+		}
+	}
+}
+
+func TestNegotiation(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: Subprotocols(), EnableCompression: true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		//echo the messages back in the negotiated format
+		for {
+			var msg testMsg
+			if err := Read(conn, &msg); err != nil {
+				if _, ok := err.(*DecodeError); ok {
+					continue
+				}
+				return
+			}
+			Write(conn, msg)
+		}
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	tests := []struct {
+		name         string
+		subprotocols []string
+		want         string
+		messageType  int
+	}{
+		{name: "no subprotocol is json", want: "", messageType: websocket.TextMessage},
+		{name: "json", subprotocols: []string{JSONSubprotocol}, want: JSONSubprotocol, messageType: websocket.TextMessage},
+		{name: "msgpack", subprotocols: []string{MsgPackSubprotocol}, want: MsgPackSubprotocol, messageType: websocket.BinaryMessage},
+		{name: "server prefers msgpack", subprotocols: []string{JSONSubprotocol, MsgPackSubprotocol}, want: MsgPackSubprotocol, messageType: websocket.BinaryMessage},
+		{name: "unknown subprotocol is json", subprotocols: []string{"xml"}, want: "", messageType: websocket.TextMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := websocket.Dialer{Subprotocols: tt.subprotocols, EnableCompression: true}
+			conn, resp, err := dialer.Dial(url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if conn.Subprotocol() != tt.want {
+				t.Errorf("negotiated %q, want %q", conn.Subprotocol(), tt.want)
+			}
+			if extensions := resp.Header.Get("Sec-Websocket-Extensions"); !strings.Contains(extensions, "permessage-deflate") {
+				t.Errorf("compression was not negotiated: %q", extensions)
+			}
+
+			//a message that can't be decoded is skipped
+			conn.WriteMessage(tt.messageType, []byte{0xc1})
+			sent := testMsg{Action: "GAME_PLAY", Data: `{"card":3}`, Player: testPlayer{ID: 7}}
+			if err := Write(conn, sent); err != nil {
+				t.Fatal(err)
+			}
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if messageType != tt.messageType {
+				t.Errorf("message type = %d, want %d", messageType, tt.messageType)
+			}
+			var got testMsg
+			if err := ForSubprotocol(conn.Subprotocol()).Unmarshal(data, &got); err != nil || !reflect.DeepEqual(got, sent) {
+				t.Errorf("echoed %+v, %v, want %+v", got, err, sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package invitations
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//EmailSender sends an email, implementations can use SMTP or a provider API
+type EmailSender interface {
+	SendEmail(to string, subject string, body string) error
+}
+
+//FileEmailSender writes the emails as files in a directory instead of sending them,
+//it is meant for local runs and tests
+type FileEmailSender struct {
+	Dir string
+}
+
+func (sender FileEmailSender) SendEmail(to string, subject string, body string) error {
+	if err := os.MkdirAll(sender.Dir, 0755); err != nil {
+		return err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(to))
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n", to, subject, now.Format(time.RFC1123Z), body)
+	return ioutil.WriteFile(filepath.Join(sender.Dir, name), []byte(content), 0644)
+}
+
+//EmailChannel delivers the invitations by email with the links to answer them
+type EmailChannel struct {
+	Sender EmailSender
+	//BaseURL is the address of the game server the links point to
+	BaseURL string
+}
+
+func (channel EmailChannel) Deliver(invitation Invitation) error {
+	baseURL := strings.TrimSuffix(channel.BaseURL, "/")
+	subject := fmt.Sprintf("%s invited you to play %s", invitation.FromName, invitation.GameName)
+	body := fmt.Sprintf("%s (%s) invited you to play %s.\r\n\r\n"+
+		"Accept: POST %s/games/invitations/%s/accept\r\n"+
+		"Decline: POST %s/games/invitations/%s/decline\r\n\r\n"+
+		"The invitation expires on %s.",
+		invitation.FromName, invitation.FromEmail, invitation.GameName,
+		baseURL, invitation.ID, baseURL, invitation.ID,
+		invitation.ExpiresAt.Format(time.RFC1123))
+	return channel.Sender.SendEmail(invitation.ToEmail, subject, body)
+}
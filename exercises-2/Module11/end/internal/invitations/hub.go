@@ -0,0 +1,89 @@
+package invitations
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//ErrNotConnected is returned by the hub when the invited user isn't listening for notifications
+var ErrNotConnected = errors.New("user is not connected")
+
+//Notifier pushes a message to a connected user
+type Notifier interface {
+	Notify(msg games.GameMsg) error
+}
+
+//NotifierFunc lets a function be used as a Notifier
+type NotifierFunc func(msg games.GameMsg) error
+
+func (f NotifierFunc) Notify(msg games.GameMsg) error {
+	return f(msg)
+}
+
+//Hub is the in-app channel, it notifies the users who are connected right away
+type Hub struct {
+	mu        sync.Mutex
+	listeners map[string]map[*listener]struct{}
+}
+
+type listener struct {
+	Notifier
+}
+
+//NewHub type
+func NewHub() *Hub {
+	return &Hub{listeners: make(map[string]map[*listener]struct{})}
+}
+
+//Subscribe notifies the user of the invitations they receive until the returned function is called,
+//a user can be connected from several places at once
+func (hub *Hub) Subscribe(email string, notifier Notifier) func() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	l := &listener{notifier}
+	if hub.listeners[email] == nil {
+		hub.listeners[email] = make(map[*listener]struct{})
+	}
+	hub.listeners[email][l] = struct{}{}
+
+	return func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+
+		delete(hub.listeners[email], l)
+		if len(hub.listeners[email]) == 0 {
+			delete(hub.listeners, email)
+		}
+	}
+}
+
+//Deliver sends ON_GAME_INVITATION to every connection of the invited user
+func (hub *Hub) Deliver(invitation Invitation) error {
+	hub.mu.Lock()
+	notifiers := make([]Notifier, 0, len(hub.listeners[invitation.ToEmail]))
+	for l := range hub.listeners[invitation.ToEmail] {
+		notifiers = append(notifiers, l.Notifier)
+	}
+	hub.mu.Unlock()
+
+	if len(notifiers) == 0 {
+		return ErrNotConnected
+	}
+	msg, err := games.WrapCommand(games.ON_GAME_INVITATION, invitation, games.Player{})
+	if err != nil {
+		return err
+	}
+	delivered := false
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(msg); err == nil {
+			delivered = true
+		}
+	}
+	if !delivered {
+		return ErrNotConnected
+	}
+	return nil
+}
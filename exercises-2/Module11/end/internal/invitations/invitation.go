@@ -0,0 +1,56 @@
+package invitations
+
+import (
+	"errors"
+	"time"
+)
+
+//Status of an invitation
+type Status string
+
+const (
+	Pending  Status = "pending"
+	Accepted Status = "accepted"
+	Declined Status = "declined"
+	Expired  Status = "expired"
+)
+
+//ErrNotFound is returned by the stores for unknown invitations
+var ErrNotFound = errors.New("no such invitation")
+
+//Invitation to join a game session
+type Invitation struct {
+	ID          string    `json:"id"`
+	SessionID   string    `json:"sessionId"`
+	GameID      string    `json:"gameId"`
+	GameName    string    `json:"gameName"`
+	FromEmail   string    `json:"fromEmail"`
+	FromName    string    `json:"fromName"`
+	ToEmail     string    `json:"toEmail"`
+	Status      Status    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	RespondedAt time.Time `json:"respondedAt,omitempty"`
+}
+
+//CurrentStatus is the status of the invitation at the given time, pending invitations expire
+func (invitation Invitation) CurrentStatus(now time.Time) Status {
+	if invitation.Status == Pending && now.After(invitation.ExpiresAt) {
+		return Expired
+	}
+	return invitation.Status
+}
+
+//InvitationStore persists the invitations
+type InvitationStore interface {
+	CreateInvitation(invitation Invitation) error
+	GetInvitation(id string) (Invitation, error)
+	//GetInvitationsFor returns the invitations sent to the email that are still pending at the given time
+	GetInvitationsFor(email string, now time.Time) ([]Invitation, error)
+	UpdateStatus(id string, status Status, at time.Time) error
+}
+
+//Channel delivers an invitation to the invited user
+type Channel interface {
+	Deliver(invitation Invitation) error
+}
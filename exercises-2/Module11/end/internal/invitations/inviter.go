@@ -0,0 +1,106 @@
+package invitations
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//DefaultTTL is how long an invitation can be accepted
+const DefaultTTL = 24 * time.Hour
+
+var (
+	//ErrNotInvited is returned when a user answers an invitation sent to someone else
+	ErrNotInvited = errors.New("the invitation was sent to another user")
+	//ErrNotPending is returned when answering an invitation that was already answered or has expired
+	ErrNotPending = errors.New("the invitation is no longer pending")
+)
+
+//Inviter keeps the invitations of the hosts and delivers them through its channels
+type Inviter struct {
+	store    InvitationStore
+	channels []Channel
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+//NewInviter type
+func NewInviter(store InvitationStore, ttl time.Duration, channels ...Channel) *Inviter {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Inviter{
+		store:    store,
+		channels: channels,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+//SendGameInvitation records an invitation for each invited user and delivers it on every channel,
+//a channel failing to deliver doesn't stop the others
+func (inviter *Inviter) SendGameInvitation(sessionID string, game games.Game, host games.Player, invited []games.Player) error {
+	now := inviter.now()
+	for _, player := range invited {
+		invitation := Invitation{
+			ID:        uuid.New().String(),
+			SessionID: sessionID,
+			GameID:    game.ID,
+			GameName:  game.Name,
+			FromEmail: host.Email,
+			FromName:  host.Name,
+			ToEmail:   player.Email,
+			Status:    Pending,
+			CreatedAt: now,
+			ExpiresAt: now.Add(inviter.ttl),
+		}
+		if err := inviter.store.CreateInvitation(invitation); err != nil {
+			return err
+		}
+		for _, channel := range inviter.channels {
+			if err := channel.Deliver(invitation); err != nil && err != ErrNotConnected {
+				log.Printf("couldn't deliver invitation %s to %s: %v", invitation.ID, invitation.ToEmail, err)
+			}
+		}
+	}
+	return nil
+}
+
+//Pending returns the invitations of the user that can still be answered
+func (inviter *Inviter) Pending(email string) ([]Invitation, error) {
+	return inviter.store.GetInvitationsFor(email, inviter.now())
+}
+
+//Accept marks the invitation as accepted by the invited user
+func (inviter *Inviter) Accept(id string, email string) (Invitation, error) {
+	return inviter.respond(id, email, Accepted)
+}
+
+//Decline marks the invitation as declined by the invited user
+func (inviter *Inviter) Decline(id string, email string) (Invitation, error) {
+	return inviter.respond(id, email, Declined)
+}
+
+func (inviter *Inviter) respond(id string, email string, status Status) (Invitation, error) {
+	invitation, err := inviter.store.GetInvitation(id)
+	if err != nil {
+		return Invitation{}, err
+	}
+	if invitation.ToEmail != email {
+		return Invitation{}, ErrNotInvited
+	}
+	now := inviter.now()
+	if invitation.CurrentStatus(now) != Pending {
+		return Invitation{}, ErrNotPending
+	}
+	if err := inviter.store.UpdateStatus(id, status, now); err != nil {
+		return Invitation{}, err
+	}
+	invitation.Status = status
+	invitation.RespondedAt = now
+	return invitation, nil
+}
@@ -0,0 +1,105 @@
+package invitations
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+var (
+	pokemon = games.Game{ID: "pokemoncards", Name: "Pokemon memory game"}
+	dave    = games.Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan     = games.Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	ann     = games.Player{ID: 3, Name: "ann", Email: "ann@gmail.com"}
+)
+
+func TestInviter_SendGameInvitation(t *testing.T) {
+	store := NewMemoryInvitationStore()
+	hub := NewHub()
+	inviter := NewInviter(store, time.Hour, hub)
+
+	var notified []games.GameMsg
+	unsubscribe := hub.Subscribe(dan.Email, NotifierFunc(func(msg games.GameMsg) error {
+		notified = append(notified, msg)
+		return nil
+	}))
+	defer unsubscribe()
+
+	//ann isn't connected, the invitation is kept for when ann asks for the pending ones
+	if err := inviter.SendGameInvitation("session", pokemon, dave, []games.Player{dan, ann}); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 1 || notified[0].GameAction != games.ON_GAME_INVITATION {
+		t.Fatalf("dan was notified with %+v", notified)
+	}
+	for _, player := range []games.Player{dan, ann} {
+		pending, _ := inviter.Pending(player.Email)
+		if len(pending) != 1 || pending[0].SessionID != "session" || pending[0].FromEmail != dave.Email {
+			t.Errorf("pending invitations of %s = %+v", player.Name, pending)
+		}
+	}
+}
+
+func TestInviter_Respond(t *testing.T) {
+	now := time.Now()
+	inviter := NewInviter(NewMemoryInvitationStore(), time.Hour)
+	inviter.now = func() time.Time { return now }
+
+	inviter.SendGameInvitation("session", pokemon, dave, []games.Player{dan, ann})
+	invitationOf := func(player games.Player) Invitation {
+		pending, _ := inviter.Pending(player.Email)
+		return pending[0]
+	}
+	danInvitation, annInvitation := invitationOf(dan), invitationOf(ann)
+
+	if _, err := inviter.Accept(danInvitation.ID, ann.Email); err != ErrNotInvited {
+		t.Errorf("Accept() by another user = %v, want %v", err, ErrNotInvited)
+	}
+	if _, err := inviter.Accept("unknown", dan.Email); err != ErrNotFound {
+		t.Errorf("Accept() of unknown invitation = %v, want %v", err, ErrNotFound)
+	}
+	accepted, err := inviter.Accept(danInvitation.ID, dan.Email)
+	if err != nil || accepted.Status != Accepted {
+		t.Fatalf("Accept() = %+v, %v", accepted, err)
+	}
+	if _, err := inviter.Decline(danInvitation.ID, dan.Email); err != ErrNotPending {
+		t.Errorf("Decline() of accepted invitation = %v, want %v", err, ErrNotPending)
+	}
+	if pending, _ := inviter.Pending(dan.Email); len(pending) != 0 {
+		t.Errorf("answered invitations are still pending: %+v", pending)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := inviter.Decline(annInvitation.ID, ann.Email); err != ErrNotPending {
+		t.Errorf("Decline() of expired invitation = %v, want %v", err, ErrNotPending)
+	}
+	if pending, _ := inviter.Pending(ann.Email); len(pending) != 0 {
+		t.Errorf("expired invitations are still pending: %+v", pending)
+	}
+}
+
+func TestEmailChannel_Deliver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel := EmailChannel{Sender: FileEmailSender{Dir: dir}, BaseURL: "http://localhost:8080/"}
+	inviter := NewInviter(NewMemoryInvitationStore(), time.Hour, channel)
+	inviter.SendGameInvitation("session", pokemon, dave, []games.Player{dan})
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("%d emails written, want 1", len(files))
+	}
+	content, _ := ioutil.ReadFile(dir + "/" + files[0].Name())
+	invitation, _ := inviter.Pending(dan.Email)
+	for _, want := range []string{"To: " + dan.Email, "dave invited you to play Pokemon memory game",
+		"http://localhost:8080/games/invitations/" + invitation[0].ID + "/accept"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("email doesn't contain %q:\n%s", want, content)
+		}
+	}
+}
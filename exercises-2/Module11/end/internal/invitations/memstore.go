@@ -0,0 +1,67 @@
+package invitations
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//MemoryInvitationStore keeps the invitations in memory, it is meant for tests and local runs
+type MemoryInvitationStore struct {
+	mu          sync.Mutex
+	invitations map[string]Invitation
+}
+
+//NewMemoryInvitationStore type
+func NewMemoryInvitationStore() *MemoryInvitationStore {
+	return &MemoryInvitationStore{invitations: make(map[string]Invitation)}
+}
+
+func (store *MemoryInvitationStore) CreateInvitation(invitation Invitation) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.invitations[invitation.ID] = invitation
+	return nil
+}
+
+func (store *MemoryInvitationStore) GetInvitation(id string) (Invitation, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	invitation, ok := store.invitations[id]
+	if !ok {
+		return Invitation{}, ErrNotFound
+	}
+	return invitation, nil
+}
+
+func (store *MemoryInvitationStore) GetInvitationsFor(email string, now time.Time) ([]Invitation, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	pending := []Invitation{}
+	for _, invitation := range store.invitations {
+		if invitation.ToEmail == email && invitation.CurrentStatus(now) == Pending {
+			pending = append(pending, invitation)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].CreatedAt.After(pending[j].CreatedAt)
+	})
+	return pending, nil
+}
+
+func (store *MemoryInvitationStore) UpdateStatus(id string, status Status, at time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	invitation, ok := store.invitations[id]
+	if !ok {
+		return ErrNotFound
+	}
+	invitation.Status = status
+	invitation.RespondedAt = at
+	store.invitations[id] = invitation
+	return nil
+}
@@ -0,0 +1,147 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/users/auth"
+)
+
+//failure is an error counted in the report under its kind
+type failure struct {
+	kind string
+	err  error
+}
+
+func (f *failure) Error() string {
+	return f.kind + ": " + f.err.Error()
+}
+
+func fail(kind string, err error) error {
+	return &failure{kind: kind, err: err}
+}
+
+//kindOf is the kind an error is counted under
+func kindOf(err error) string {
+	if f, ok := err.(*failure); ok {
+		return f.kind
+	}
+	return "other"
+}
+
+//client talks to the gameserver as the web client of the players does
+type client struct {
+	server      *url.URL
+	http        *http.Client
+	dialer      *websocket.Dialer
+	subprotocol string
+}
+
+func newClient(scenario Scenario) (*client, error) {
+	server, err := url.Parse(scenario.Server)
+	if err != nil {
+		return nil, err
+	}
+	timeout := scenario.replyTimeout()
+	dialer := &websocket.Dialer{HandshakeTimeout: timeout}
+	if scenario.Subprotocol != "" {
+		dialer.Subprotocols = []string{scenario.Subprotocol}
+	}
+	return &client{
+		server:      server,
+		http:        &http.Client{Timeout: timeout},
+		dialer:      dialer,
+		subprotocol: scenario.Subprotocol,
+	}, nil
+}
+
+//authenticate registers the user the first time and logs it in once it exists, it returns its token
+func (c *client) authenticate(name string, email string, password string) (string, error) {
+	user := map[string]string{"name": name, "email": email, "password": password}
+	token, status, err := c.post("/register", user)
+	if err != nil {
+		return "", fail("register", err)
+	}
+	if status == http.StatusCreated {
+		return token, nil
+	}
+	//the server answers bad request to the users already registered
+	if status != http.StatusBadRequest {
+		return "", fail("register: "+strconv.Itoa(status), fmt.Errorf("the server answered %d", status))
+	}
+	token, status, err = c.post("/login", user)
+	if err != nil {
+		return "", fail("login", err)
+	}
+	if status != http.StatusOK {
+		return "", fail("login: "+strconv.Itoa(status), fmt.Errorf("the server answered %d", status))
+	}
+	return token, nil
+}
+
+//post sends the json body and returns the access token of the answer
+func (c *client) post(path string, body interface{}) (string, int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", 0, err
+	}
+	resp, err := c.http.Post(c.url("http", path, nil), "application/json", bytes.NewReader(data))
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		//the connection is reused once the body is read
+		io.Copy(ioutil.Discard, resp.Body)
+		return "", resp.StatusCode, nil
+	}
+	var answer struct {
+		Token string `json:"access-token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil || answer.Token == "" {
+		return "", resp.StatusCode, fmt.Errorf("no access token in the answer of %s", path)
+	}
+	return answer.Token, resp.StatusCode, nil
+}
+
+//dial opens the websocket of a player, it returns how long the handshake took
+func (c *client) dial(path string, query url.Values, token string) (*websocket.Conn, time.Duration, error) {
+	query.Set("version", strconv.Itoa(games.ProtocolVersion))
+	header := http.Header{}
+	header.Set(auth.TokenName, token)
+
+	start := time.Now()
+	conn, resp, err := c.dialer.Dial(c.url("ws", path, query), header)
+	elapsed := time.Since(start)
+	if err != nil {
+		if resp != nil {
+			return nil, elapsed, fail("connect: "+strconv.Itoa(resp.StatusCode), err)
+		}
+		return nil, elapsed, fail("connect", err)
+	}
+	return conn, elapsed, nil
+}
+
+//url returns the url of the path on the server, with a websocket scheme for ws
+func (c *client) url(scheme string, path string, query url.Values) string {
+	u := *c.server
+	if scheme == "ws" {
+		u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
@@ -0,0 +1,188 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/users/auth"
+)
+
+//fakeServer registers the users and echoes the chat messages to everyone in the session,
+//the second message of each player is rejected
+type fakeServer struct {
+	mu       sync.Mutex
+	users    map[string]bool
+	logins   int
+	sessions map[string][]*fakeConn
+}
+
+type fakeConn struct {
+	mu    sync.Mutex
+	conn  *websocket.Conn
+	email string
+}
+
+func (conn *fakeConn) send(action games.GameAction, data interface{}) {
+	msg, _ := games.WrapCommand(action, data, games.Player{})
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	conn.conn.WriteJSON(msg)
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{users: make(map[string]bool), sessions: make(map[string][]*fakeConn)}
+}
+
+func (server *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/register" || r.URL.Path == "/login":
+		var user struct{ Email string }
+		json.NewDecoder(r.Body).Decode(&user)
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		if r.URL.Path == "/login" {
+			server.logins++
+		} else if server.users[user.Email] {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		} else {
+			server.users[user.Email] = true
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": true, "access-token": user.Email})
+	case r.URL.Path == "/games/startnewgame":
+		server.connect(w, r, "session-"+r.Header.Get(auth.TokenName), true)
+	case strings.HasPrefix(r.URL.Path, "/games/joingame/"):
+		server.connect(w, r, strings.TrimPrefix(r.URL.Path, "/games/joingame/"), false)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (server *fakeServer) connect(w http.ResponseWriter, r *http.Request, id string, host bool) {
+	upgrader := websocket.Upgrader{}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn := &fakeConn{conn: ws, email: r.Header.Get(auth.TokenName)}
+	server.mu.Lock()
+	server.sessions[id] = append(server.sessions[id], conn)
+	server.mu.Unlock()
+	if host {
+		conn.send(games.ON_GAME_SESSION_CREATED, games.OnNewGameSessionCreated{SessionID: id})
+	}
+	for {
+		var msg games.GameMsg
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		var chat games.ChatMessage
+		json.Unmarshal([]byte(msg.Data), &chat)
+		if strings.Contains(chat.Text, "#2 ") {
+			conn.send(games.ON_ERROR, games.OnError{Code: games.ErrCodeRateLimited, Action: msg.GameAction, Data: msg.Data})
+			continue
+		}
+		server.mu.Lock()
+		conns := append([]*fakeConn(nil), server.sessions[id]...)
+		server.mu.Unlock()
+		for _, other := range conns {
+			other.send(games.ON_CHAT_MESSAGE, games.OnChatMessage{From: games.Player{Email: conn.email}, Text: chat.Text})
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	fake := newFakeServer()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	scenario := Scenario{
+		Name:               "test",
+		Server:             server.URL,
+		GameID:             "pokemoncards",
+		Sessions:           2,
+		PlayersPerSession:  2,
+		DurationSeconds:    3,
+		MessagesPerSecond:  1,
+		ReplyTimeoutMillis: 1000,
+		Seed:               1,
+	}
+	report, err := Run(context.Background(), scenario)
+	if err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	if report.Players != 4 || report.Connected != 4 || report.Login.Count != 4 || report.Connect.Count != 4 {
+		t.Fatalf("report = %+v, want the 4 players connected", report)
+	}
+	//each player got its messages back but the second one
+	rejected := report.Errors["server: "+string(games.ErrCodeRateLimited)]
+	if report.Sent < 4*2 || rejected != 4 || report.Lost != 0 || report.RoundTrip.Count != report.Sent-rejected {
+		t.Errorf("sent %d, %d round trips, %d rejected and %d lost", report.Sent, report.RoundTrip.Count, rejected, report.Lost)
+	}
+	//the messages of the other player are not waited for
+	if report.Received < report.RoundTrip.Count+rejected+2 || report.Received > 2*report.RoundTrip.Count+rejected+2 {
+		t.Errorf("received %d messages", report.Received)
+	}
+
+	//the users registered by the first run log in
+	if report, _ := Run(context.Background(), scenario); report.Connected != 4 || fake.logins != 4 {
+		t.Errorf("%d players connected with %d logins on the second run", report.Connected, fake.logins)
+	}
+}
+
+func TestScenario_Validate(t *testing.T) {
+	valid := Scenario{Server: "http://localhost:8080", GameID: "pokemoncards", Sessions: 1, PlayersPerSession: 2, DurationSeconds: 10}
+	tests := []struct {
+		name    string
+		change  func(scenario *Scenario)
+		wantErr bool
+	}{
+		{name: "valid", change: func(scenario *Scenario) {}},
+		{name: "no server", change: func(scenario *Scenario) { scenario.Server = "" }, wantErr: true},
+		{name: "no players", change: func(scenario *Scenario) { scenario.PlayersPerSession = 0 }, wantErr: true},
+		{name: "negative rate", change: func(scenario *Scenario) { scenario.MessagesPerSecond = -1 }, wantErr: true},
+		{name: "rate over the chat limit", change: func(scenario *Scenario) { scenario.MessagesPerSecond = 2 }, wantErr: true},
+		{name: "unknown subprotocol", change: func(scenario *Scenario) { scenario.Subprotocol = "xml" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := valid
+			tt.change(&scenario)
+			if err := scenario.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if warnings := valid.Warnings(); len(warnings) != 0 {
+		t.Errorf("warnings = %v", warnings)
+	}
+	valid.MessageSize = int(games.DefaultRateLimitConfig.MaxMessageSize) + 1
+	if warnings := valid.Warnings(); len(warnings) != 1 {
+		t.Errorf("warnings for messages over the read limit = %v, want 1", warnings)
+	}
+}
+
+func TestHistogram_latency(t *testing.T) {
+	var h histogram
+	for i := 100; i >= 1; i-- {
+		h.add(time.Duration(i) * time.Millisecond)
+	}
+	want := Latency{Count: 100, P50: 50, P90: 90, P99: 99, Max: 100}
+	if got := h.latency(); got != want {
+		t.Errorf("latency() = %+v, want %+v", got, want)
+	}
+	if got := (&histogram{}).latency(); got != (Latency{}) {
+		t.Errorf("latency() of no sample = %+v", got)
+	}
+}
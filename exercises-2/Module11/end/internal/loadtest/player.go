@@ -0,0 +1,183 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/games/wire"
+)
+
+//player is a synthetic user connected to a session, it sends chat messages and waits for them to come back
+type player struct {
+	//index numbers the players of the run, the users are named after it
+	index    int
+	name     string
+	email    string
+	conn     *websocket.Conn
+	stats    *stats
+	scenario Scenario
+	//created receives the id of the session the player opened as its host
+	created chan string
+	//pending holds when the messages waiting to come back were sent
+	mu      sync.Mutex
+	pending map[string]time.Time
+	closing bool
+	//done is closed once the connection can no longer be read
+	done chan struct{}
+}
+
+func newPlayer(scenario Scenario, stats *stats, index int, name string, email string, conn *websocket.Conn) *player {
+	p := &player{
+		index:    index,
+		name:     name,
+		email:    email,
+		conn:     conn,
+		stats:    stats,
+		scenario: scenario,
+		created:  make(chan string, 1),
+		pending:  make(map[string]time.Time),
+		done:     make(chan struct{}),
+	}
+	go p.read()
+	return p
+}
+
+//read counts the messages of the server and measures the round trip of the messages of the player
+func (p *player) read() {
+	defer close(p.done)
+	for {
+		var msg games.GameMsg
+		if err := wire.Read(p.conn, &msg); err != nil {
+			if _, ok := err.(*wire.DecodeError); ok {
+				p.stats.addError("decode")
+				continue
+			}
+			p.mu.Lock()
+			closing := p.closing
+			p.mu.Unlock()
+			if !closing {
+				p.stats.addError("disconnected")
+			}
+			return
+		}
+		var roundTrip time.Duration
+		switch msg.GameAction {
+		case games.ON_GAME_SESSION_CREATED:
+			var created games.OnNewGameSessionCreated
+			if err := json.Unmarshal([]byte(msg.Data), &created); err == nil {
+				select {
+				case p.created <- created.SessionID:
+				default:
+				}
+			}
+		case games.ON_CHAT_MESSAGE:
+			var chat games.OnChatMessage
+			if err := json.Unmarshal([]byte(msg.Data), &chat); err == nil && chat.From.Email == p.email {
+				if sent, ok := p.answered(chat.Text); ok {
+					roundTrip = time.Since(sent)
+				}
+			}
+		case games.ON_ERROR:
+			var onError games.OnError
+			json.Unmarshal([]byte(msg.Data), &onError)
+			p.stats.addError("server: " + string(onError.Code))
+			//a message that was rejected won't come back
+			var chat games.ChatMessage
+			if onError.Action == games.CHAT_MESSAGE && json.Unmarshal([]byte(onError.Data), &chat) == nil {
+				p.answered(chat.Text)
+			}
+		case games.ON_GAME_OVER:
+			p.stats.addError("session ended")
+		}
+		p.stats.addReceived(roundTrip)
+	}
+}
+
+//answered removes the message from the ones waiting to come back and returns when it was sent
+func (p *player) answered(text string) (time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sent, ok := p.pending[text]
+	delete(p.pending, text)
+	return sent, ok
+}
+
+//send sends the chat messages of the player at the rate of the scenario from offset until the run is over
+func (p *player) send(offset time.Duration, until time.Time, stop <-chan struct{}) {
+	if p.scenario.MessagesPerSecond <= 0 {
+		return
+	}
+	interval := time.Duration(float64(time.Second) / p.scenario.MessagesPerSecond)
+	next := time.Now().Add(offset)
+	for count := 1; next.Before(until); count++ {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return
+		case <-p.done:
+			timer.Stop()
+			return
+		}
+		next = next.Add(interval)
+
+		text := p.message(count)
+		data, _ := json.Marshal(games.ChatMessage{Text: text})
+		msg := games.GameMsg{GameAction: games.CHAT_MESSAGE, Data: string(data), Version: games.ProtocolVersion}
+		p.mu.Lock()
+		p.pending[text] = time.Now()
+		p.mu.Unlock()
+		if err := wire.Write(p.conn, msg); err != nil {
+			p.answered(text)
+			p.stats.addError("write")
+			return
+		}
+		p.stats.addSent()
+	}
+}
+
+//message is the text of the message, it starts with what tells it apart from the others
+func (p *player) message(count int) string {
+	text := fmt.Sprintf("%s#%d ", p.name, count)
+	if size := p.scenario.messageSize(); len(text) < size {
+		text += strings.Repeat("x", size-len(text))
+	}
+	return text
+}
+
+//close waits for the messages still on their way then closes the connection,
+//the ones that didn't come back within the reply timeout are lost
+func (p *player) close() {
+	deadline := time.Now().Add(p.scenario.replyTimeout())
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		waiting := len(p.pending)
+		p.mu.Unlock()
+		if waiting == 0 {
+			break
+		}
+		select {
+		case <-p.done:
+			deadline = time.Now()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	p.mu.Lock()
+	p.closing = true
+	p.stats.addLost(len(p.pending))
+	p.pending = make(map[string]time.Time)
+	p.mu.Unlock()
+
+	closing := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "load test over")
+	p.conn.WriteControl(websocket.CloseMessage, closing, time.Now().Add(time.Second))
+	p.conn.Close()
+	<-p.done
+}
@@ -0,0 +1,149 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+//Run plays the scenario against its server and reports what was measured, it returns once every
+//player is done or the context is done
+func Run(ctx context.Context, scenario Scenario) (Report, error) {
+	if err := scenario.Validate(); err != nil {
+		return Report{}, err
+	}
+	c, err := newClient(scenario)
+	if err != nil {
+		return Report{}, err
+	}
+	runner := &runner{
+		scenario: scenario,
+		client:   c,
+		stats:    newStats(),
+		offsets:  offsets(scenario),
+		stop:     ctx.Done(),
+	}
+
+	start := time.Now()
+	var sessions sync.WaitGroup
+	for i := 0; i < scenario.Sessions; i++ {
+		sessions.Add(1)
+		go func(session int) {
+			defer sessions.Done()
+			runner.runSession(session)
+		}(i)
+	}
+	sessions.Wait()
+	return runner.stats.report(scenario, time.Since(start)), nil
+}
+
+type runner struct {
+	scenario Scenario
+	client   *client
+	stats    *stats
+	//offsets are when each player sends its first message, drawn from the seed of the scenario
+	offsets []time.Duration
+	stop    <-chan struct{}
+}
+
+//offsets spreads the first messages of the players over the interval between two messages
+func offsets(scenario Scenario) []time.Duration {
+	offsets := make([]time.Duration, scenario.Players())
+	if scenario.MessagesPerSecond <= 0 {
+		return offsets
+	}
+	interval := float64(time.Second) / scenario.MessagesPerSecond
+	r := rand.New(rand.NewSource(scenario.Seed))
+	for i := range offsets {
+		offsets[i] = time.Duration(r.Float64() * interval)
+	}
+	return offsets
+}
+
+//wait waits for the delay, it returns false when the run is stopped meanwhile
+func (runner *runner) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-runner.stop:
+		return false
+	}
+}
+
+//runSession opens the session with its host, has the other players join it then send their messages
+func (runner *runner) runSession(session int) {
+	if !runner.wait(runner.scenario.sessionDelay(session)) {
+		return
+	}
+	first := session * runner.scenario.PlayersPerSession
+	host, err := runner.connect(first, "/games/startnewgame", url.Values{"gameid": {runner.scenario.GameID}})
+	if err != nil {
+		runner.stats.addError(kindOf(err))
+		return
+	}
+	players := []*player{host}
+	defer func() {
+		for _, p := range players {
+			p.close()
+		}
+	}()
+
+	var id string
+	select {
+	case id = <-host.created:
+	case <-host.done:
+		return
+	case <-time.After(runner.scenario.replyTimeout()):
+		runner.stats.addError("session not created")
+		return
+	case <-runner.stop:
+		return
+	}
+	for i := first + 1; i < first+runner.scenario.PlayersPerSession; i++ {
+		p, err := runner.connect(i, "/games/joingame/"+url.PathEscape(id), url.Values{})
+		if err != nil {
+			runner.stats.addError(kindOf(err))
+			continue
+		}
+		players = append(players, p)
+	}
+
+	until := time.Now().Add(runner.scenario.duration())
+	var senders sync.WaitGroup
+	for _, p := range players {
+		senders.Add(1)
+		go func(p *player) {
+			defer senders.Done()
+			p.send(runner.offsets[p.index], until, runner.stop)
+		}(p)
+	}
+	senders.Wait()
+}
+
+//connect logs the user of the player in and opens its websocket
+func (runner *runner) connect(index int, path string, query url.Values) (*player, error) {
+	name, email, password := runner.scenario.user(index)
+	start := time.Now()
+	token, err := runner.client.authenticate(name, email, password)
+	if err != nil {
+		return nil, err
+	}
+	runner.stats.addLogin(time.Since(start))
+
+	select {
+	case <-runner.stop:
+		return nil, fail("stopped", errors.New("the run was stopped"))
+	default:
+	}
+	conn, elapsed, err := runner.client.dial(path, query, token)
+	if err != nil {
+		return nil, err
+	}
+	runner.stats.addConnect(elapsed)
+	return newPlayer(runner.scenario, runner.stats, index, name, email, conn), nil
+}
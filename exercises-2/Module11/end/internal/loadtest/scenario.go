@@ -0,0 +1,146 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+	"github.com/someuser/gameserver/internal/games/wire"
+)
+
+//Scenario is the load run against a server, it is read from a json file so the same load can be run again
+type Scenario struct {
+	Name string `json:"name"`
+	//Server is the base url of the gameserver
+	Server string `json:"server"`
+	GameID string `json:"gameId"`
+	//Sessions are opened by a host then joined by the other PlayersPerSession-1 players
+	Sessions          int `json:"sessions"`
+	PlayersPerSession int `json:"playersPerSession"`
+	//RampUpSeconds spreads the opening of the sessions, DurationSeconds is how long the players send messages once they all joined
+	RampUpSeconds   int `json:"rampUpSeconds,omitempty"`
+	DurationSeconds int `json:"durationSeconds"`
+	//MessagesPerSecond is how many chat messages each player sends, of MessageSize characters, it can't be
+	//over the rate the chat lets the players send
+	MessagesPerSecond float64 `json:"messagesPerSecond"`
+	MessageSize       int     `json:"messageSize,omitempty"`
+	//ReplyTimeoutMillis is how long a message has to come back to its sender before it is counted as lost
+	ReplyTimeoutMillis int `json:"replyTimeoutMillis,omitempty"`
+	//Subprotocol is the encoding of the messages, gameserver.json or gameserver.msgpack
+	Subprotocol string `json:"subprotocol,omitempty"`
+	//the users are named UserPrefix-N, they are registered on the first run and logged in on the next ones
+	UserPrefix string `json:"userPrefix,omitempty"`
+	Password   string `json:"password,omitempty"`
+	//Seed sets when each player sends its messages, the same seed sends them at the same times
+	Seed int64 `json:"seed,omitempty"`
+}
+
+const (
+	defaultMessageSize  = 32
+	defaultReplyTimeout = 5 * time.Second
+	defaultUserPrefix   = "loadtest"
+	//userEmailDomain is the domain of the emails of the synthetic users
+	userEmailDomain = "loadtest.invalid"
+)
+
+//LoadScenario reads a scenario from a json file
+func LoadScenario(path string) (Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return Scenario{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := scenario.Validate(); err != nil {
+		return Scenario{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return scenario, nil
+}
+
+//Validate checks the scenario can be run
+func (scenario Scenario) Validate() error {
+	if scenario.Server == "" || scenario.GameID == "" {
+		return errors.New("the server and the game id are missing")
+	}
+	if scenario.Sessions <= 0 || scenario.PlayersPerSession <= 0 {
+		return errors.New("the sessions and the players per session must be positive")
+	}
+	if scenario.DurationSeconds <= 0 || scenario.RampUpSeconds < 0 {
+		return errors.New("the duration must be positive and the ramp up can't be negative")
+	}
+	if scenario.MessagesPerSecond < 0 || scenario.MessageSize < 0 || scenario.ReplyTimeoutMillis < 0 {
+		return errors.New("the message rate, size and reply timeout can't be negative")
+	}
+	if chat := games.DefaultChatConfig; scenario.MessagesPerSecond > chat.Rate {
+		return fmt.Errorf("the players can't send more than the %g chat messages per second the server lets them send", chat.Rate)
+	}
+	switch scenario.Subprotocol {
+	case "", wire.JSONSubprotocol, wire.MsgPackSubprotocol:
+		return nil
+	}
+	return errors.New("unknown subprotocol " + scenario.Subprotocol)
+}
+
+//Warnings tells what in the scenario goes over the default limits of the server, the messages
+//the server rejects are counted as errors
+func (scenario Scenario) Warnings() []string {
+	var warnings []string
+	limits := games.DefaultRateLimitConfig
+	if total := scenario.MessagesPerSecond * float64(scenario.PlayersPerSession); total > limits.SessionRate {
+		warnings = append(warnings, fmt.Sprintf("the sessions get %g messages per second, the server drops the ones over %g",
+			total, limits.SessionRate))
+	}
+	if size := scenario.messageSize(); int64(size) > limits.MaxMessageSize {
+		warnings = append(warnings, fmt.Sprintf("the messages of %d characters are larger than the %d bytes the server reads",
+			size, limits.MaxMessageSize))
+	}
+	return warnings
+}
+
+//Players is how many players the scenario connects
+func (scenario Scenario) Players() int {
+	return scenario.Sessions * scenario.PlayersPerSession
+}
+
+func (scenario Scenario) messageSize() int {
+	if scenario.MessageSize > 0 {
+		return scenario.MessageSize
+	}
+	return defaultMessageSize
+}
+
+func (scenario Scenario) replyTimeout() time.Duration {
+	if scenario.ReplyTimeoutMillis > 0 {
+		return time.Duration(scenario.ReplyTimeoutMillis) * time.Millisecond
+	}
+	return defaultReplyTimeout
+}
+
+func (scenario Scenario) duration() time.Duration {
+	return time.Duration(scenario.DurationSeconds) * time.Second
+}
+
+//sessionDelay is when the session is opened during the ramp up
+func (scenario Scenario) sessionDelay(session int) time.Duration {
+	rampUp := time.Duration(scenario.RampUpSeconds) * time.Second
+	return rampUp * time.Duration(session) / time.Duration(scenario.Sessions)
+}
+
+//user returns the synthetic user of the player
+func (scenario Scenario) user(player int) (name string, email string, password string) {
+	prefix := scenario.UserPrefix
+	if prefix == "" {
+		prefix = defaultUserPrefix
+	}
+	password = scenario.Password
+	if password == "" {
+		password = prefix
+	}
+	name = fmt.Sprintf("%s-%d", prefix, player)
+	return name, name + "@" + userEmailDomain, password
+}
@@ -0,0 +1,190 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Latency sums up the durations measured, in milliseconds
+type Latency struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+//histogram keeps all the durations measured to compute their percentiles
+type histogram struct {
+	samples []time.Duration
+}
+
+func (h *histogram) add(d time.Duration) {
+	h.samples = append(h.samples, d)
+}
+
+//latency returns the percentiles of the durations, the nearest rank is used
+func (h *histogram) latency() Latency {
+	if len(h.samples) == 0 {
+		return Latency{}
+	}
+	sorted := append([]time.Duration(nil), h.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return millis(sorted[rank])
+	}
+	return Latency{
+		Count: len(sorted),
+		P50:   percentile(0.5),
+		P90:   percentile(0.9),
+		P99:   percentile(0.99),
+		Max:   millis(sorted[len(sorted)-1]),
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+//stats are collected by all the players of the run
+type stats struct {
+	mu        sync.Mutex
+	login     histogram
+	connect   histogram
+	roundTrip histogram
+	connected int
+	sent      int
+	received  int
+	lost      int
+	errors    map[string]int
+}
+
+func newStats() *stats {
+	return &stats{errors: make(map[string]int)}
+}
+
+func (s *stats) addLogin(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.login.add(d)
+}
+
+func (s *stats) addConnect(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connect.add(d)
+	s.connected++
+}
+
+func (s *stats) addSent() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent++
+}
+
+//addReceived counts a message read from the server, roundTrip is set for the messages of the player coming back
+func (s *stats) addReceived(roundTrip time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received++
+	if roundTrip > 0 {
+		s.roundTrip.add(roundTrip)
+	}
+}
+
+func (s *stats) addLost(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lost += count
+}
+
+func (s *stats) addError(kind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[kind]++
+}
+
+//Report is the outcome of a run
+type Report struct {
+	Scenario string `json:"scenario"`
+	Players  int    `json:"players"`
+	//Connected is how many players got a websocket to their session
+	Connected int `json:"connected"`
+	//Seconds is how long the run took, the throughputs are the messages per second over the whole run
+	Seconds   float64 `json:"seconds"`
+	Login     Latency `json:"login"`
+	Connect   Latency `json:"connect"`
+	RoundTrip Latency `json:"roundTrip"`
+	Sent      int     `json:"sent"`
+	Received  int     `json:"received"`
+	//Lost are the messages sent that didn't come back within the reply timeout
+	Lost              int            `json:"lost"`
+	SentPerSecond     float64        `json:"sentPerSecond"`
+	ReceivedPerSecond float64        `json:"receivedPerSecond"`
+	Errors            map[string]int `json:"errors"`
+	TotalErrors       int            `json:"totalErrors"`
+}
+
+func (s *stats) report(scenario Scenario, elapsed time.Duration) Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := Report{
+		Scenario:  scenario.Name,
+		Players:   scenario.Players(),
+		Connected: s.connected,
+		Seconds:   elapsed.Seconds(),
+		Login:     s.login.latency(),
+		Connect:   s.connect.latency(),
+		RoundTrip: s.roundTrip.latency(),
+		Sent:      s.sent,
+		Received:  s.received,
+		Lost:      s.lost,
+		Errors:    make(map[string]int),
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		report.SentPerSecond = float64(s.sent) / seconds
+		report.ReceivedPerSecond = float64(s.received) / seconds
+	}
+	for kind, count := range s.errors {
+		report.Errors[kind] = count
+		report.TotalErrors += count
+	}
+	return report
+}
+
+//Print writes the report for a person to read
+func (report Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "scenario %s: %d/%d players connected in %.1fs\n", report.Scenario, report.Connected, report.Players, report.Seconds)
+	fmt.Fprintf(w, "%-11s %8s %9s %9s %9s %9s\n", "latency ms", "count", "p50", "p90", "p99", "max")
+	for _, row := range []struct {
+		name    string
+		latency Latency
+	}{
+		{"login", report.Login},
+		{"connect", report.Connect},
+		{"round trip", report.RoundTrip},
+	} {
+		fmt.Fprintf(w, "%-11s %8d %9.1f %9.1f %9.1f %9.1f\n", row.name, row.latency.Count,
+			row.latency.P50, row.latency.P90, row.latency.P99, row.latency.Max)
+	}
+	fmt.Fprintf(w, "messages: %d sent (%.1f/s), %d received (%.1f/s), %d lost\n",
+		report.Sent, report.SentPerSecond, report.Received, report.ReceivedPerSecond, report.Lost)
+	fmt.Fprintf(w, "errors: %d\n", report.TotalErrors)
+	kinds := make([]string, 0, len(report.Errors))
+	for kind := range report.Errors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "  %-30s %d\n", kind, report.Errors[kind])
+	}
+}
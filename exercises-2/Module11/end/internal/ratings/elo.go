@@ -0,0 +1,84 @@
+package ratings
+
+import (
+	"math"
+)
+
+//Elo rates multiplayer games as a round of duels between every two players,
+//the winners beat the others and players with the same outcome draw
+type Elo struct {
+	K float64
+}
+
+//DefaultElo type
+var DefaultElo = Elo{K: 32}
+
+//Expected score of a player with rating a against one with rating b
+func (elo Elo) Expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+func duelScore(a, b Outcome) float64 {
+	switch {
+	case a == b:
+		return 0.5
+	case a == Win || b == Loss:
+		return 1
+	default:
+		return 0
+	}
+}
+
+//Rate is a RateFunc
+func (elo Elo) Rate(match Match, current map[uint]Rating) ([]Result, []Rating) {
+	before := make([]Rating, len(match.Players))
+	for i, player := range match.Players {
+		rating, ok := current[player.UserID]
+		if !ok {
+			rating = Rating{UserID: player.UserID, GameID: match.GameID, Rating: DefaultRating}
+		}
+		rating.Name = player.Name
+		before[i] = rating
+	}
+
+	results := make([]Result, 0, len(match.Players))
+	updated := make([]Rating, 0, len(match.Players))
+	for i, player := range match.Players {
+		delta := 0.0
+		if len(match.Players) > 1 {
+			for j, other := range match.Players {
+				if i == j {
+					continue
+				}
+				delta += duelScore(player.Outcome, other.Outcome) - elo.Expected(before[i].Rating, before[j].Rating)
+			}
+			delta *= elo.K / float64(len(match.Players)-1)
+		}
+
+		rating := before[i]
+		rating.Rating = math.Round((rating.Rating+delta)*100) / 100
+		rating.Games++
+		switch player.Outcome {
+		case Win:
+			rating.Wins++
+		case Loss:
+			rating.Losses++
+		default:
+			rating.Draws++
+		}
+		rating.UpdatedAt = match.PlayedAt
+		updated = append(updated, rating)
+
+		results = append(results, Result{
+			SessionID:    match.SessionID,
+			GameID:       match.GameID,
+			UserID:       player.UserID,
+			Outcome:      player.Outcome,
+			Score:        player.Score,
+			RatingBefore: before[i].Rating,
+			RatingAfter:  rating.Rating,
+			PlayedAt:     match.PlayedAt,
+		})
+	}
+	return results, updated
+}
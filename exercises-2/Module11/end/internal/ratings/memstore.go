@@ -0,0 +1,136 @@
+package ratings
+
+import (
+	"sort"
+	"sync"
+)
+
+type ratingKey struct {
+	userID uint
+	gameID string
+}
+
+//MemoryRatingStore keeps results and ratings in memory, it is meant for tests and local runs
+type MemoryRatingStore struct {
+	mu      sync.Mutex
+	ratings map[ratingKey]Rating
+	results []Result
+}
+
+//NewMemoryRatingStore type
+func NewMemoryRatingStore() *MemoryRatingStore {
+	return &MemoryRatingStore{ratings: make(map[ratingKey]Rating)}
+}
+
+func (store *MemoryRatingStore) RecordMatch(match Match, rate RateFunc) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current := make(map[uint]Rating)
+	for _, player := range match.Players {
+		if rating, ok := store.ratings[ratingKey{player.UserID, match.GameID}]; ok {
+			current[player.UserID] = rating
+		}
+	}
+	results, updated := rate(match, current)
+	for _, rating := range updated {
+		store.ratings[ratingKey{rating.UserID, rating.GameID}] = rating
+	}
+	store.results = append(store.results, results...)
+	return nil
+}
+
+func (store *MemoryRatingStore) GetRating(userID uint, gameID string) (Rating, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if rating, ok := store.ratings[ratingKey{userID, gameID}]; ok {
+		return rating, nil
+	}
+	return Rating{UserID: userID, GameID: gameID, Rating: DefaultRating}, nil
+}
+
+func (store *MemoryRatingStore) GetLeaderboard(query LeaderboardQuery) ([]LeaderboardEntry, int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entries := store.leaderboard(query)
+	total := len(entries)
+	if query.Offset >= total {
+		return []LeaderboardEntry{}, total, nil
+	}
+	end := total
+	if query.Limit > 0 && query.Offset+query.Limit < total {
+		end = query.Offset + query.Limit
+	}
+	return entries[query.Offset:end], total, nil
+}
+
+func (store *MemoryRatingStore) GetRank(query LeaderboardQuery, userID uint) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, entry := range store.leaderboard(query) {
+		if entry.UserID == userID {
+			return entry.Rank, nil
+		}
+	}
+	return 0, nil
+}
+
+//leaderboard ranks everyone, by their rating or by what they won since the start of the window
+func (store *MemoryRatingStore) leaderboard(query LeaderboardQuery) []LeaderboardEntry {
+	byUser := make(map[uint]*LeaderboardEntry)
+	for key, rating := range store.ratings {
+		if query.GameID != "" && key.gameID != query.GameID {
+			continue
+		}
+		entry, ok := byUser[key.userID]
+		if !ok {
+			entry = &LeaderboardEntry{UserID: key.userID, Name: rating.Name}
+			byUser[key.userID] = entry
+		}
+		//the rating of all games is the average of the ratings of each game
+		entry.Rating = (entry.Rating*float64(entry.Games) + rating.Rating*float64(rating.Games)) / float64(entry.Games+rating.Games)
+		entry.Games += rating.Games
+	}
+
+	//a time windowed leaderboard only counts the games played since the start of the window
+	windowed := !query.Since.IsZero()
+	played := make(map[uint]int)
+	if windowed {
+		for _, result := range store.results {
+			if result.PlayedAt.Before(query.Since) || (query.GameID != "" && result.GameID != query.GameID) {
+				continue
+			}
+			if entry, ok := byUser[result.UserID]; ok {
+				entry.Gain += result.RatingAfter - result.RatingBefore
+				played[result.UserID]++
+			}
+		}
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(byUser))
+	for userID, entry := range byUser {
+		if windowed {
+			if played[userID] == 0 {
+				continue
+			}
+			entry.Games = played[userID]
+		}
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if windowed && entries[i].Gain != entries[j].Gain {
+			return entries[i].Gain > entries[j].Gain
+		}
+		if entries[i].Rating != entries[j].Rating {
+			return entries[i].Rating > entries[j].Rating
+		}
+		return entries[i].UserID < entries[j].UserID
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
@@ -0,0 +1,92 @@
+package ratings
+
+import (
+	"time"
+)
+
+//DefaultRating is the rating of a user who hasn't played the game yet
+const DefaultRating = 1500
+
+//Outcome of a game for a player
+type Outcome string
+
+const (
+	Win  Outcome = "win"
+	Loss Outcome = "loss"
+	Draw Outcome = "draw"
+)
+
+//Rating of a user in a game
+type Rating struct {
+	UserID    uint      `json:"userId"`
+	Name      string    `json:"name"`
+	GameID    string    `json:"gameId"`
+	Rating    float64   `json:"rating"`
+	Games     int       `json:"games"`
+	Wins      int       `json:"wins"`
+	Losses    int       `json:"losses"`
+	Draws     int       `json:"draws"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+//Result of a game for a single user
+type Result struct {
+	SessionID    string    `json:"sessionId"`
+	GameID       string    `json:"gameId"`
+	UserID       uint      `json:"userId"`
+	Outcome      Outcome   `json:"outcome"`
+	Score        int       `json:"score"`
+	RatingBefore float64   `json:"ratingBefore"`
+	RatingAfter  float64   `json:"ratingAfter"`
+	PlayedAt     time.Time `json:"playedAt"`
+}
+
+//PlayerResult is how a player ended a game
+type PlayerResult struct {
+	UserID  uint
+	Name    string
+	Score   int
+	Outcome Outcome
+}
+
+//Match is a finished game to be rated
+type Match struct {
+	SessionID string
+	GameID    string
+	Players   []PlayerResult
+	PlayedAt  time.Time
+}
+
+//LeaderboardEntry type, Gain is the rating won over the period of a time windowed leaderboard
+type LeaderboardEntry struct {
+	Rank   int     `json:"rank"`
+	UserID uint    `json:"userId"`
+	Name   string  `json:"name"`
+	Rating float64 `json:"rating"`
+	Games  int     `json:"games"`
+	Gain   float64 `json:"gain,omitempty"`
+}
+
+//LeaderboardQuery selects a page of a leaderboard, an empty GameID is the leaderboard of all games
+//and a non zero Since ranks by the rating won since then
+type LeaderboardQuery struct {
+	GameID string
+	Since  time.Time
+	Offset int
+	Limit  int
+}
+
+//RateFunc computes the results and the new ratings of the players of a match from their current ratings
+type RateFunc func(match Match, current map[uint]Rating) ([]Result, []Rating)
+
+//RatingStore persists results and ratings
+type RatingStore interface {
+	//RecordMatch reads the current ratings of the players, rates the match and
+	//saves the results and the new ratings atomically
+	RecordMatch(match Match, rate RateFunc) error
+	GetRating(userID uint, gameID string) (Rating, error)
+	//GetLeaderboard returns a page of the leaderboard and the number of entries in it
+	GetLeaderboard(query LeaderboardQuery) ([]LeaderboardEntry, int, error)
+	//GetRank returns the rank of the user in the leaderboard, 0 if the user isn't ranked
+	GetRank(query LeaderboardQuery, userID uint) (int, error)
+}
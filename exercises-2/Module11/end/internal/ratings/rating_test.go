@@ -0,0 +1,126 @@
+package ratings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+func TestElo_Rate(t *testing.T) {
+	tests := []struct {
+		name      string
+		outcomes  []Outcome
+		current   map[uint]Rating
+		wantAfter []float64
+	}{
+		{name: "win between new players", outcomes: []Outcome{Win, Loss},
+			wantAfter: []float64{1516, 1484}},
+		{name: "draw between equal players", outcomes: []Outcome{Draw, Draw},
+			wantAfter: []float64{1500, 1500}},
+		{name: "upset", outcomes: []Outcome{Win, Loss},
+			current:   map[uint]Rating{1: {UserID: 1, Rating: 1400}, 2: {UserID: 2, Rating: 1800}},
+			wantAfter: []float64{1429.09, 1770.91}},
+		{name: "multiplayer", outcomes: []Outcome{Win, Loss, Loss},
+			wantAfter: []float64{1516, 1492, 1492}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := Match{GameID: "pokemoncards", PlayedAt: time.Now()}
+			for i, outcome := range tt.outcomes {
+				match.Players = append(match.Players, PlayerResult{UserID: uint(i + 1), Outcome: outcome})
+			}
+			results, updated := DefaultElo.Rate(match, tt.current)
+			for i, want := range tt.wantAfter {
+				if updated[i].Rating != want || results[i].RatingAfter != want {
+					t.Errorf("rating of player %d = %v, want %v", i+1, updated[i].Rating, want)
+				}
+				if updated[i].Games != 1 {
+					t.Errorf("games of player %d = %d, want 1", i+1, updated[i].Games)
+				}
+			}
+		})
+	}
+}
+
+func TestRecorder_RecordResult(t *testing.T) {
+	store := NewMemoryRatingStore()
+	recorder := NewRecorder(store)
+
+	dave := games.Player{ID: 1, Name: "dave", Email: "dave123@gmail.com"}
+	dan := games.Player{ID: 2, Name: "dan", Email: "dan@gmail.com"}
+	guest := games.Player{Name: "guest", Email: "guest@gmail.com"}
+	bot := games.Player{ID: 3, Name: "Bot 1 (easy)", Email: "bot1@bots.invalid", Bot: true}
+
+	err := recorder.RecordResult("pokemoncards", "session", []games.Player{dave, dan, guest, bot},
+		games.GameResult{Winners: []games.Player{dave}, Scores: map[string]int{dave.Email: 5, dan.Email: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.results) != 2 {
+		t.Fatalf("recorded %d results, want 2 as the guest isn't a registered user and bots aren't rated", len(store.results))
+	}
+	if store.results[0].Outcome != Win || store.results[0].Score != 5 || store.results[1].Outcome != Loss {
+		t.Errorf("results = %+v", store.results)
+	}
+
+	//a game everyone won is a draw
+	recorder.RecordResult("pokemoncards", "tie", []games.Player{dave, dan},
+		games.GameResult{Winners: []games.Player{dave, dan}})
+	if rating, _ := store.GetRating(dan.ID, "pokemoncards"); rating.Draws != 1 || rating.Losses != 1 {
+		t.Errorf("rating of dan = %+v, want a loss and a draw", rating)
+	}
+}
+
+func TestMemoryRatingStore_GetLeaderboard(t *testing.T) {
+	store := NewMemoryRatingStore()
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	now := time.Now()
+
+	record := func(gameID string, playedAt time.Time, winner uint, loser uint) {
+		store.RecordMatch(Match{GameID: gameID, PlayedAt: playedAt, Players: []PlayerResult{
+			{UserID: winner, Outcome: Win},
+			{UserID: loser, Outcome: Loss},
+		}}, DefaultElo.Rate)
+	}
+	record("pokemoncards", lastWeek, 1, 2)
+	record("pokemoncards", lastWeek, 1, 3)
+	record("pokemoncards", now, 3, 2)
+	record("chess", now, 2, 1)
+
+	tests := []struct {
+		name      string
+		query     LeaderboardQuery
+		wantUsers []uint
+		wantTotal int
+	}{
+		{name: "per game", query: LeaderboardQuery{GameID: "pokemoncards"}, wantUsers: []uint{1, 3, 2}, wantTotal: 3},
+		{name: "page", query: LeaderboardQuery{GameID: "pokemoncards", Offset: 1, Limit: 1}, wantUsers: []uint{3}, wantTotal: 3},
+		{name: "all games", query: LeaderboardQuery{}, wantUsers: []uint{1, 3, 2}, wantTotal: 3},
+		{name: "time windowed", query: LeaderboardQuery{GameID: "pokemoncards", Since: now.Add(-time.Hour)},
+			wantUsers: []uint{3, 2}, wantTotal: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, total, err := store.GetLeaderboard(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != tt.wantTotal || len(entries) != len(tt.wantUsers) {
+				t.Fatalf("GetLeaderboard() = %+v total %d", entries, total)
+			}
+			for i, userID := range tt.wantUsers {
+				if entries[i].UserID != userID || entries[i].Rank != tt.query.Offset+i+1 {
+					t.Errorf("entry %d = %+v, want user %d", i, entries[i], userID)
+				}
+			}
+		})
+	}
+
+	if rank, _ := store.GetRank(LeaderboardQuery{GameID: "pokemoncards"}, 3); rank != 2 {
+		t.Errorf("GetRank() = %d, want 2", rank)
+	}
+	if rank, _ := store.GetRank(LeaderboardQuery{GameID: "chess"}, 3); rank != 0 {
+		t.Errorf("GetRank() = %d for a user who didn't play, want 0", rank)
+	}
+}
@@ -0,0 +1,60 @@
+package ratings
+
+import (
+	"time"
+
+	"github.com/someuser/gameserver/internal/games"
+)
+
+//Recorder rates the games decided by the server rules, it is a games.ResultRecorder
+type Recorder struct {
+	Store RatingStore
+	Rate  RateFunc
+}
+
+//NewRecorder type
+func NewRecorder(store RatingStore) *Recorder {
+	return &Recorder{Store: store, Rate: DefaultElo.Rate}
+}
+
+//RecordResult saves the outcome of every registered player of the session and updates their ratings
+func (recorder *Recorder) RecordResult(gameID string, sessionID string, players []games.Player, result games.GameResult) error {
+	winners := make(map[string]bool)
+	for _, winner := range result.Winners {
+		winners[winner.Email] = true
+	}
+
+	match := Match{SessionID: sessionID, GameID: gameID, PlayedAt: time.Now()}
+	for _, player := range players {
+		//only registered users are rated, never the bots
+		if player.ID == 0 || player.Bot {
+			continue
+		}
+		outcome := Loss
+		if winners[player.Email] {
+			outcome = Win
+		}
+		match.Players = append(match.Players, PlayerResult{
+			UserID:  player.ID,
+			Name:    player.Name,
+			Score:   result.Scores[player.Email],
+			Outcome: outcome,
+		})
+	}
+	if len(match.Players) == 0 {
+		return nil
+	}
+
+	//when everyone won nobody did
+	allWon := true
+	for _, player := range match.Players {
+		allWon = allWon && player.Outcome == Win
+	}
+	if allWon {
+		for i := range match.Players {
+			match.Players[i].Outcome = Draw
+		}
+	}
+
+	return recorder.Store.RecordMatch(match, recorder.Rate)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/someuser/gameserver/internal/ratings"
+	"github.com/someuser/gameserver/internal/users"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	defaultRadius   = 5
+)
+
+var ratingsService *RatingsService
+
+//Get returns the ratings service, RATINGS_STORE=memory keeps the ratings in the process only
+func Get() *RatingsService {
+	if ratingsService == nil {
+		var store ratings.RatingStore
+		if viper.GetString("RATINGS_STORE") == "memory" {
+			store = ratings.NewMemoryRatingStore()
+		} else {
+			store = GetRatingsDataStore()
+		}
+		ratingsService = &RatingsService{DB: store}
+	}
+	return ratingsService
+}
+
+type RatingsService struct {
+	DB ratings.RatingStore
+}
+
+//parseLeaderboardQuery reads gameid, offset and limit and the start of the window
+//either as a date in since or as a duration back from now in period
+func parseLeaderboardQuery(r *http.Request) (ratings.LeaderboardQuery, error) {
+	values := r.URL.Query()
+	query := ratings.LeaderboardQuery{GameID: values.Get("gameid"), Limit: defaultPageSize}
+
+	var err error
+	if offset := values.Get("offset"); offset != "" {
+		if query.Offset, err = strconv.Atoi(offset); err != nil || query.Offset < 0 {
+			return query, errors.New("invalid offset")
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 || query.Limit > maxPageSize {
+			return query, errors.New("invalid limit")
+		}
+	}
+	if since := values.Get("since"); since != "" {
+		if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return query, errors.New("invalid since, expected a RFC3339 date")
+		}
+	} else if period := values.Get("period"); period != "" {
+		duration, err := time.ParseDuration(period)
+		if err != nil || duration <= 0 {
+			return query, errors.New("invalid period")
+		}
+		query.Since = time.Now().Add(-duration)
+	}
+	return query, nil
+}
+
+func (rs *RatingsService) writeLeaderboard(w http.ResponseWriter, query ratings.LeaderboardQuery) {
+	entries, total, err := rs.DB.GetLeaderboard(query)
+	if err != nil {
+		log.Print("error occued during leaderboard fetch ", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"offset":  query.Offset,
+		"limit":   query.Limit,
+	}}
+	json.NewEncoder(w).Encode(resp)
+}
+
+//Leaderboard returns a page of the leaderboard of a game, or of all games when no gameid is passed
+func (rs *RatingsService) Leaderboard(w http.ResponseWriter, r *http.Request) {
+
+	query, err := parseLeaderboardQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": false, "message": err.Error()})
+		return
+	}
+	rs.writeLeaderboard(w, query)
+}
+
+//AroundMe returns the part of the leaderboard around the user, radius entries above and below
+func (rs *RatingsService) AroundMe(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := r.Context().Value("user").(*users.User)
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	query, err := parseLeaderboardQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": false, "message": err.Error()})
+		return
+	}
+	radius := defaultRadius
+	if value := r.URL.Query().Get("radius"); value != "" {
+		if radius, err = strconv.Atoi(value); err != nil || radius < 0 || 2*radius+1 > maxPageSize {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	rank, err := rs.DB.GetRank(query, user.ID)
+	if err != nil {
+		log.Print("error occued during rank fetch ", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if rank == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	query.Offset = rank - 1 - radius
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	query.Limit = 2*radius + 1
+	rs.writeLeaderboard(w, query)
+}
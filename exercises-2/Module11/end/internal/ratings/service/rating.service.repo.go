@@ -0,0 +1,160 @@
+package service
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/someuser/gameserver/internal/ratings"
+	database "github.com/someuser/gameserver/internal/users/db"
+)
+
+type RatingsDB struct {
+	*sql.DB
+}
+
+func GetRatingsDataStore() ratings.RatingStore {
+	return &RatingsDB{database.Get()}
+}
+
+//RecordMatch locks the ratings of the players for the time it takes to rate the match
+func (db *RatingsDB) RecordMatch(match ratings.Match, rate ratings.RateFunc) error {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := make(map[uint]ratings.Rating)
+	for _, player := range match.Players {
+		var rating ratings.Rating
+		row := tx.QueryRow(`select user_id,game_id,rating,games,wins,losses,draws,updated_at from ratings
+							where user_id = ? and game_id = ? for update`, player.UserID, match.GameID)
+		err := row.Scan(&rating.UserID, &rating.GameID, &rating.Rating, &rating.Games, &rating.Wins, &rating.Losses,
+			&rating.Draws, &rating.UpdatedAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		current[player.UserID] = rating
+	}
+
+	results, updated := rate(match, current)
+
+	for _, result := range results {
+		_, err := tx.Exec(`insert into game_results(session_id,game_id,user_id,outcome,score,rating_before,rating_after,played_at)
+							values(?,?,?,?,?,?,?,?)`,
+			result.SessionID, result.GameID, result.UserID, string(result.Outcome), result.Score,
+			result.RatingBefore, result.RatingAfter, result.PlayedAt)
+		if err != nil {
+			return err
+		}
+	}
+	for _, rating := range updated {
+		_, err := tx.Exec(`insert into ratings(user_id,game_id,rating,games,wins,losses,draws,updated_at)values(?,?,?,?,?,?,?,?)
+							on duplicate key update rating = values(rating), games = values(games), wins = values(wins),
+							losses = values(losses), draws = values(draws), updated_at = values(updated_at)`,
+			rating.UserID, rating.GameID, rating.Rating, rating.Games, rating.Wins, rating.Losses, rating.Draws, rating.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (db *RatingsDB) GetRating(userID uint, gameID string) (ratings.Rating, error) {
+
+	rating := ratings.Rating{UserID: userID, GameID: gameID, Rating: ratings.DefaultRating}
+
+	row := db.QueryRow(`select r.rating,r.games,r.wins,r.losses,r.draws,r.updated_at,u.name from ratings r
+						join users u on u.id = r.user_id where r.user_id = ? and r.game_id = ?`, userID, gameID)
+	err := row.Scan(&rating.Rating, &rating.Games, &rating.Wins, &rating.Losses, &rating.Draws, &rating.UpdatedAt, &rating.Name)
+
+	if err != nil && err != sql.ErrNoRows {
+		return rating, err
+	}
+	return rating, nil
+}
+
+//leaderboardQuery returns the query of the whole leaderboard, with the rating of a game or the average
+//of all of them, and when it is time windowed with only what was won since the start of the window
+func leaderboardQuery(query ratings.LeaderboardQuery) (string, []interface{}) {
+	var args []interface{}
+
+	current := "select user_id, sum(rating*games)/sum(games) as rating, sum(games) as games from ratings group by user_id"
+	if query.GameID != "" {
+		current = "select user_id, rating, games from ratings where game_id = ?"
+		args = append(args, query.GameID)
+	}
+	if query.Since.IsZero() {
+		return "select r.user_id, u.name, r.rating, r.games, 0 as gain from (" + current + ") r join users u on u.id = r.user_id", args
+	}
+
+	window := "select user_id, count(*) as games, sum(rating_after - rating_before) as gain from game_results where played_at >= ?"
+	args = append(args, query.Since)
+	if query.GameID != "" {
+		window += " and game_id = ?"
+		args = append(args, query.GameID)
+	}
+	window += " group by user_id"
+
+	return "select r.user_id, u.name, r.rating, w.games, w.gain from (" + current + ") r join (" + window +
+		") w on w.user_id = r.user_id join users u on u.id = r.user_id", args
+}
+
+func (db *RatingsDB) GetLeaderboard(query ratings.LeaderboardQuery) ([]ratings.LeaderboardEntry, int, error) {
+
+	base, args := leaderboardQuery(query)
+
+	var total int
+	if err := db.QueryRow("select count(*) from ("+base+") b", args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = total
+	}
+	rows, err := db.Query(base+" order by gain desc, rating desc, user_id limit ? offset ?", append(args, limit, query.Offset)...)
+	if err != nil {
+		log.Print("error occued during leaderboard fetch ", err.Error())
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []ratings.LeaderboardEntry{}
+	for rows.Next() {
+		entry := ratings.LeaderboardEntry{Rank: query.Offset + len(entries) + 1}
+		if err := rows.Scan(&entry.UserID, &entry.Name, &entry.Rating, &entry.Games, &entry.Gain); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+func (db *RatingsDB) GetRank(query ratings.LeaderboardQuery, userID uint) (int, error) {
+
+	base, args := leaderboardQuery(query)
+
+	var rating, gain float64
+	err := db.QueryRow("select rating, gain from ("+base+") b where b.user_id = ?", append(args, userID)...).Scan(&rating, &gain)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var ahead int
+	err = db.QueryRow("select count(*) from ("+base+`) b where b.gain > ? or (b.gain = ? and b.rating > ?)
+						or (b.gain = ? and b.rating = ? and b.user_id < ?)`,
+		append(args, gain, gain, rating, gain, rating, userID)...).Scan(&ahead)
+	if err != nil {
+		return 0, err
+	}
+	return ahead + 1, nil
+}
@@ -1,13 +1,16 @@
 package routes
 
 import (
+	"expvar"
 	"net/http"
 	"net/http/pprof"
 
 	"github.com/gorilla/mux"
 	gamesService "github.com/someuser/gameserver/internal/games/service"
+	ratingsService "github.com/someuser/gameserver/internal/ratings/service"
 	"github.com/someuser/gameserver/internal/users/auth"
 	usersService "github.com/someuser/gameserver/internal/users/service"
+	webhooksService "github.com/someuser/gameserver/internal/webhooks/service"
 )
 
 func Handlers() *mux.Router {
@@ -15,6 +18,7 @@ func Handlers() *mux.Router {
 	r := mux.NewRouter().StrictSlash(true)
 
 	us := usersService.Get()
+	rs := ratingsService.Get()
 	jv := auth.GetAuthenticator()
 
 	r.HandleFunc("/register", us.CreateUser).Methods("POST")
@@ -35,9 +39,39 @@ func Handlers() *mux.Router {
 	s.HandleFunc("/user/{id}", us.DeleteUser).Methods("DELETE")
 	g := r.PathPrefix("/games").Subrouter()
 	g.Use(jv.JwtVerify)
+	g.HandleFunc("/", gamesService.ListGames).Methods("GET")
 	g.HandleFunc("/gameinfo", gamesService.GetGameInfo).Methods("GET")
 	g.HandleFunc("/startnewgame", gamesService.StartNewGame).Methods("GET")
 	g.HandleFunc("/joingame/{gametoken}", gamesService.JoinGame).Methods("GET")
+	g.HandleFunc("/spectate/{gametoken}", gamesService.SpectateGame).Methods("GET")
+	g.HandleFunc("/joinbycode/{code}", gamesService.JoinGameByCode).Methods("GET")
+	g.HandleFunc("/spectatebycode/{code}", gamesService.SpectateGameByCode).Methods("GET")
+	g.HandleFunc("/session/{gametoken}", gamesService.GetSessionInfo).Methods("GET")
+	g.HandleFunc("/matchmaking/queue", gamesService.EnqueueForMatch).Methods("GET")
+	g.HandleFunc("/matchmaking/queue", gamesService.CancelMatchmaking).Methods("DELETE")
+	g.HandleFunc("/matchmaking/status", gamesService.GetMatchmakingStatus).Methods("GET")
+	g.HandleFunc("/invitations", gamesService.ListInvitations).Methods("GET")
+	g.HandleFunc("/invitations/live", gamesService.ListenInvitations).Methods("GET")
+	g.HandleFunc("/invitations/{id}/accept", gamesService.AcceptInvitation).Methods("POST")
+	g.HandleFunc("/invitations/{id}/decline", gamesService.DeclineInvitation).Methods("POST")
+	g.HandleFunc("/replays/{session}", gamesService.GetReplay).Methods("GET")
+	g.HandleFunc("/replays/{session}/play", gamesService.PlayReplay).Methods("GET")
+
+	a := r.PathPrefix("/admin").Subrouter()
+	a.Use(jv.JwtVerify, jv.AdminVerify)
+	a.HandleFunc("/sessions", gamesService.ListActiveSessions).Methods("GET")
+	a.HandleFunc("/sessions/{gametoken}", gamesService.GetActiveSession).Methods("GET")
+	a.HandleFunc("/sessions/{gametoken}/kick", gamesService.KickPlayer).Methods("POST")
+	a.HandleFunc("/sessions/{gametoken}/broadcast", gamesService.BroadcastSystemMessage).Methods("POST")
+	a.HandleFunc("/sessions/{gametoken}/end", gamesService.EndSession).Methods("POST")
+	a.HandleFunc("/webhooks/deliveries", webhooksService.Get().Deliveries).Methods("GET")
+	//the counters of the server, like the messages dropped for going over the rate limits
+	a.Handle("/debug/vars", expvar.Handler()).Methods("GET")
+
+	l := r.PathPrefix("/leaderboards").Subrouter()
+	l.Use(jv.JwtVerify)
+	l.HandleFunc("/", rs.Leaderboard).Methods("GET")
+	l.HandleFunc("/me", rs.AroundMe).Methods("GET")
 
 	return r
 }
@@ -8,10 +8,13 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/someuser/gameserver/internal/users"
+	"github.com/spf13/viper"
 )
 
 type JwtAuthenticator struct{}
@@ -170,3 +173,26 @@ func (jwtAuth JwtAuthenticator) GetTokenForUser(user *users.User) (string, error
 
 	return tokenString, nil
 }
+
+//AdminVerify Middleware function, it lets through the users whose id is listed in ADMIN_USER_IDS and has to run after JwtVerify
+func (jwtAuth JwtAuthenticator) AdminVerify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		usr, ok := r.Context().Value("user").(*users.User)
+		if !ok || !isAdmin(usr.ID) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//isAdmin goes by the id of the signed token, unlike the email it can't be claimed by registering
+func isAdmin(id uint) bool {
+	for _, admin := range strings.Split(viper.GetString("ADMIN_USER_IDS"), ",") {
+		if adminID, err := strconv.ParseUint(strings.TrimSpace(admin), 10, 64); err == nil && uint(adminID) == id && id != 0 {
+			return true
+		}
+	}
+	return false
+}
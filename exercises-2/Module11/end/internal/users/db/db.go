@@ -73,7 +73,8 @@ func createAndOpen(name string, dbURI string) (*sql.DB, error) {
 		return nil, err
 	}
 
-	db, err = sql.Open("mysql", dbURI+name)
+	//parseTime lets the driver scan DATETIME columns into time.Time
+	db, err = sql.Open("mysql", dbURI+name+"?parseTime=true")
 	if err != nil {
 		return nil, err
 	}
@@ -83,12 +84,157 @@ func createAndOpen(name string, dbURI string) (*sql.DB, error) {
 						name varchar(100) NOT NULL,
 						email varchar(100) NOT NULL,
 						password varchar(100) NOT NULL,
+						PRIMARY KEY (id),
+						UNIQUE KEY email (email)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+	if err = addUniqueEmail(db); err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS game_sessions (
+						id varchar(36) NOT NULL,
+						game_id varchar(100) NOT NULL,
+						players text NOT NULL,
+						game_data mediumtext NOT NULL,
+						logic_state mediumtext NOT NULL,
+						private tinyint(1) NOT NULL DEFAULT 0,
+						passphrase varchar(60) NOT NULL DEFAULT '',
+						join_code varchar(6) NOT NULL DEFAULT '',
+						host varchar(100) NOT NULL DEFAULT '',
+						created_at datetime NOT NULL,
+						updated_at datetime NOT NULL,
 						PRIMARY KEY (id)
 					);`)
 	if err != nil {
 		return nil, err
 	}
 
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS ratings (
+						user_id int NOT NULL,
+						game_id varchar(100) NOT NULL,
+						rating double NOT NULL,
+						games int NOT NULL,
+						wins int NOT NULL,
+						losses int NOT NULL,
+						draws int NOT NULL,
+						updated_at datetime NOT NULL,
+						PRIMARY KEY (user_id, game_id),
+						KEY game_rating (game_id, rating)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS game_results (
+						id int NOT NULL AUTO_INCREMENT,
+						session_id varchar(36) NOT NULL,
+						game_id varchar(100) NOT NULL,
+						user_id int NOT NULL,
+						outcome varchar(10) NOT NULL,
+						score int NOT NULL,
+						rating_before double NOT NULL,
+						rating_after double NOT NULL,
+						played_at datetime NOT NULL,
+						PRIMARY KEY (id),
+						KEY user_results (user_id, played_at),
+						KEY game_results (game_id, played_at)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS invitations (
+						id varchar(36) NOT NULL,
+						session_id varchar(36) NOT NULL,
+						game_id varchar(100) NOT NULL,
+						game_name varchar(255) NOT NULL,
+						from_email varchar(255) NOT NULL,
+						from_name varchar(255) NOT NULL,
+						to_email varchar(255) NOT NULL,
+						status varchar(10) NOT NULL,
+						created_at datetime NOT NULL,
+						expires_at datetime NOT NULL,
+						responded_at datetime NULL,
+						PRIMARY KEY (id),
+						KEY invited (to_email, status, expires_at)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS cluster_nodes (
+						id varchar(255) NOT NULL,
+						addr varchar(255) NOT NULL,
+						seen_at datetime(3) NOT NULL,
+						PRIMARY KEY (id)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS session_owners (
+						session_id varchar(36) NOT NULL,
+						node_id varchar(255) NOT NULL,
+						PRIMARY KEY (session_id),
+						KEY node_sessions (node_id)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS join_codes (
+						code varchar(6) NOT NULL,
+						session_id varchar(36) NOT NULL,
+						expires_at datetime NOT NULL,
+						PRIMARY KEY (code)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS player_strikes (
+						id int NOT NULL AUTO_INCREMENT,
+						email varchar(100) NOT NULL,
+						struck_at datetime(3) NOT NULL,
+						PRIMARY KEY (id),
+						KEY email_strikes (email, struck_at)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS player_bans (
+						email varchar(100) NOT NULL,
+						banned_until datetime(3) NOT NULL,
+						PRIMARY KEY (email)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+						seq bigint NOT NULL AUTO_INCREMENT,
+						id varchar(36) NOT NULL,
+						event_id varchar(36) NOT NULL,
+						event_type varchar(100) NOT NULL,
+						subscription_id varchar(100) NOT NULL,
+						url varchar(2048) NOT NULL,
+						status varchar(20) NOT NULL,
+						attempts int NOT NULL,
+						status_code int NOT NULL,
+						error text NOT NULL,
+						created_at datetime(3) NOT NULL,
+						finished_at datetime(3) NOT NULL,
+						PRIMARY KEY (seq),
+						UNIQUE KEY id (id)
+					);`)
+	if err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -118,3 +264,18 @@ func connectDB() *sql.DB {
 	log.Println("Successfully connected to db!")
 	return db
 }
+
+//addUniqueEmail adds the unique email index to the users tables created before it was part of the table,
+//the emails registered more than once have to be fixed by hand first
+func addUniqueEmail(db *sql.DB) error {
+	var count int
+	err := db.QueryRow(`select count(*) from information_schema.statistics
+						where table_schema = database() and table_name = 'users' and index_name = 'email'`).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+	if _, err = db.Exec("ALTER TABLE users ADD UNIQUE KEY email (email)"); err != nil {
+		return fmt.Errorf("couldn't make the emails of the users unique: %v", err)
+	}
+	return nil
+}
@@ -8,13 +8,18 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/someuser/gameserver/internal/users"
 	"github.com/someuser/gameserver/internal/users/auth"
+	webhooksService "github.com/someuser/gameserver/internal/webhooks/service"
 )
 
 var usersService *UsersService
 
 func Get() *UsersService {
 	if usersService == nil {
-		usersService = &UsersService{DB: GetUsersDataStore(), JwtAuth: auth.GetAuthenticator()}
+		usersService = &UsersService{
+			DB:      GetUsersDataStore(),
+			JwtAuth: auth.GetAuthenticator(),
+			Events:  webhooksService.Get().Dispatcher,
+		}
 		return usersService
 	}
 	return usersService
@@ -23,6 +28,8 @@ func Get() *UsersService {
 type UsersService struct {
 	DB      users.UserDatastore
 	JwtAuth users.UserAuth
+	//Events is told about the users who registered
+	Events users.EventPublisher
 }
 
 func (us *UsersService) Login(w http.ResponseWriter, r *http.Request) {
@@ -62,9 +69,18 @@ func (us *UsersService) CreateUser(w http.ResponseWriter, r *http.Request) {
 	user := &users.User{}
 	json.NewDecoder(r.Body).Decode(user)
 
-	_, err := us.DB.FindUser(user.Email, user.Password)
-
-	if err == nil {
+	if users.ReservedEmail(user.Email) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	//an email belongs to a single user whatever the password
+	taken, err := us.DB.EmailTaken(user.Email)
+	if err != nil {
+		log.Print("error occued EmailTaken ", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if taken {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -75,6 +91,12 @@ func (us *UsersService) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
+	if us.Events != nil {
+		event := users.UserEvent{ID: user.ID, Name: user.Name, Email: user.Email}
+		if err := us.Events.Publish(users.EventUserRegistered, event); err != nil {
+			log.Print("error occued publishing the user registration ", err.Error())
+		}
+	}
 
 	tokenString, err := us.JwtAuth.GetTokenForUser(user)
 	var resp = map[string]interface{}{"status": true, "user": user, "access-token": tokenString}
@@ -102,6 +124,21 @@ func (us *UsersService) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	var id = params["id"]
 
 	json.NewDecoder(r.Body).Decode(&user)
+	if users.ReservedEmail(user.Email) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	//the user can keep their email but not take the one of another user
+	taken, err := us.DB.EmailTakenByOther(user.Email, id)
+	if err != nil {
+		log.Print("error occued EmailTakenByOther ", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if taken {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
 	if err := us.DB.UpdateUser(id, user); err != nil {
 		log.Print("error occued during user update ", err.Error())
@@ -89,6 +89,22 @@ func (db *UsersDB) FindUser(email, password string) (*users.User, error) {
 	return user, nil
 }
 
+func (db *UsersDB) EmailTaken(email string) (bool, error) {
+	var count int
+	if err := db.QueryRow("select count(*) from users where email = ?", email).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (db *UsersDB) EmailTakenByOther(email, id string) (bool, error) {
+	var count int
+	if err := db.QueryRow("select count(*) from users where email = ? and id <> ?", email, id).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (db *UsersDB) UpdateUser(id string, user users.User) error {
 
 	result, err := db.Exec("update users set name = ? , email= ? ,password = ? where id = ?", user.Name, user.Email, user.Password, id)
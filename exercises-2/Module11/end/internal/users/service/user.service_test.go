@@ -10,6 +10,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gorilla/mux"
+
 	"github.com/someuser/gameserver/internal/users"
 )
 
@@ -52,6 +54,22 @@ func (dbMock *UserDatastoreMock) FindUser(email, password string) (*users.User,
 	}
 	return nil, errors.New("couldnt find user")
 }
+func (dbMock *UserDatastoreMock) EmailTaken(email string) (bool, error) {
+	for _, user := range dbMock.users {
+		if strings.EqualFold(user.Email, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (dbMock *UserDatastoreMock) EmailTakenByOther(email, id string) (bool, error) {
+	for _, user := range dbMock.users {
+		if strings.EqualFold(user.Email, email) && strconv.Itoa(int(user.ID)) != id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
 func (dbMock *UserDatastoreMock) UpdateUser(id string, user users.User) error {
 	for i, u := range dbMock.users {
 		if uid, _ := strconv.Atoi(id); uid == int(u.ID) {
@@ -97,6 +115,18 @@ func (jwtMock *JwtVerifyMock) GetTokenForUser(user *users.User) (string, error)
 	return "a-mocked-token", nil
 }
 
+//EventPublisherMock keeps the events published
+type EventPublisherMock struct {
+	events []string
+	data   []interface{}
+}
+
+func (mock *EventPublisherMock) Publish(eventType string, data interface{}) error {
+	mock.events = append(mock.events, eventType)
+	mock.data = append(mock.data, data)
+	return nil
+}
+
 func TestUsersService_Login(t *testing.T) {
 	type fields struct {
 		DB users.UserDatastore
@@ -190,9 +220,11 @@ func TestUsersService_CreateUser(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			events := &EventPublisherMock{}
 			us := &UsersService{
 				DB:      tt.fields.DB,
 				JwtAuth: &JwtVerifyMock{},
+				Events:  events,
 			}
 			jsonuser, _ := json.Marshal(tt.args.user)
 			req, _ := http.NewRequest("POST", "/register", strings.NewReader(string(jsonuser)))
@@ -212,11 +244,77 @@ func TestUsersService_CreateUser(t *testing.T) {
 					status, http.StatusOK)
 				t.Fail()
 			}
+			want := users.UserEvent{Name: tt.args.user.Name, Email: tt.args.user.Email}
+			if len(events.events) != 1 || events.events[0] != users.EventUserRegistered || events.data[0] != want {
+				t.Errorf("published %v %+v, want %s of %+v", events.events, events.data, users.EventUserRegistered, want)
+			}
 			t.Log(testMap)
 		})
 	}
 }
 
+func TestUsersService_CreateUser_emailRejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+	}{
+		//the email of dave with another password
+		{name: "email taken", email: "Dave123@gmail.com"},
+		{name: "bot domain", email: "bot1@Bots.invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &UserDatastoreMock{}
+			db.init()
+			events := &EventPublisherMock{}
+			us := &UsersService{DB: db, JwtAuth: &JwtVerifyMock{}, Events: events}
+
+			jsonuser, _ := json.Marshal(users.User{Name: "eve", Email: tt.email, Password: "eve123"})
+			req, _ := http.NewRequest("POST", "/register", strings.NewReader(string(jsonuser)))
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(us.CreateUser).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+			if len(db.users) != 2 || len(events.events) != 0 {
+				t.Errorf("registered %d users and published %v", len(db.users), events.events)
+			}
+		})
+	}
+}
+
+func TestUsersService_UpdateUser_email(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantCode int
+	}{
+		{name: "own email", email: "dave123@gmail.com", wantCode: http.StatusOK},
+		{name: "email of another user", email: "Dan@gmail.com", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &UserDatastoreMock{}
+			db.init()
+			us := &UsersService{DB: db, JwtAuth: &JwtVerifyMock{}}
+
+			jsonuser, _ := json.Marshal(users.User{ID: 1, Name: "dave", Email: tt.email, Password: "dave123"})
+			req, _ := http.NewRequest("PUT", "/user/1", strings.NewReader(string(jsonuser)))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(us.UpdateUser).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantCode)
+			}
+			if db.users[1].Email != "dan@gmail.com" {
+				t.Errorf("the email of dan was changed to %s", db.users[1].Email)
+			}
+		})
+	}
+}
+
 func TestUsersService_FetchUsers(t *testing.T) {
 	us := &UsersService{
 		DB:      &UserDatastoreMock{},
@@ -1,6 +1,9 @@
 package users
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 //User struct declaration
 type User struct {
@@ -10,10 +13,37 @@ type User struct {
 	Password string `json:"password"`
 }
 
+//BotEmailDomain is the domain of the emails of the bots playing in the game sessions, no user can take it
+const BotEmailDomain = "bots.invalid"
+
+//ReservedEmail tells whether the email can't be registered by a user
+func ReservedEmail(email string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSpace(email)), "@"+BotEmailDomain)
+}
+
+//EventUserRegistered is published once a user registered
+const EventUserRegistered = "user.registered"
+
+//EventPublisher lets the services outside of the server, like the webhooks, know about the users
+type EventPublisher interface {
+	Publish(eventType string, data interface{}) error
+}
+
+//UserEvent is the data of the events of the users, it leaves out the password
+type UserEvent struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
 type UserDatastore interface {
 	CreateUser(user *User) error
 	GetAllUsers() ([]User, error)
 	FindUser(email, password string) (*User, error)
+	//EmailTaken tells whether a user already registered with the email
+	EmailTaken(email string) (bool, error)
+	//EmailTakenByOther tells whether a user other than the one with the id registered with the email
+	EmailTakenByOther(email, id string) (bool, error)
 	UpdateUser(id string, user User) error
 	DeleteUser(id string) error
 	GetUser(id string) (User, error)
@@ -0,0 +1,40 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+//DeadLetter is an event that couldn't be delivered with the last attempt made
+type DeadLetter struct {
+	Delivery Delivery `json:"delivery"`
+	Event    Event    `json:"event"`
+}
+
+//FileDeadLetters appends the dead letters to a file, a json object per line
+type FileDeadLetters struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (letters *FileDeadLetters) Add(delivery Delivery, event Event) error {
+	line, err := json.Marshal(DeadLetter{Delivery: delivery, Event: event})
+	if err != nil {
+		return err
+	}
+	letters.mu.Lock()
+	defer letters.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(letters.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(letters.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,293 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//Config is how the events are delivered
+type Config struct {
+	//MaxAttempts is how many times a delivery is tried before the event goes to the dead letters
+	MaxAttempts int
+	//Backoff is the wait before the first retry, it doubles with each retry up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	//Timeout is how long a subscriber has to answer
+	Timeout time.Duration
+	//QueueSize is how many events can wait to be delivered to a subscription, Workers how many are delivered to it at once
+	QueueSize int
+	Workers   int
+}
+
+//DefaultConfig type
+var DefaultConfig = Config{
+	MaxAttempts: 5,
+	Backoff:     time.Second,
+	MaxBackoff:  time.Minute,
+	Timeout:     10 * time.Second,
+	QueueSize:   1000,
+	Workers:     4,
+}
+
+//Validate checks the config can be used
+func (config Config) Validate() error {
+	if config.MaxAttempts <= 0 || config.QueueSize <= 0 || config.Workers <= 0 {
+		return errors.New("the webhook attempts, queue size and workers must be positive")
+	}
+	if config.Backoff <= 0 || config.MaxBackoff < config.Backoff || config.Timeout <= 0 {
+		return errors.New("the webhook backoff and timeout must be positive, the max backoff at least the backoff")
+	}
+	return nil
+}
+
+//backoff is the wait before the attempt following the one given
+func (config Config) backoff(attempt int) time.Duration {
+	wait := config.Backoff
+	for i := 1; i < attempt && wait < config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > config.MaxBackoff {
+		wait = config.MaxBackoff
+	}
+	return wait
+}
+
+//Dispatcher posts the events to the subscriptions that want them, in the background. Each subscription has
+//its own queue and workers so an endpoint that is down or slow only holds back its own deliveries
+type Dispatcher struct {
+	config      Config
+	client      *http.Client
+	history     DeliveryStore
+	deadLetters DeadLetters
+	lanes       []*lane
+
+	//mu guards closed and the retries of the lanes
+	mu     sync.RWMutex
+	closed bool
+	//pending counts the deliveries not finished yet, the ones waiting for a retry included
+	pending sync.WaitGroup
+	//done is closed once the pending deliveries are finished, the workers then stop
+	done    chan struct{}
+	workers sync.WaitGroup
+}
+
+//lane delivers the events of a subscription
+type lane struct {
+	subscription Subscription
+	queue        chan *attempt
+	//retries are the deliveries waiting for their backoff to be over before they are queued again
+	retries map[*attempt]*time.Timer
+}
+
+//attempt is a delivery on its way with the event it posts
+type attempt struct {
+	delivery Delivery
+	event    Event
+	body     []byte
+}
+
+//NewDispatcher starts delivering the events published to the subscriptions, the history and the dead letters are optional
+func NewDispatcher(subscriptions []Subscription, config Config, history DeliveryStore, deadLetters DeadLetters) *Dispatcher {
+	dispatcher := &Dispatcher{
+		config:      config,
+		client:      &http.Client{Timeout: config.Timeout},
+		history:     history,
+		deadLetters: deadLetters,
+		done:        make(chan struct{}),
+	}
+	for _, subscription := range subscriptions {
+		lane := &lane{
+			subscription: subscription,
+			queue:        make(chan *attempt, config.QueueSize),
+			retries:      make(map[*attempt]*time.Timer),
+		}
+		dispatcher.lanes = append(dispatcher.lanes, lane)
+		for i := 0; i < config.Workers; i++ {
+			dispatcher.workers.Add(1)
+			go dispatcher.work(lane)
+		}
+	}
+	return dispatcher
+}
+
+//Publish queues the event for the subscriptions that want it, it never waits for them.
+//ErrQueueFull is returned when the queue of one of them is full, the others still get the event
+func (dispatcher *Dispatcher) Publish(eventType string, data interface{}) error {
+	wanted := false
+	for _, lane := range dispatcher.lanes {
+		wanted = wanted || lane.subscription.Wants(eventType)
+	}
+	if !wanted {
+		return nil
+	}
+	event := Event{ID: uuid.New().String(), Type: eventType, Time: time.Now(), Data: data}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	dispatcher.mu.RLock()
+	defer dispatcher.mu.RUnlock()
+	if dispatcher.closed {
+		return ErrClosed
+	}
+	for _, lane := range dispatcher.lanes {
+		if !lane.subscription.Wants(eventType) {
+			continue
+		}
+		delivery := Delivery{
+			ID:             uuid.New().String(),
+			EventID:        event.ID,
+			EventType:      event.Type,
+			SubscriptionID: lane.subscription.ID,
+			URL:            lane.subscription.URL,
+			CreatedAt:      time.Now(),
+		}
+		dispatcher.pending.Add(1)
+		select {
+		case lane.queue <- &attempt{delivery: delivery, event: event, body: body}:
+		default:
+			dispatcher.pending.Done()
+			err = ErrQueueFull
+		}
+	}
+	return err
+}
+
+//Close stops taking events and waits for the queued ones to be tried once more,
+//the ones that still fail go to the dead letters without waiting for a retry
+func (dispatcher *Dispatcher) Close() {
+	dispatcher.mu.Lock()
+	if dispatcher.closed {
+		dispatcher.mu.Unlock()
+		return
+	}
+	dispatcher.closed = true
+	var givenUp []*attempt
+	for _, lane := range dispatcher.lanes {
+		for attempt, timer := range lane.retries {
+			timer.Stop()
+			givenUp = append(givenUp, attempt)
+		}
+		lane.retries = make(map[*attempt]*time.Timer)
+	}
+	dispatcher.mu.Unlock()
+
+	for _, attempt := range givenUp {
+		dispatcher.finish(attempt)
+	}
+	dispatcher.pending.Wait()
+	close(dispatcher.done)
+	dispatcher.workers.Wait()
+}
+
+func (dispatcher *Dispatcher) work(lane *lane) {
+	defer dispatcher.workers.Done()
+	for {
+		select {
+		case attempt := <-lane.queue:
+			dispatcher.try(lane, attempt)
+		case <-dispatcher.done:
+			return
+		}
+	}
+}
+
+//try posts the event to the subscription, a failed attempt that can be made again is scheduled
+//once its backoff is over so the worker goes on with the next events meanwhile
+func (dispatcher *Dispatcher) try(lane *lane, attempt *attempt) {
+	delivery := &attempt.delivery
+	delivery.Attempts++
+	retry, err := dispatcher.post(lane.subscription, attempt.event, attempt.body, delivery)
+	if err == nil {
+		delivery.Status, delivery.Error = Delivered, ""
+	} else {
+		delivery.Status, delivery.Error = Failed, err.Error()
+		if retry && delivery.Attempts < dispatcher.config.MaxAttempts && dispatcher.schedule(lane, attempt) {
+			return
+		}
+	}
+	dispatcher.finish(attempt)
+}
+
+//schedule queues the attempt again after the backoff, it returns false when the dispatcher is closed
+func (dispatcher *Dispatcher) schedule(lane *lane, attempt *attempt) bool {
+	dispatcher.mu.Lock()
+	defer dispatcher.mu.Unlock()
+	if dispatcher.closed {
+		return false
+	}
+	lane.retries[attempt] = time.AfterFunc(dispatcher.config.backoff(attempt.delivery.Attempts), func() {
+		//Close gives up the retries it takes out of the lane first
+		dispatcher.mu.Lock()
+		_, waiting := lane.retries[attempt]
+		delete(lane.retries, attempt)
+		dispatcher.mu.Unlock()
+		if waiting {
+			lane.queue <- attempt
+		}
+	})
+	return true
+}
+
+//finish saves the delivery to the history, the failed ones go to the dead letters
+func (dispatcher *Dispatcher) finish(attempt *attempt) {
+	defer dispatcher.pending.Done()
+	delivery := attempt.delivery
+	delivery.FinishedAt = time.Now()
+
+	if dispatcher.history != nil {
+		if err := dispatcher.history.SaveDelivery(delivery); err != nil {
+			log.Printf("couldn't save webhook delivery %s: %v", delivery.ID, err)
+		}
+	}
+	if delivery.Status == Failed {
+		log.Printf("couldn't deliver webhook event %s to %s after %d attempts: %s",
+			delivery.EventID, delivery.SubscriptionID, delivery.Attempts, delivery.Error)
+		if dispatcher.deadLetters != nil {
+			if err := dispatcher.deadLetters.Add(delivery, attempt.event); err != nil {
+				log.Printf("couldn't add webhook event %s to the dead letters: %v", delivery.EventID, err)
+			}
+		}
+	}
+}
+
+//post makes an attempt, it tells if a failed one can be tried again: the subscribers answering
+//with a client error other than a timeout or too many requests won't accept the event later either
+func (dispatcher *Dispatcher) post(subscription Subscription, event Event, body []byte, delivery *Delivery) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, body))
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+
+	resp, err := dispatcher.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	//the connection is reused once the body is read
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("the subscriber answered %s", resp.Status)
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
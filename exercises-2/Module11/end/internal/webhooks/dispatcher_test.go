@@ -0,0 +1,197 @@
+package webhooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	MaxAttempts: 3,
+	Backoff:     time.Millisecond,
+	MaxBackoff:  4 * time.Millisecond,
+	Timeout:     time.Second,
+	QueueSize:   10,
+	Workers:     1,
+}
+
+//receiver is a subscriber answering with the statuses in turn, the last one once they run out
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	events   []Event
+	invalid  int
+}
+
+func (rec *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if !Verify("secret", body, r.Header.Get(SignatureHeader)) {
+		rec.invalid++
+	}
+	var event Event
+	json.Unmarshal(body, &event)
+	rec.events = append(rec.events, event)
+	status := rec.statuses[0]
+	if len(rec.statuses) > 1 {
+		rec.statuses = rec.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func waitForDeliveries(t *testing.T, history DeliveryStore, count int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if deliveries, _ := history.Deliveries(count); len(deliveries) == count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("there weren't %d deliveries", count)
+}
+
+func TestDispatcher(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantStatus   Status
+		wantAttempts int
+	}{
+		{name: "delivered", statuses: []int{http.StatusOK}, wantStatus: Delivered, wantAttempts: 1},
+		{name: "delivered once the subscriber is back", statuses: []int{http.StatusServiceUnavailable, http.StatusNoContent},
+			wantStatus: Delivered, wantAttempts: 2},
+		{name: "attempts run out", statuses: []int{http.StatusInternalServerError}, wantStatus: Failed, wantAttempts: 3},
+		{name: "rejected", statuses: []int{http.StatusBadRequest}, wantStatus: Failed, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &receiver{statuses: tt.statuses}
+			server := httptest.NewServer(rec)
+			defer server.Close()
+			dir, _ := ioutil.TempDir("", "webhooks")
+			defer os.RemoveAll(dir)
+			deadLetters := &FileDeadLetters{Path: filepath.Join(dir, "dead.jsonl")}
+
+			history := NewMemoryDeliveryStore(10)
+			subscriptions := []Subscription{
+				{ID: "analytics", URL: server.URL, Secret: "secret"},
+				{ID: "bots", URL: server.URL, Secret: "secret", Events: []string{"game.ended"}},
+			}
+			dispatcher := NewDispatcher(subscriptions, testConfig, history, deadLetters)
+			if err := dispatcher.Publish("user.registered", map[string]string{"email": "dave123@gmail.com"}); err != nil {
+				t.Fatalf("Publish() = %v", err)
+			}
+			//the retries are given up once the dispatcher is closed
+			waitForDeliveries(t, history, 1)
+			dispatcher.Close()
+			if err := dispatcher.Publish("user.registered", nil); err != ErrClosed {
+				t.Errorf("Publish() once closed = %v, want %v", err, ErrClosed)
+			}
+
+			//only the subscription to every event got it
+			deliveries, _ := history.Deliveries(10)
+			if len(deliveries) != 1 {
+				t.Fatalf("deliveries = %+v, want 1", deliveries)
+			}
+			delivery := deliveries[0]
+			if delivery.SubscriptionID != "analytics" || delivery.Status != tt.wantStatus || delivery.Attempts != tt.wantAttempts {
+				t.Errorf("delivery = %+v, want %s after %d attempts", delivery, tt.wantStatus, tt.wantAttempts)
+			}
+			if len(rec.events) != tt.wantAttempts || rec.events[0].Type != "user.registered" || rec.invalid != 0 {
+				t.Errorf("received %+v with %d invalid signatures", rec.events, rec.invalid)
+			}
+
+			var letters []DeadLetter
+			if file, err := os.Open(deadLetters.Path); err == nil {
+				scanner := bufio.NewScanner(file)
+				for scanner.Scan() {
+					var letter DeadLetter
+					json.Unmarshal(scanner.Bytes(), &letter)
+					letters = append(letters, letter)
+				}
+				file.Close()
+			}
+			wantLetters := 0
+			if tt.wantStatus == Failed {
+				wantLetters = 1
+			}
+			if len(letters) != wantLetters {
+				t.Errorf("dead letters = %+v, want %d", letters, wantLetters)
+			}
+		})
+	}
+}
+
+func TestDispatcher_subscriptionDown(t *testing.T) {
+	rec := &receiver{statuses: []int{http.StatusOK}}
+	up := httptest.NewServer(rec)
+	defer up.Close()
+	//the endpoint down holds the requests until it is released then fails them
+	release := make(chan struct{})
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	config := testConfig
+	config.Backoff, config.MaxBackoff = time.Hour, time.Hour
+	history := NewMemoryDeliveryStore(10)
+	subscriptions := []Subscription{
+		{ID: "down", URL: down.URL, Secret: "secret"},
+		{ID: "analytics", URL: up.URL, Secret: "secret"},
+	}
+	dispatcher := NewDispatcher(subscriptions, config, history, nil)
+	for i := 0; i < 3; i++ {
+		if err := dispatcher.Publish("game.ended", nil); err != nil {
+			t.Fatalf("Publish() = %v", err)
+		}
+	}
+	//the other subscription gets its events while the one down doesn't answer
+	waitForDeliveries(t, history, 3)
+	close(release)
+	//the retries waiting for their backoff are given up
+	dispatcher.Close()
+
+	deliveries, _ := history.Deliveries(10)
+	failed := 0
+	for _, delivery := range deliveries {
+		if delivery.SubscriptionID == "down" && delivery.Status == Failed && delivery.Attempts == 1 {
+			failed++
+		}
+	}
+	if len(deliveries) != 6 || failed != 3 {
+		t.Errorf("deliveries = %+v, want 3 of them failed once", deliveries)
+	}
+}
+
+func TestConfig_backoff(t *testing.T) {
+	config := Config{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, wait := range want {
+		if got := config.backoff(i + 1); got != wait {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, wait)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"game.ended"}`)
+	signature := Sign("secret", body)
+	if !Verify("secret", body, signature) {
+		t.Error("the signature is not verified with its secret")
+	}
+	if Verify("other", body, signature) || Verify("secret", []byte(`{"type":"user.registered"}`), signature) {
+		t.Error("the signature is verified with another secret or body")
+	}
+}
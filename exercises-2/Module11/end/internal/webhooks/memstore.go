@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"sync"
+)
+
+//MemoryDeliveryStore keeps the most recent deliveries in memory
+type MemoryDeliveryStore struct {
+	mu         sync.Mutex
+	deliveries []Delivery
+	size       int
+}
+
+//NewMemoryDeliveryStore keeps up to size deliveries
+func NewMemoryDeliveryStore(size int) *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{size: size}
+}
+
+func (store *MemoryDeliveryStore) SaveDelivery(delivery Delivery) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.deliveries = append(store.deliveries, delivery)
+	if len(store.deliveries) > store.size {
+		store.deliveries = store.deliveries[len(store.deliveries)-store.size:]
+	}
+	return nil
+}
+
+func (store *MemoryDeliveryStore) Deliveries(limit int) ([]Delivery, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	recent := make([]Delivery, 0, limit)
+	for i := len(store.deliveries) - 1; i >= 0 && len(recent) < limit; i-- {
+		recent = append(recent, store.deliveries[i])
+	}
+	return recent, nil
+}
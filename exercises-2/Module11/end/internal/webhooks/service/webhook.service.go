@@ -0,0 +1,116 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/spf13/viper"
+
+	"github.com/someuser/gameserver/internal/webhooks"
+)
+
+const (
+	defaultHistorySize = 1000
+	defaultPageSize    = 50
+)
+
+var webhooksService *WebhooksService
+
+//WebhooksService posts the events of the server to the subscriptions of WEBHOOKS_FILE
+type WebhooksService struct {
+	Dispatcher *webhooks.Dispatcher
+	History    webhooks.DeliveryStore
+}
+
+//Get returns the webhooks service, the failed deliveries are written to WEBHOOK_DEAD_LETTER_FILE when it is set
+func Get() *WebhooksService {
+	if webhooksService == nil {
+		subscriptions, err := webhooks.LoadSubscriptions(viper.GetString("WEBHOOKS_FILE"))
+		if err != nil {
+			log.Fatalf("Error reading the webhook subscriptions, %s", err)
+		}
+
+		history := getHistory()
+		var deadLetters webhooks.DeadLetters
+		if path := viper.GetString("WEBHOOK_DEAD_LETTER_FILE"); path != "" {
+			deadLetters = &webhooks.FileDeadLetters{Path: path}
+		}
+
+		webhooksService = &WebhooksService{
+			Dispatcher: webhooks.NewDispatcher(subscriptions, getConfig(), history, deadLetters),
+			History:    history,
+		}
+		log.Printf("posting the events to %d webhook subscriptions", len(subscriptions))
+	}
+	return webhooksService
+}
+
+//getHistory returns where the WEBHOOK_HISTORY_SIZE most recent deliveries are kept, WEBHOOK_HISTORY_STORE=memory
+//keeps the ones of this replica only
+func getHistory() webhooks.DeliveryStore {
+	size := viper.GetInt("WEBHOOK_HISTORY_SIZE")
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	if viper.GetString("WEBHOOK_HISTORY_STORE") == "memory" {
+		return webhooks.NewMemoryDeliveryStore(size)
+	}
+	return GetDeliveriesDataStore(size)
+}
+
+//getConfig returns how the events are delivered, WEBHOOK_MAX_ATTEMPTS, WEBHOOK_BACKOFF, WEBHOOK_MAX_BACKOFF,
+//WEBHOOK_TIMEOUT, WEBHOOK_QUEUE_SIZE and WEBHOOK_WORKERS override the defaults
+func getConfig() webhooks.Config {
+	config := webhooks.DefaultConfig
+	if attempts := viper.GetInt("WEBHOOK_MAX_ATTEMPTS"); attempts != 0 {
+		config.MaxAttempts = attempts
+	}
+	if backoff := viper.GetDuration("WEBHOOK_BACKOFF"); backoff != 0 {
+		config.Backoff = backoff
+	}
+	if backoff := viper.GetDuration("WEBHOOK_MAX_BACKOFF"); backoff != 0 {
+		config.MaxBackoff = backoff
+	}
+	if timeout := viper.GetDuration("WEBHOOK_TIMEOUT"); timeout != 0 {
+		config.Timeout = timeout
+	}
+	if size := viper.GetInt("WEBHOOK_QUEUE_SIZE"); size != 0 {
+		config.QueueSize = size
+	}
+	if workers := viper.GetInt("WEBHOOK_WORKERS"); workers != 0 {
+		config.Workers = workers
+	}
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Error in the webhook config, %s", err)
+	}
+	return config
+}
+
+//Close delivers the events already published, the ones failing go to the dead letters without more retries
+func (ws *WebhooksService) Close() {
+	ws.Dispatcher.Close()
+}
+
+//Deliveries returns the most recent deliveries first, up to limit of them
+func (ws *WebhooksService) Deliveries(w http.ResponseWriter, r *http.Request) {
+
+	limit := defaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": false, "message": "invalid limit"})
+			return
+		}
+	}
+	deliveries, err := ws.History.Deliveries(limit)
+	if err != nil {
+		log.Print("error occued during webhook deliveries fetch ", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var resp = map[string]interface{}{"status": true, "message": deliveries}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"database/sql"
+
+	database "github.com/someuser/gameserver/internal/users/db"
+	"github.com/someuser/gameserver/internal/webhooks"
+)
+
+//DeliveriesDB keeps the history of the deliveries of every replica, the oldest ones beyond size are deleted
+type DeliveriesDB struct {
+	*sql.DB
+	size int
+}
+
+func GetDeliveriesDataStore(size int) webhooks.DeliveryStore {
+	return &DeliveriesDB{database.Get(), size}
+}
+
+func (db *DeliveriesDB) SaveDelivery(delivery webhooks.Delivery) error {
+
+	_, err := db.Exec(`insert into webhook_deliveries(id,event_id,event_type,subscription_id,url,status,attempts,
+						status_code,error,created_at,finished_at)values(?,?,?,?,?,?,?,?,?,?,?)`,
+		delivery.ID, delivery.EventID, delivery.EventType, delivery.SubscriptionID, delivery.URL, delivery.Status,
+		delivery.Attempts, delivery.StatusCode, delivery.Error, delivery.CreatedAt, delivery.FinishedAt)
+	if err != nil {
+		return err
+	}
+	//mysql can't delete from a table it reads in a subquery unless the subquery is materialized
+	_, err = db.Exec(`delete from webhook_deliveries where seq <= (select seq from
+						(select seq from webhook_deliveries order by seq desc limit 1 offset ?) as oldest)`, db.size)
+	return err
+}
+
+func (db *DeliveriesDB) Deliveries(limit int) ([]webhooks.Delivery, error) {
+
+	rows, err := db.Query(`select id,event_id,event_type,subscription_id,url,status,attempts,status_code,error,
+						created_at,finished_at from webhook_deliveries order by seq desc limit ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []webhooks.Delivery{}
+	for rows.Next() {
+		var delivery webhooks.Delivery
+		err := rows.Scan(&delivery.ID, &delivery.EventID, &delivery.EventType, &delivery.SubscriptionID, &delivery.URL,
+			&delivery.Status, &delivery.Attempts, &delivery.StatusCode, &delivery.Error, &delivery.CreatedAt,
+			&delivery.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,133 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+//the headers of the webhook requests, the signature is the hex HMAC-SHA256 of the body with the secret of the subscription
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+//Status of a delivery
+type Status string
+
+const (
+	Delivered Status = "delivered"
+	Failed    Status = "failed"
+)
+
+//Event is the json body posted to the subscribers
+type Event struct {
+	ID   string      `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+//Subscription is an endpoint the events are posted to
+type Subscription struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+	//Events are the types of the events posted to the endpoint, all of them when it is empty
+	Events []string `json:"events,omitempty"`
+}
+
+//Wants tells if the event is posted to the subscription
+func (subscription Subscription) Wants(eventType string) bool {
+	if len(subscription.Events) == 0 {
+		return true
+	}
+	for _, wanted := range subscription.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+//Delivery is the outcome of posting an event to a subscription
+type Delivery struct {
+	ID             string    `json:"id"`
+	EventID        string    `json:"eventId"`
+	EventType      string    `json:"eventType"`
+	SubscriptionID string    `json:"subscriptionId"`
+	URL            string    `json:"url"`
+	Status         Status    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	StatusCode     int       `json:"statusCode,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	FinishedAt     time.Time `json:"finishedAt"`
+}
+
+//DeliveryStore keeps the history of the deliveries
+type DeliveryStore interface {
+	SaveDelivery(delivery Delivery) error
+	//Deliveries returns the most recent deliveries first
+	Deliveries(limit int) ([]Delivery, error)
+}
+
+//DeadLetters keeps the events that couldn't be delivered so they can be looked at or sent again
+type DeadLetters interface {
+	Add(delivery Delivery, event Event) error
+}
+
+//Sign returns the signature of the body with the secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//Verify tells if the signature of the body was made with the secret, it is what the receivers check
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+//LoadSubscriptions reads the subscriptions from a json file, a missing file is no subscription
+func LoadSubscriptions(path string) ([]Subscription, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var subscriptions []Subscription
+	if err := json.Unmarshal(data, &subscriptions); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	ids := make(map[string]bool)
+	for _, subscription := range subscriptions {
+		if subscription.ID == "" || subscription.URL == "" {
+			return nil, fmt.Errorf("%s: the subscriptions need an id and an url", path)
+		}
+		if subscription.Secret == "" {
+			return nil, fmt.Errorf("%s: subscription %s has no secret", path, subscription.ID)
+		}
+		if ids[subscription.ID] {
+			return nil, fmt.Errorf("%s: subscription %s is defined twice", path, subscription.ID)
+		}
+		ids[subscription.ID] = true
+	}
+	return subscriptions, nil
+}
+
+var (
+	//ErrClosed is returned when publishing to a dispatcher that was closed
+	ErrClosed = errors.New("the webhook dispatcher is closed")
+	//ErrQueueFull is returned when publishing to a dispatcher with too many events waiting to be delivered
+	ErrQueueFull = errors.New("too many webhook events are waiting to be delivered")
+)